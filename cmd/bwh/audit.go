@@ -4,9 +4,12 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"regexp"
+	"slices"
 	"sort"
 	"time"
 
+	"github.com/strahe/bwh/internal/config"
 	"github.com/strahe/bwh/pkg/client"
 	"github.com/urfave/cli/v3"
 )
@@ -14,7 +17,12 @@ import (
 var auditCmd = &cli.Command{
 	Name:  "audit",
 	Usage: "display audit log entries",
-	Flags: []cli.Flag{
+	Commands: []*cli.Command{
+		auditCheckCmd,
+		auditExportCmd,
+		auditWatchCmd,
+	},
+	Flags: append([]cli.Flag{
 		&cli.BoolFlag{
 			Name:  "compact",
 			Usage: "display audit log in compact format",
@@ -24,11 +32,16 @@ var auditCmd = &cli.Command{
 			Usage: "limit number of entries to display",
 			Value: 10,
 		},
-	},
+	}, auditFilterFlags()...),
 	Action: func(ctx context.Context, cmd *cli.Command) error {
 		compact := cmd.Bool("compact")
 		limit := cmd.Int("limit")
 
+		filter, err := parseAuditFilter(cmd, time.Now())
+		if err != nil {
+			return err
+		}
+
 		bwhClient, resolvedName, err := createBWHClient(cmd)
 		if err != nil {
 			return err
@@ -46,7 +59,11 @@ var auditCmd = &cli.Command{
 			return nil
 		}
 
-		entries := auditLog.LogEntries
+		entries := filter.apply(auditLog.LogEntries)
+		if len(entries) == 0 {
+			fmt.Printf("No audit log entries match the filters (%d total)\n", len(auditLog.LogEntries))
+			return nil
+		}
 		sort.Slice(entries, func(i, j int) bool {
 			return entries[i].Timestamp > entries[j].Timestamp
 		})
@@ -102,11 +119,104 @@ func displayCompactAuditLog(entries []client.AuditLogEntry, instanceName string)
 	fmt.Printf("└─ End of audit log\n")
 }
 
+// auditFilterFlags returns the flags that select audit log entries.
+func auditFilterFlags() []cli.Flag {
+	return append([]cli.Flag{
+		&cli.StringFlag{
+			Name:  "since",
+			Usage: "only include entries at or after this time (RFC3339, YYYY-MM-DD or an age such as 7d, 12h)",
+		},
+		&cli.StringFlag{
+			Name:  "until",
+			Usage: "only include entries before this time (RFC3339, YYYY-MM-DD or an age such as 7d, 12h)",
+		},
+	}, auditMatchFlags()...)
+}
+
+// auditMatchFlags returns the audit filter flags that do not depend on time.
+func auditMatchFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.IntSliceFlag{
+			Name:  "type",
+			Usage: "only include entries with this event type (repeatable)",
+		},
+		&cli.StringSliceFlag{
+			Name:  "ip",
+			Usage: "only include entries from this requestor IPv4 address or CIDR (repeatable)",
+		},
+		&cli.StringFlag{
+			Name:  "grep",
+			Usage: "only include entries whose summary matches this regular expression",
+		},
+	}
+}
+
+// auditFilter selects audit log entries. Zero fields match everything.
+type auditFilter struct {
+	since   time.Time
+	until   time.Time
+	types   []int
+	nets    []*net.IPNet
+	summary *regexp.Regexp
+}
+
+func parseAuditFilter(cmd *cli.Command, now time.Time) (*auditFilter, error) {
+	filter := &auditFilter{types: cmd.IntSlice("type")}
+
+	var err error
+	if filter.since, err = parseTimeFlag(cmd.String("since"), now); err != nil {
+		return nil, fmt.Errorf("invalid --since: %w", err)
+	}
+	if filter.until, err = parseTimeFlag(cmd.String("until"), now); err != nil {
+		return nil, fmt.Errorf("invalid --until: %w", err)
+	}
+	if !filter.since.IsZero() && !filter.until.IsZero() && !filter.since.Before(filter.until) {
+		return nil, fmt.Errorf("--since must be before --until")
+	}
+
+	if filter.nets, err = config.ParseIPv4Nets(cmd.StringSlice("ip")); err != nil {
+		return nil, fmt.Errorf("invalid --ip: %w", err)
+	}
+
+	if pattern := cmd.String("grep"); pattern != "" {
+		if filter.summary, err = regexp.Compile(pattern); err != nil {
+			return nil, fmt.Errorf("invalid --grep: %w", err)
+		}
+	}
+	return filter, nil
+}
+
+func (f *auditFilter) match(e client.AuditLogEntry) bool {
+	t := time.Unix(e.Timestamp, 0)
+	if !f.since.IsZero() && t.Before(f.since) {
+		return false
+	}
+	if !f.until.IsZero() && !t.Before(f.until) {
+		return false
+	}
+	if len(f.types) > 0 && !slices.Contains(f.types, e.Type) {
+		return false
+	}
+	if len(f.nets) > 0 && !config.IPv4InNets(e.RequestorIPv4, f.nets) {
+		return false
+	}
+	if f.summary != nil && !f.summary.MatchString(e.Summary) {
+		return false
+	}
+	return true
+}
+
+// apply returns the entries that match the filter, keeping their order.
+func (f *auditFilter) apply(entries []client.AuditLogEntry) []client.AuditLogEntry {
+	var matched []client.AuditLogEntry
+	for _, e := range entries {
+		if f.match(e) {
+			matched = append(matched, e)
+		}
+	}
+	return matched
+}
+
 func intToIP(ipInt uint32) string {
-	ip := make(net.IP, 4)
-	ip[0] = byte(ipInt >> 24)
-	ip[1] = byte(ipInt >> 16)
-	ip[2] = byte(ipInt >> 8)
-	ip[3] = byte(ipInt)
-	return ip.String()
+	return config.IPv4(ipInt).String()
 }
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/strahe/bwh/internal/config"
+	"github.com/strahe/bwh/internal/notify"
+	"github.com/strahe/bwh/pkg/client"
+	"github.com/urfave/cli/v3"
+)
+
+var auditCheckCmd = &cli.Command{
+	Name:  "check",
+	Usage: "report audit log entries from addresses outside the trusted CIDRs",
+	Description: `Checks every audit log entry against the instance's trusted_cidrs and lists
+the entries whose requestor address is outside all of them. The command exits
+with a non-zero status when any are found, so it can run from cron:
+
+  bwh audit check --since 1h
+
+Configure the trusted ranges per instance in the config file:
+
+  instances:
+    web:
+      trusted_cidrs: [203.0.113.0/24, 198.51.100.7]`,
+	Flags: append([]cli.Flag{
+		&cli.StringSliceFlag{
+			Name:  "trusted",
+			Usage: "additional trusted IPv4 address or CIDR (repeatable)",
+		},
+		notifyFlag(),
+	}, auditFilterFlags()...),
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		filter, err := parseAuditFilter(cmd, time.Now())
+		if err != nil {
+			return err
+		}
+		extra, err := config.ParseIPv4Nets(cmd.StringSlice("trusted"))
+		if err != nil {
+			return fmt.Errorf("invalid --trusted: %w", err)
+		}
+		notifier, err := flagNotifier(cmd)
+		if err != nil {
+			return err
+		}
+
+		bwhClient, instance, resolvedName, err := createBWHClientWithInstance(cmd)
+		if err != nil {
+			return err
+		}
+		trusted, err := instance.TrustedNets()
+		if err != nil {
+			return fmt.Errorf("invalid trusted_cidrs for instance %s: %w", resolvedName, err)
+		}
+		return runAuditCheck(ctx, bwhClient, resolvedName, append(trusted, extra...), filter, notifier)
+	},
+}
+
+// runAuditCheck prints the audit entries selected by filter whose requestor
+// is outside trusted, and returns an error if there are any. Findings are
+// also sent to notifier if there is one.
+func runAuditCheck(ctx context.Context, api auditLogAPI, resolvedName string, trusted []*net.IPNet, filter *auditFilter, notifier eventNotifier) error {
+	if len(trusted) == 0 {
+		return fmt.Errorf("no trusted CIDRs configured for instance %s: set trusted_cidrs in the config file or pass --trusted", resolvedName)
+	}
+
+	auditLog, err := api.GetAuditLog(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get audit log: %w", err)
+	}
+	entries := filter.apply(auditLog.LogEntries)
+
+	var untrusted []client.AuditLogEntry
+	for _, e := range entries {
+		if !config.IPv4InNets(e.RequestorIPv4, trusted) {
+			untrusted = append(untrusted, e)
+		}
+	}
+
+	ranges := make([]string, len(trusted))
+	for i, n := range trusted {
+		ranges[i] = n.String()
+	}
+	if len(untrusted) == 0 {
+		fmt.Printf("✅ %s: %d audit log entr%s checked, all from trusted ranges (%s)\n", resolvedName, len(entries), pluralSuffix(len(entries), "y", "ies"), strings.Join(ranges, ", "))
+		return nil
+	}
+
+	sort.SliceStable(untrusted, func(i, j int) bool {
+		return untrusted[i].Timestamp > untrusted[j].Timestamp
+	})
+	fmt.Printf("⚠️  %s: %d of %d audit log entries from outside the trusted ranges (%s)\n", resolvedName, len(untrusted), len(entries), strings.Join(ranges, ", "))
+	for _, e := range untrusted {
+		fmt.Printf("├─ [%s] %-15s | %s\n", time.Unix(e.Timestamp, 0).Local().Format("2006-01-02 15:04:05"), intToIP(e.RequestorIPv4), e.Summary)
+	}
+	fmt.Printf("└─ End of untrusted entries\n")
+
+	var addresses []string
+	for _, e := range untrusted {
+		if ip := intToIP(e.RequestorIPv4); !slices.Contains(addresses, ip) {
+			addresses = append(addresses, ip)
+		}
+	}
+	sendNotification(ctx, notifier, notify.Event{
+		Title:    "Audit log entries from untrusted addresses",
+		Message:  fmt.Sprintf("%d of %d audit log entries came from outside the trusted ranges (%s).", len(untrusted), len(entries), strings.Join(ranges, ", ")),
+		Severity: notify.Warning,
+		Instance: resolvedName,
+		Source:   "audit",
+		Fields:   map[string]string{"count": strconv.Itoa(len(untrusted)), "addresses": strings.Join(addresses, ", ")},
+	})
+	return fmt.Errorf("%d audit log entr%s from untrusted addresses", len(untrusted), pluralSuffix(len(untrusted), "y", "ies"))
+}
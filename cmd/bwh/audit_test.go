@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/strahe/bwh/internal/config"
+	"github.com/strahe/bwh/internal/notify"
+	"github.com/strahe/bwh/pkg/client"
+)
+
+func TestAuditFilter(t *testing.T) {
+	entries := []client.AuditLogEntry{
+		{Timestamp: 1000, RequestorIPv4: 0x01020304, Type: 1, Summary: "Logged in"},
+		{Timestamp: 2000, RequestorIPv4: 0x0A000005, Type: 2, Summary: "VPS stopped"},
+		{Timestamp: 3000, RequestorIPv4: 0x0A0000FF, Type: 1, Summary: "Logged in via API"},
+	}
+	nets, err := config.ParseIPv4Nets([]string{"10.0.0.0/24"})
+	if err != nil {
+		t.Fatalf("config.ParseIPv4Nets() error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		filter auditFilter
+		want   []int64
+	}{
+		{"no filter", auditFilter{}, []int64{1000, 2000, 3000}},
+		{"time range", auditFilter{since: time.Unix(1500, 0), until: time.Unix(3000, 0)}, []int64{2000}},
+		{"type", auditFilter{types: []int{1}}, []int64{1000, 3000}},
+		{"cidr", auditFilter{nets: nets}, []int64{2000, 3000}},
+		{"summary regex", auditFilter{summary: regexp.MustCompile(`(?i)logged in$`)}, []int64{1000}},
+		{"combined", auditFilter{types: []int{1}, nets: nets}, []int64{3000}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []int64
+			for _, e := range tt.filter.apply(entries) {
+				got = append(got, e.Timestamp)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("apply() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("apply() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+
+	single, err := config.ParseIPv4Nets([]string{"1.2.3.4"})
+	if err != nil || !config.IPv4InNets(0x01020304, single) || config.IPv4InNets(0x01020305, single) {
+		t.Fatalf("single address should match only itself: %v, %v", single, err)
+	}
+	for _, bad := range []string{"2001:db8::/32", "1.2.3", "10.0.0.0/33"} {
+		if _, err := config.ParseIPv4Nets([]string{bad}); err == nil {
+			t.Fatalf("config.ParseIPv4Nets(%q) error = nil, want error", bad)
+		}
+	}
+}
+
+func TestPollAuditLog(t *testing.T) {
+	markPath := filepath.Join(t.TempDir(), "web", auditMarkFile)
+	api := &fakeAuditAPI{entries: []client.AuditLogEntry{
+		{Timestamp: 1000, Summary: "old"},
+		{Timestamp: 2000, Summary: "old at mark"},
+	}}
+	notifier := &fakeEventNotifier{}
+	poll := func(filter *auditFilter) []client.AuditLogEntry {
+		t.Helper()
+		var fresh []client.AuditLogEntry
+		captureStdout(t, func() {
+			var err error
+			if fresh, err = pollAuditLog(context.Background(), api, "web", markPath, filter, notifier); err != nil {
+				t.Fatalf("pollAuditLog() error = %v", err)
+			}
+		})
+		return fresh
+	}
+
+	if fresh := poll(&auditFilter{}); len(fresh) != 0 {
+		t.Fatalf("first poll printed %v, want only the mark recorded", fresh)
+	}
+
+	api.entries = append(api.entries,
+		client.AuditLogEntry{Timestamp: 2000, Summary: "same second as mark"},
+		client.AuditLogEntry{Timestamp: 3000, RequestorIPv4: 0x01020304, Summary: "Logged in"},
+	)
+	fresh := poll(&auditFilter{})
+	if len(fresh) != 2 || fresh[0].Summary != "same second as mark" || fresh[1].Summary != "Logged in" {
+		t.Fatalf("second poll = %v", fresh)
+	}
+	if len(notifier.events) != 2 || notifier.events[1].Fields["ip"] != "1.2.3.4" || notifier.events[1].Instance != "web" {
+		t.Fatalf("notifications = %+v", notifier.events)
+	}
+
+	if fresh := poll(&auditFilter{}); len(fresh) != 0 {
+		t.Fatalf("third poll repeated entries: %v", fresh)
+	}
+
+	api.entries = append(api.entries,
+		client.AuditLogEntry{Timestamp: 4000, Summary: "VPS stopped"},
+		client.AuditLogEntry{Timestamp: 4000, Summary: "Logged in again"},
+	)
+	fresh = poll(&auditFilter{summary: regexp.MustCompile("Logged in")})
+	if len(fresh) != 1 || !strings.Contains(fresh[0].Summary, "again") {
+		t.Fatalf("filtered poll = %v", fresh)
+	}
+	if fresh := poll(&auditFilter{}); len(fresh) != 0 {
+		t.Fatalf("filtered-out entries resurfaced: %v", fresh)
+	}
+}
+
+func TestRunAuditCheck(t *testing.T) {
+	api := &fakeAuditAPI{entries: []client.AuditLogEntry{
+		{Timestamp: 1000, RequestorIPv4: 0xCB007105, Summary: "Logged in"},
+		{Timestamp: 2000, RequestorIPv4: 0x01020304, Summary: "VPS stopped"},
+		{Timestamp: 3000, RequestorIPv4: 0x05060708, Summary: "Root password reset"},
+	}}
+	trusted, err := config.ParseIPv4Nets([]string{"203.0.113.0/24"})
+	if err != nil {
+		t.Fatalf("ParseIPv4Nets() error = %v", err)
+	}
+
+	notifier := &fakeEventNotifier{}
+	out := captureStdout(t, func() {
+		err := runAuditCheck(context.Background(), api, "web", trusted, &auditFilter{}, notifier)
+		if err == nil || err.Error() != "2 audit log entries from untrusted addresses" {
+			t.Fatalf("runAuditCheck() error = %v, want 2 untrusted entries", err)
+		}
+	})
+	if !strings.Contains(out, "2 of 3") || strings.Index(out, "5.6.7.8") > strings.Index(out, "1.2.3.4") || strings.Contains(out, "203.0.113.5") {
+		t.Fatalf("output = %s", out)
+	}
+	if len(notifier.events) != 1 || notifier.events[0].Severity != notify.Warning || notifier.events[0].Fields["addresses"] != "5.6.7.8, 1.2.3.4" {
+		t.Fatalf("notifications = %+v", notifier.events)
+	}
+
+	out = captureStdout(t, func() {
+		if err := runAuditCheck(context.Background(), api, "web", trusted, &auditFilter{until: time.Unix(2000, 0)}, nil); err != nil {
+			t.Fatalf("runAuditCheck() error = %v", err)
+		}
+	})
+	if !strings.Contains(out, "1 audit log entry checked") {
+		t.Fatalf("output = %s", out)
+	}
+
+	if err := runAuditCheck(context.Background(), api, "web", nil, &auditFilter{}, nil); err == nil || !strings.Contains(err.Error(), "no trusted CIDRs") {
+		t.Fatalf("runAuditCheck() error = %v, want no trusted CIDRs", err)
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/strahe/bwh/internal/history"
+	"github.com/strahe/bwh/internal/notify"
+	"github.com/strahe/bwh/pkg/client"
+	"github.com/urfave/cli/v3"
+)
+
+// auditMarkFile is the history file holding the audit watch high-water mark.
+const auditMarkFile = "audit-mark.json"
+
+var auditWatchCmd = &cli.Command{
+	Name:  "watch",
+	Usage: "poll the audit log and print entries not seen before",
+	Description: `Polls the audit log every --interval and prints only new entries. The newest
+entry seen is remembered in ~/.bwh/history/<instance>/audit-mark.json (or
+$BWH_HISTORY_DIR), so restarting the watch or running it from cron with --once
+never repeats an entry. The first run records the mark without printing the
+existing entries.
+
+Filters select which new entries are printed; the mark always advances past
+every entry. With --notify, each printed entry is also sent to the named
+notifiers.`,
+	Flags: append([]cli.Flag{
+		&cli.DurationFlag{
+			Name:  "interval",
+			Usage: "delay between polls",
+			Value: 5 * time.Minute,
+		},
+		&cli.BoolFlag{
+			Name:  "once",
+			Usage: "poll once and exit (for cron)",
+		},
+		notifyFlag(),
+	}, auditMatchFlags()...),
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		interval := cmd.Duration("interval")
+		if interval < time.Minute {
+			return fmt.Errorf("--interval must be at least 1m")
+		}
+		filter, err := parseAuditFilter(cmd, time.Now())
+		if err != nil {
+			return err
+		}
+		notifier, err := flagNotifier(cmd)
+		if err != nil {
+			return err
+		}
+
+		bwhClient, resolvedName, err := createBWHClient(cmd)
+		if err != nil {
+			return err
+		}
+		dir, err := history.DefaultDir()
+		if err != nil {
+			return err
+		}
+		markPath := history.FilePath(dir, resolvedName, auditMarkFile)
+
+		if cmd.Bool("once") {
+			_, err := pollAuditLog(ctx, bwhClient, resolvedName, markPath, filter, notifier)
+			return err
+		}
+
+		ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		fmt.Printf("👀 Watching audit log for instance %s every %s (Ctrl+C to stop)\n", resolvedName, interval)
+		for {
+			if _, err := pollAuditLog(ctx, bwhClient, resolvedName, markPath, filter, notifier); err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				fmt.Printf("⚠️  %v\n", err)
+			}
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(interval):
+			}
+		}
+	},
+}
+
+// pollAuditLog fetches the audit log, prints the new entries that match
+// filter, sends them to notifier if there is one, and advances the
+// high-water mark stored at markPath. It returns the new entries that were
+// printed.
+func pollAuditLog(ctx context.Context, api auditLogAPI, resolvedName, markPath string, filter *auditFilter, notifier eventNotifier) ([]client.AuditLogEntry, error) {
+	auditLog, err := api.GetAuditLog(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audit log: %w", err)
+	}
+	mark, err := history.LoadMark(markPath)
+	if err != nil {
+		return nil, err
+	}
+
+	fresh, next := newAuditEntries(auditLog.LogEntries, mark)
+	if err := history.SaveMark(markPath, next); err != nil {
+		return nil, err
+	}
+
+	if mark == nil {
+		fmt.Printf("📌 %s: recorded audit log high-water mark (%d existing entries not shown)\n", resolvedName, len(auditLog.LogEntries))
+		return nil, nil
+	}
+
+	matched := filter.apply(fresh)
+	for _, e := range matched {
+		fmt.Printf("🆕 [%s] %s %-15s | %s\n", time.Unix(e.Timestamp, 0).Local().Format("2006-01-02 15:04:05"), resolvedName, intToIP(e.RequestorIPv4), e.Summary)
+		sendNotification(ctx, notifier, notify.Event{
+			Title:    "New audit log entry: " + e.Summary,
+			Message:  fmt.Sprintf("%s from %s", e.Summary, intToIP(e.RequestorIPv4)),
+			Severity: notify.Info,
+			Instance: resolvedName,
+			Source:   "audit",
+			Time:     time.Unix(e.Timestamp, 0),
+			Fields:   map[string]string{"ip": intToIP(e.RequestorIPv4), "type": strconv.Itoa(e.Type)},
+		})
+	}
+	return matched, nil
+}
+
+// newAuditEntries returns the entries newer than mark, oldest first, and the
+// mark to store next. A nil mark treats every entry as already seen.
+func newAuditEntries(entries []client.AuditLogEntry, mark *history.Mark) ([]client.AuditLogEntry, *history.Mark) {
+	sorted := append([]client.AuditLogEntry(nil), entries...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Timestamp < sorted[j].Timestamp
+	})
+
+	next := &history.Mark{}
+	seen := map[string]bool{}
+	if mark != nil {
+		next.Timestamp = mark.Timestamp
+		next.Keys = append(next.Keys, mark.Keys...)
+		for _, k := range mark.Keys {
+			seen[k] = true
+		}
+	}
+
+	var fresh []client.AuditLogEntry
+	for _, e := range sorted {
+		key := auditEntryKey(e)
+		if mark != nil && (e.Timestamp < mark.Timestamp || (e.Timestamp == mark.Timestamp && seen[key])) {
+			continue
+		}
+		if mark != nil {
+			fresh = append(fresh, e)
+		}
+		if e.Timestamp > next.Timestamp {
+			next.Timestamp = e.Timestamp
+			next.Keys = nil
+		}
+		if e.Timestamp == next.Timestamp {
+			next.Keys = append(next.Keys, key)
+		}
+	}
+	return fresh, next
+}
+
+// auditEntryKey identifies an audit entry among entries with the same
+// timestamp.
+func auditEntryKey(e client.AuditLogEntry) string {
+	sum := sha256.Sum256([]byte(strconv.FormatInt(e.Timestamp, 10) + "|" + strconv.FormatUint(uint64(e.RequestorIPv4), 10) + "|" + strconv.Itoa(e.Type) + "|" + e.Summary))
+	return hex.EncodeToString(sum[:8])
+}
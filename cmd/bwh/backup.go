@@ -100,11 +100,12 @@ func displayBackupsCompact(backups []client.BackupInfo) {
 }
 
 var backupCopyToSnapshotCmd = &cli.Command{
-	Name:      "copy-to-snapshot",
-	Aliases:   []string{"cts"},
-	Usage:     "copy a backup to a restorable snapshot",
-	ArgsUsage: "<backup_token>",
-	Flags:     writeFlags(),
+	Name:          "copy-to-snapshot",
+	Aliases:       []string{"cts"},
+	Usage:         "copy a backup to a restorable snapshot",
+	ArgsUsage:     "<backup_token>",
+	ShellComplete: completeArgs(fromAPI(backupCompletions)),
+	Flags:         waitWriteFlags(),
 	Action: func(ctx context.Context, cmd *cli.Command) error {
 		if cmd.Args().Len() != 1 {
 			return fmt.Errorf("backup token is required")
@@ -116,7 +117,7 @@ var backupCopyToSnapshotCmd = &cli.Command{
 			return err
 		}
 
-		return runBackupCopyToSnapshot(ctx, bwhClient, resolvedName, backupToken, cmd.Bool("dry-run"), skipConfirm(cmd), promptConfirmation)
+		return runBackupCopyToSnapshot(ctx, bwhClient, resolvedName, backupToken, cmd.Bool("dry-run"), skipConfirm(cmd), promptConfirmation, newUnlockWait(cmd, bwhClient, resolvedName))
 	},
 }
 
@@ -125,7 +126,7 @@ type backupCopyAPI interface {
 	CopyBackupToSnapshot(context.Context, string) error
 }
 
-func runBackupCopyToSnapshot(ctx context.Context, api backupCopyAPI, resolvedName, backupToken string, dryRun, skipConfirm bool, confirm confirmationFunc) error {
+func runBackupCopyToSnapshot(ctx context.Context, api backupCopyAPI, resolvedName, backupToken string, dryRun, skipConfirm bool, confirm confirmationFunc, wait unlockWaitFunc) error {
 	if err := validateBackupToken(backupToken); err != nil {
 		return err
 	}
@@ -173,5 +174,5 @@ func runBackupCopyToSnapshot(ctx context.Context, api backupCopyAPI, resolvedNam
 	fmt.Printf("✅ Backup successfully copied to snapshot\n")
 	fmt.Printf("💡 Use 'bwh snapshot list' to see the new snapshot\n")
 
-	return nil
+	return wait.run(ctx)
 }
@@ -0,0 +1,429 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/strahe/bwh/internal/notify"
+	"github.com/strahe/bwh/pkg/client"
+	"github.com/urfave/cli/v3"
+)
+
+// checkStatus is a Nagios plugin state. The values are the plugin exit codes.
+type checkStatus int
+
+const (
+	checkOK checkStatus = iota
+	checkWarning
+	checkCritical
+	checkUnknown
+)
+
+func (s checkStatus) String() string {
+	switch s {
+	case checkOK:
+		return "OK"
+	case checkWarning:
+		return "WARNING"
+	case checkCritical:
+		return "CRITICAL"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// severity orders states as CRITICAL > UNKNOWN > WARNING > OK, so that a
+// probe that could not run is not hidden by a warning.
+func (s checkStatus) severity() int {
+	switch s {
+	case checkWarning:
+		return 1
+	case checkUnknown:
+		return 2
+	case checkCritical:
+		return 3
+	default:
+		return 0
+	}
+}
+
+// checkProbes lists the probe names in output order.
+var checkProbes = []string{"running", "throttle", "nullroute", "abuse", "bandwidth", "disk", "api"}
+
+var checkCmd = &cli.Command{
+	Name:  "check",
+	Usage: "run monitoring probes with Nagios-compatible output",
+	Description: `Runs the selected probes and prints one Nagios plugin status line with
+performance data, followed by one line per probe. The exit status is 0 (OK),
+1 (WARNING), 2 (CRITICAL) or 3 (UNKNOWN), so the command can be used directly
+as a Nagios, Icinga, Zabbix or Sensu check.
+
+Probes:
+  running    VPS power state; stopped is CRITICAL
+  throttle   CPU or disk throttling; WARNING while throttled
+  nullroute  nullrouted IPs; CRITICAL while any IP is nullrouted
+  abuse      suspension (CRITICAL) or unresolved policy violation (WARNING)
+  bandwidth  monthly data transfer used, in percent
+  disk       disk space used, in percent (KVM only)
+  api        remaining API points in the 15 minute and 24 hour windows
+
+With --notify, every run that is not OK is also sent to the named notifiers:
+WARNING and UNKNOWN as warnings, CRITICAL as critical.`,
+	Flags: []cli.Flag{
+		&cli.StringSliceFlag{
+			Name:  "probe",
+			Usage: "probe to run (repeatable): " + strings.Join(checkProbes, ", ") + " (default: all)",
+		},
+		&cli.FloatFlag{
+			Name:  "bandwidth-warning",
+			Usage: "bandwidth percent used that is WARNING",
+			Value: 80,
+		},
+		&cli.FloatFlag{
+			Name:  "bandwidth-critical",
+			Usage: "bandwidth percent used that is CRITICAL",
+			Value: 95,
+		},
+		&cli.FloatFlag{
+			Name:  "disk-warning",
+			Usage: "disk percent used that is WARNING",
+			Value: 80,
+		},
+		&cli.FloatFlag{
+			Name:  "disk-critical",
+			Usage: "disk percent used that is CRITICAL",
+			Value: 90,
+		},
+		&cli.IntFlag{
+			Name:  "api-warning",
+			Usage: "remaining API points at or below which the check is WARNING",
+			Value: 50,
+		},
+		&cli.IntFlag{
+			Name:  "api-critical",
+			Usage: "remaining API points at or below which the check is CRITICAL",
+			Value: 10,
+		},
+		notifyFlag(),
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		opts := checkOptions{
+			probes:            cmd.StringSlice("probe"),
+			bandwidthWarning:  cmd.Float("bandwidth-warning"),
+			bandwidthCritical: cmd.Float("bandwidth-critical"),
+			diskWarning:       cmd.Float("disk-warning"),
+			diskCritical:      cmd.Float("disk-critical"),
+			apiWarning:        cmd.Int("api-warning"),
+			apiCritical:       cmd.Int("api-critical"),
+		}
+		if err := opts.validate(); err != nil {
+			fmt.Printf("BWH UNKNOWN - %v\n", err)
+			return cli.Exit("", int(checkUnknown))
+		}
+
+		notifier, err := flagNotifier(cmd)
+		if err != nil {
+			fmt.Printf("BWH UNKNOWN - %v\n", err)
+			return cli.Exit("", int(checkUnknown))
+		}
+		bwhClient, resolvedName, err := createBWHClient(cmd)
+		if err != nil {
+			fmt.Printf("BWH UNKNOWN - %v\n", err)
+			return cli.Exit("", int(checkUnknown))
+		}
+
+		if status := runCheck(ctx, bwhClient, resolvedName, opts, notifier); status != checkOK {
+			return cli.Exit("", int(status))
+		}
+		return nil
+	},
+}
+
+type checkOptions struct {
+	probes            []string
+	bandwidthWarning  float64
+	bandwidthCritical float64
+	diskWarning       float64
+	diskCritical      float64
+	apiWarning        int
+	apiCritical       int
+}
+
+func (o *checkOptions) validate() error {
+	if len(o.probes) == 0 {
+		o.probes = checkProbes
+	}
+	for _, p := range o.probes {
+		if !slices.Contains(checkProbes, p) {
+			return fmt.Errorf("unknown probe %q (want %s)", p, strings.Join(checkProbes, ", "))
+		}
+	}
+	if o.bandwidthWarning > o.bandwidthCritical {
+		return fmt.Errorf("--bandwidth-warning must not be above --bandwidth-critical")
+	}
+	if o.diskWarning > o.diskCritical {
+		return fmt.Errorf("--disk-warning must not be above --disk-critical")
+	}
+	if o.apiWarning < o.apiCritical {
+		return fmt.Errorf("--api-warning must not be below --api-critical")
+	}
+	return nil
+}
+
+func (o *checkOptions) has(probe string) bool {
+	return slices.Contains(o.probes, probe)
+}
+
+// checkResult is the outcome of one probe.
+type checkResult struct {
+	probe   string
+	status  checkStatus
+	message string
+	perf    []string
+}
+
+type checkAPI interface {
+	GetServiceInfo(context.Context) (*client.ServiceInfo, error)
+	GetLiveServiceInfo(context.Context) (*client.LiveServiceInfo, error)
+	GetRateLimitStatus(context.Context) (*client.RateLimitStatus, error)
+}
+
+// runCheck runs the probes in opts, prints the plugin output and returns the
+// overall state. A state other than OK is also sent to notifier if there is
+// one.
+func runCheck(ctx context.Context, api checkAPI, resolvedName string, opts checkOptions, notifier eventNotifier) checkStatus {
+	// Live info includes the service info, so it is fetched at most once and
+	// only when a probe needs live data.
+	var info *client.ServiceInfo
+	var live *client.LiveServiceInfo
+	var infoErr error
+	if opts.has("running") || opts.has("throttle") || opts.has("disk") {
+		if live, infoErr = api.GetLiveServiceInfo(ctx); infoErr == nil {
+			info = &live.ServiceInfo
+		}
+	} else if opts.has("nullroute") || opts.has("abuse") || opts.has("bandwidth") {
+		info, infoErr = api.GetServiceInfo(ctx)
+	}
+
+	var results []checkResult
+	for _, probe := range checkProbes {
+		if !opts.has(probe) {
+			continue
+		}
+		var r checkResult
+		switch {
+		case probe == "api":
+			rate, err := api.GetRateLimitStatus(ctx)
+			if err != nil {
+				r = checkResult{status: checkUnknown, message: fmt.Sprintf("failed to get rate limit status: %v", err)}
+				break
+			}
+			r = checkAPIBudget(rate, opts)
+		case infoErr != nil:
+			r = checkResult{status: checkUnknown, message: fmt.Sprintf("failed to get service info: %v", infoErr)}
+		case probe == "running":
+			r = checkRunning(live)
+		case probe == "throttle":
+			r = checkThrottle(live)
+		case probe == "nullroute":
+			r = checkNullroute(info)
+		case probe == "abuse":
+			r = checkAbuse(info)
+		case probe == "bandwidth":
+			r = checkBandwidth(info, opts)
+		case probe == "disk":
+			r = checkDisk(live, opts)
+		}
+		r.probe = probe
+		results = append(results, r)
+	}
+
+	status := checkOK
+	var problems, perf []string
+	for _, r := range results {
+		if r.status.severity() > status.severity() {
+			status = r.status
+		}
+		if r.status != checkOK {
+			problems = append(problems, r.probe+": "+r.message)
+		}
+		perf = append(perf, r.perf...)
+	}
+
+	summary := fmt.Sprintf("%d probe%s OK", len(results), pluralSuffix(len(results), "", "s"))
+	if len(problems) > 0 {
+		summary = strings.Join(problems, "; ")
+	}
+	line := fmt.Sprintf("BWH %s - %s: %s", status, resolvedName, summary)
+	if len(perf) > 0 {
+		line += " | " + strings.Join(perf, " ")
+	}
+	fmt.Println(line)
+	for _, r := range results {
+		fmt.Printf("[%s] %s: %s\n", r.status, r.probe, r.message)
+	}
+
+	if status != checkOK {
+		severity := notify.Warning
+		if status == checkCritical {
+			severity = notify.Critical
+		}
+		sendNotification(ctx, notifier, notify.Event{
+			Title:    fmt.Sprintf("BWH check %s", status),
+			Message:  summary,
+			Severity: severity,
+			Instance: resolvedName,
+			Source:   "check",
+		})
+	}
+	return status
+}
+
+func checkRunning(live *client.LiveServiceInfo) checkResult {
+	switch state := client.PowerState(live); state {
+	case client.PowerStateRunning:
+		return checkResult{status: checkOK, message: "VPS is running", perf: []string{perfValue("running", 1, "", "", "", "0", "1")}}
+	case client.PowerStateStopped:
+		return checkResult{status: checkCritical, message: "VPS is stopped", perf: []string{perfValue("running", 0, "", "", "", "0", "1")}}
+	case client.PowerStateUnknown:
+		return checkResult{status: checkUnknown, message: "power state not reported"}
+	default:
+		return checkResult{status: checkWarning, message: "VPS is " + state, perf: []string{perfValue("running", 0, "", "", "", "0", "1")}}
+	}
+}
+
+func checkThrottle(live *client.LiveServiceInfo) checkResult {
+	cpu, disk := live.IsCPUThrottled.Value != 0, live.IsDiskThrottled.Value != 0
+	perf := []string{
+		perfValue("cpu_throttled", boolValue(cpu), "", "", "", "0", "1"),
+		perfValue("disk_throttled", boolValue(disk), "", "", "", "0", "1"),
+	}
+	var throttled []string
+	if cpu {
+		throttled = append(throttled, "CPU")
+	}
+	if disk {
+		throttled = append(throttled, "disk")
+	}
+	if len(throttled) > 0 {
+		return checkResult{status: checkWarning, message: strings.Join(throttled, " and ") + " throttled", perf: perf}
+	}
+	return checkResult{status: checkOK, message: "not throttled", perf: perf}
+}
+
+func checkNullroute(info *client.ServiceInfo) checkResult {
+	perf := []string{perfValue("nullrouted_ips", float64(len(info.IPNullroutes)), "", "", "0", "0", "")}
+	if len(info.IPNullroutes) == 0 {
+		return checkResult{status: checkOK, message: "no nullrouted IPs", perf: perf}
+	}
+	ips := slices.Sorted(maps.Keys(info.IPNullroutes))
+	return checkResult{status: checkCritical, message: "nullrouted: " + strings.Join(ips, ", "), perf: perf}
+}
+
+func checkAbuse(info *client.ServiceInfo) checkResult {
+	switch {
+	case info.Suspended:
+		return checkResult{status: checkCritical, message: "VPS is suspended"}
+	case info.PolicyViolation:
+		return checkResult{status: checkWarning, message: "unresolved policy violation"}
+	default:
+		return checkResult{status: checkOK, message: "not suspended, no policy violations"}
+	}
+}
+
+func checkBandwidth(info *client.ServiceInfo, opts checkOptions) checkResult {
+	multiplier := int64(info.MonthlyDataMultiplier)
+	if multiplier <= 0 {
+		multiplier = 1
+	}
+	used, limit := info.DataCounter*multiplier, info.PlanMonthlyData*multiplier
+	if limit <= 0 {
+		return checkResult{status: checkUnknown, message: "monthly data allowance not reported"}
+	}
+	percent := float64(used) / float64(limit) * 100
+	return checkResult{
+		status:  thresholdStatus(percent, opts.bandwidthWarning, opts.bandwidthCritical),
+		message: fmt.Sprintf("%.1f%% used (%s of %s)", percent, formatBytes(used), formatBytes(limit)),
+		perf: []string{
+			perfValue("bandwidth", percent, "%", formatPerfFloat(opts.bandwidthWarning), formatPerfFloat(opts.bandwidthCritical), "0", "100"),
+			perfValue("data_used", float64(used), "B", "", "", "0", strconv.FormatInt(limit, 10)),
+		},
+	}
+}
+
+func checkDisk(live *client.LiveServiceInfo, opts checkOptions) checkResult {
+	quota := live.VeDiskQuotaGB.Value << 30
+	if quota <= 0 {
+		return checkResult{status: checkUnknown, message: "disk usage not reported (KVM only)"}
+	}
+	used := live.VeUsedDiskSpaceB.Value
+	percent := float64(used) / float64(quota) * 100
+	return checkResult{
+		status:  thresholdStatus(percent, opts.diskWarning, opts.diskCritical),
+		message: fmt.Sprintf("%.1f%% used (%s of %s)", percent, formatBytes(used), formatBytes(quota)),
+		perf: []string{
+			perfValue("disk", percent, "%", formatPerfFloat(opts.diskWarning), formatPerfFloat(opts.diskCritical), "0", "100"),
+			perfValue("disk_used", float64(used), "B", "", "", "0", strconv.FormatInt(quota, 10)),
+		},
+	}
+}
+
+func checkAPIBudget(rate *client.RateLimitStatus, opts checkOptions) checkResult {
+	remaining := rate.RemainingPoints15Min
+	if rate.RemainingPoints24H < remaining {
+		remaining = rate.RemainingPoints24H
+	}
+	status := checkOK
+	switch {
+	case remaining <= opts.apiCritical:
+		status = checkCritical
+	case remaining <= opts.apiWarning:
+		status = checkWarning
+	}
+	// Nagios ranges alert outside start:end, so "N:" alerts below N.
+	warn, crit := strconv.Itoa(opts.apiWarning+1)+":", strconv.Itoa(opts.apiCritical+1)+":"
+	return checkResult{
+		status:  status,
+		message: fmt.Sprintf("%d points left in 15 min, %d in 24 h", rate.RemainingPoints15Min, rate.RemainingPoints24H),
+		perf: []string{
+			perfValue("api_remaining_15min", float64(rate.RemainingPoints15Min), "", warn, crit, "0", ""),
+			perfValue("api_remaining_24h", float64(rate.RemainingPoints24H), "", warn, crit, "0", ""),
+		},
+	}
+}
+
+// thresholdStatus returns the state of a value that alerts when it reaches
+// warning or critical.
+func thresholdStatus(value, warning, critical float64) checkStatus {
+	switch {
+	case value >= critical:
+		return checkCritical
+	case value >= warning:
+		return checkWarning
+	default:
+		return checkOK
+	}
+}
+
+// perfValue formats one Nagios performance data item:
+// 'label'=value[uom];[warn];[crit];[min];[max]
+func perfValue(label string, value float64, uom, warn, crit, minValue, maxValue string) string {
+	return fmt.Sprintf("%s=%s%s;%s;%s;%s;%s", label, formatPerfFloat(value), uom, warn, crit, minValue, maxValue)
+}
+
+// formatPerfFloat formats a value with at most two decimals.
+func formatPerfFloat(v float64) string {
+	return strconv.FormatFloat(math.Round(v*100)/100, 'f', -1, 64)
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/strahe/bwh/internal/notify"
+	"github.com/strahe/bwh/pkg/client"
+)
+
+type fakeCheckAPI struct {
+	live    *client.LiveServiceInfo
+	rate    *client.RateLimitStatus
+	infoErr error
+	calls   []string
+}
+
+func (f *fakeCheckAPI) GetServiceInfo(context.Context) (*client.ServiceInfo, error) {
+	f.calls = append(f.calls, "info")
+	if f.infoErr != nil {
+		return nil, f.infoErr
+	}
+	return &f.live.ServiceInfo, nil
+}
+
+func (f *fakeCheckAPI) GetLiveServiceInfo(context.Context) (*client.LiveServiceInfo, error) {
+	f.calls = append(f.calls, "live")
+	if f.infoErr != nil {
+		return nil, f.infoErr
+	}
+	return f.live, nil
+}
+
+func (f *fakeCheckAPI) GetRateLimitStatus(context.Context) (*client.RateLimitStatus, error) {
+	f.calls = append(f.calls, "rate")
+	return f.rate, nil
+}
+
+func healthyCheckAPI() *fakeCheckAPI {
+	const gb = int64(1) << 30
+	return &fakeCheckAPI{
+		live: &client.LiveServiceInfo{
+			ServiceInfo: client.ServiceInfo{
+				PlanMonthlyData:       1000 * gb,
+				DataCounter:           250 * gb,
+				MonthlyDataMultiplier: 1,
+			},
+			VeStatus:         "Running",
+			VeDiskQuotaGB:    client.FlexibleInt{Value: 20},
+			VeUsedDiskSpaceB: client.FlexibleInt{Value: 5 * gb},
+		},
+		rate: &client.RateLimitStatus{RemainingPoints15Min: 900, RemainingPoints24H: 9000},
+	}
+}
+
+func defaultCheckOptions(probes ...string) checkOptions {
+	opts := checkOptions{
+		probes:            probes,
+		bandwidthWarning:  80,
+		bandwidthCritical: 95,
+		diskWarning:       80,
+		diskCritical:      90,
+		apiWarning:        50,
+		apiCritical:       10,
+	}
+	if err := opts.validate(); err != nil {
+		panic(err)
+	}
+	return opts
+}
+
+func TestRunCheck(t *testing.T) {
+	t.Run("all probes OK", func(t *testing.T) {
+		api := healthyCheckAPI()
+		notifier := &fakeEventNotifier{}
+		var status checkStatus
+		out := captureStdout(t, func() {
+			status = runCheck(context.Background(), api, "web", defaultCheckOptions(), notifier)
+		})
+		if status != checkOK || len(notifier.events) != 0 {
+			t.Fatalf("status = %v, notifications = %v, output:\n%s", status, notifier.events, out)
+		}
+		first := strings.SplitN(out, "\n", 2)[0]
+		for _, want := range []string{
+			"BWH OK - web: 7 probes OK | ",
+			"running=1;;;0;1",
+			"bandwidth=25%;80;95;0;100",
+			"disk=25%;80;90;0;100",
+			"api_remaining_24h=9000;51:;11:;0;",
+		} {
+			if !strings.Contains(first, want) {
+				t.Fatalf("status line missing %q:\n%s", want, first)
+			}
+		}
+		if strings.Join(api.calls, ",") != "live,rate" {
+			t.Fatalf("calls = %v, want one live info and one rate limit call", api.calls)
+		}
+	})
+
+	t.Run("worst state wins", func(t *testing.T) {
+		api := healthyCheckAPI()
+		api.live.IsCPUThrottled = client.FlexibleInt{Value: 1}
+		api.live.IPNullroutes = client.IPNullroutes{"203.0.113.5": {}}
+		notifier := &fakeEventNotifier{}
+		var status checkStatus
+		out := captureStdout(t, func() {
+			status = runCheck(context.Background(), api, "web", defaultCheckOptions(), notifier)
+		})
+		if status != checkCritical || !strings.HasPrefix(out, "BWH CRITICAL - web: throttle: CPU throttled; nullroute: nullrouted: 203.0.113.5 |") {
+			t.Fatalf("status = %v, output:\n%s", status, out)
+		}
+		if len(notifier.events) != 1 || notifier.events[0].Severity != notify.Critical || notifier.events[0].Message != "throttle: CPU throttled; nullroute: nullrouted: 203.0.113.5" {
+			t.Fatalf("notifications = %+v", notifier.events)
+		}
+	})
+
+	t.Run("thresholds", func(t *testing.T) {
+		api := healthyCheckAPI()
+		api.live.DataCounter = api.live.PlanMonthlyData * 85 / 100
+		api.rate.RemainingPoints15Min = 30
+		var status checkStatus
+		out := captureStdout(t, func() {
+			status = runCheck(context.Background(), api, "web", defaultCheckOptions("bandwidth", "api"), nil)
+		})
+		if status != checkWarning || !strings.Contains(out, "[WARNING] bandwidth: 85.0% used") || !strings.Contains(out, "[WARNING] api: 30 points left") {
+			t.Fatalf("status = %v, output:\n%s", status, out)
+		}
+		if strings.Join(api.calls, ",") != "info,rate" {
+			t.Fatalf("calls = %v, want service info without live info", api.calls)
+		}
+	})
+
+	t.Run("API failure is UNKNOWN", func(t *testing.T) {
+		api := healthyCheckAPI()
+		api.infoErr = errors.New("timeout")
+		var status checkStatus
+		out := captureStdout(t, func() {
+			status = runCheck(context.Background(), api, "web", defaultCheckOptions("running", "abuse"), nil)
+		})
+		if status != checkUnknown || !strings.Contains(out, "[UNKNOWN] abuse: failed to get service info: timeout") {
+			t.Fatalf("status = %v, output:\n%s", status, out)
+		}
+	})
+}
+
+func TestCheckOptionsValidate(t *testing.T) {
+	opts := checkOptions{probes: []string{"cpu"}}
+	if err := opts.validate(); err == nil || !strings.Contains(err.Error(), `unknown probe "cpu"`) {
+		t.Fatalf("validate() error = %v", err)
+	}
+	opts = checkOptions{bandwidthWarning: 90, bandwidthCritical: 80}
+	if err := opts.validate(); err == nil {
+		t.Fatal("validate() error = nil, want threshold order error")
+	}
+}
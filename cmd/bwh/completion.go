@@ -0,0 +1,333 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/strahe/bwh/internal/config"
+	"github.com/strahe/bwh/internal/vault"
+	"github.com/strahe/bwh/pkg/client"
+	"github.com/urfave/cli/v3"
+)
+
+// completionCacheTTL is how long values fetched from the API for shell
+// completion are reused, so pressing tab repeatedly stays fast.
+const completionCacheTTL = 2 * time.Minute
+
+// completionTimeout bounds the API call of a completion; a slow API yields
+// no suggestions rather than a hanging shell.
+const completionTimeout = 5 * time.Second
+
+// completion is a value suggested by shell completion.
+type completion struct {
+	Value       string `json:"value"`
+	Description string `json:"description,omitempty"`
+}
+
+// completionSource fetches the completions of one kind, such as snapshot
+// file names, from the API.
+type completionSource struct {
+	kind  string
+	fetch func(ctx context.Context, api *client.Client) ([]completion, error)
+}
+
+var (
+	snapshotCompletions = completionSource{"snapshots", func(ctx context.Context, api *client.Client) ([]completion, error) {
+		resp, err := api.ListSnapshots(ctx)
+		if err != nil {
+			return nil, err
+		}
+		values := make([]completion, 0, len(resp.Snapshots))
+		for _, snapshot := range resp.Snapshots {
+			values = append(values, completion{snapshot.FileName, snapshot.Description})
+		}
+		return values, nil
+	}}
+
+	backupCompletions = completionSource{"backups", func(ctx context.Context, api *client.Client) ([]completion, error) {
+		resp, err := api.ListBackups(ctx)
+		if err != nil {
+			return nil, err
+		}
+		values := make([]completion, 0, len(resp.Backups))
+		for _, token := range slices.Sorted(maps.Keys(resp.Backups)) {
+			backup := resp.Backups[token]
+			values = append(values, completion{token, fmt.Sprintf("%s, %s", backup.OS, time.Unix(backup.Timestamp, 0).Format("2006-01-02 15:04"))})
+		}
+		return values, nil
+	}}
+
+	osCompletions = completionSource{"os", func(ctx context.Context, api *client.Client) ([]completion, error) {
+		resp, err := api.GetAvailableOS(ctx)
+		if err != nil {
+			return nil, err
+		}
+		values := make([]completion, 0, len(resp.Templates))
+		for _, template := range resp.Templates {
+			values = append(values, completion{Value: template})
+		}
+		return values, nil
+	}}
+
+	isoCompletions = completionSource{"isos", func(ctx context.Context, api *client.Client) ([]completion, error) {
+		info, err := api.GetServiceInfo(ctx)
+		if err != nil {
+			return nil, err
+		}
+		values := make([]completion, 0, len(info.AvailableISOs))
+		for _, iso := range info.AvailableISOs {
+			values = append(values, completion{Value: iso})
+		}
+		return values, nil
+	}}
+
+	locationCompletions = completionSource{"locations", func(ctx context.Context, api *client.Client) ([]completion, error) {
+		resp, err := api.GetMigrateLocations(ctx)
+		if err != nil {
+			return nil, err
+		}
+		values := make([]completion, 0, len(resp.Locations))
+		for _, location := range resp.Locations {
+			if location != resp.CurrentLocation {
+				values = append(values, completion{location, resp.Descriptions[location]})
+			}
+		}
+		return values, nil
+	}}
+
+	ipv6Completions = completionSource{"ipv6", func(ctx context.Context, api *client.Client) ([]completion, error) {
+		info, err := api.GetServiceInfo(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var values []completion
+		for _, ip := range info.IPAddresses {
+			if strings.Contains(ip, ":") {
+				values = append(values, completion{Value: ip})
+			}
+		}
+		return values, nil
+	}}
+
+	notificationCompletions = completionSource{"notifications", func(ctx context.Context, api *client.Client) ([]completion, error) {
+		resp, err := api.GetNotificationPreferences(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var values []completion
+		for _, category := range slices.Sorted(maps.Keys(resp.EmailPreferences)) {
+			prefs := resp.EmailPreferences[category]
+			for _, id := range slices.Sorted(maps.Keys(prefs)) {
+				values = append(values, completion{id, prefs[id].FriendlyDescription})
+			}
+		}
+		return values, nil
+	}}
+)
+
+// argCompletion completes one positional argument.
+type argCompletion func(ctx context.Context, cmd *cli.Command) []completion
+
+// fromAPI completes an argument with values from source.
+func fromAPI(source completionSource) argCompletion {
+	return func(ctx context.Context, cmd *cli.Command) []completion {
+		return fetchCompletions(ctx, cmd, source)
+	}
+}
+
+// snapshotIndexes completes an argument that takes a snapshot file name or
+// its 1-based index in 'bwh snapshot list'.
+func snapshotIndexes(ctx context.Context, cmd *cli.Command) []completion {
+	snapshots := fetchCompletions(ctx, cmd, snapshotCompletions)
+	values := slices.Clone(snapshots)
+	for i, snapshot := range snapshots {
+		values = append(values, completion{strconv.Itoa(i + 1), snapshot.Value})
+	}
+	return values
+}
+
+// fixedValues completes an argument with a fixed set of values.
+func fixedValues(values ...string) argCompletion {
+	return func(context.Context, *cli.Command) []completion {
+		completions := make([]completion, 0, len(values))
+		for _, value := range values {
+			completions = append(completions, completion{Value: value})
+		}
+		return completions
+	}
+}
+
+// completeArgs returns a ShellComplete function suggesting values for the
+// positional arguments of a command, one argCompletion per position; nil
+// entries and further positions get the default completion.
+func completeArgs(args ...argCompletion) cli.ShellCompleteFunc {
+	return func(ctx context.Context, cmd *cli.Command) {
+		if flag, ok := completingFlag(cmd); ok || flag != "" {
+			completeDefault(ctx, cmd)
+			return
+		}
+		if n := cmd.Args().Len(); n < len(args) && args[n] != nil {
+			printCompletions(args[n](ctx, cmd))
+			return
+		}
+		completeDefault(ctx, cmd)
+	}
+}
+
+// completeFlag returns a ShellComplete function suggesting values for the
+// named flag from source.
+func completeFlag(name string, source completionSource) cli.ShellCompleteFunc {
+	return func(ctx context.Context, cmd *cli.Command) {
+		if flag, ok := completingFlag(cmd); ok && flag == name {
+			printCompletions(fetchCompletions(ctx, cmd, source))
+			return
+		}
+		completeDefault(ctx, cmd)
+	}
+}
+
+// completeDefault suggests instances after --instance, and flags and
+// subcommands otherwise.
+func completeDefault(ctx context.Context, cmd *cli.Command) {
+	if flag, ok := completingFlag(cmd); ok {
+		if flag == "instance" {
+			if manager, err := config.NewManager(cmd.String("config")); err == nil {
+				for _, candidate := range instanceCompletions(manager) {
+					fmt.Println(candidate)
+				}
+			}
+		}
+		// Other flag values are left to the shell's file completion.
+		return
+	}
+	cli.DefaultCompleteWithFlags(ctx, cmd)
+}
+
+// completingFlag inspects the word before the one being completed. If it is
+// a flag of cmd that takes a value, it returns the flag's name and true. If
+// it looks like a flag but is not one, such as a partially typed flag, it
+// returns that word and false.
+func completingFlag(cmd *cli.Command) (string, bool) {
+	args := os.Args
+	if len(args) < 2 || args[len(args)-1] != "--generate-shell-completion" {
+		return "", false
+	}
+	prev := args[len(args)-2]
+	if !strings.HasPrefix(prev, "-") {
+		return "", false
+	}
+	name := strings.TrimLeft(prev, "-")
+	for _, c := range cmd.Lineage() {
+		for _, flag := range c.Flags {
+			if !slices.Contains(flag.Names(), name) {
+				continue
+			}
+			if v, ok := flag.(cli.DocGenerationFlag); ok && v.TakesValue() {
+				return flag.Names()[0], true
+			}
+			// A boolean flag; the next word is an argument.
+			return "", false
+		}
+	}
+	return prev, false
+}
+
+// printCompletions prints completions for the shell; zsh shows the
+// descriptions next to the values.
+func printCompletions(values []completion) {
+	zsh := strings.HasSuffix(os.Getenv("SHELL"), "zsh")
+	for _, c := range values {
+		switch {
+		case !zsh:
+			fmt.Println(c.Value)
+		case c.Description != "":
+			fmt.Printf("%s:%s\n", strings.ReplaceAll(c.Value, ":", `\:`), c.Description)
+		default:
+			fmt.Println(strings.ReplaceAll(c.Value, ":", `\:`))
+		}
+	}
+}
+
+// fetchCompletions returns the completions of source for the instance the
+// command would use, from the cache if it is fresh. Completion never
+// prompts and never runs an api_key_command helper: an encrypted API key is
+// only used if the vault passphrase is in the environment or the vault agent
+// is running. Errors yield no values.
+func fetchCompletions(ctx context.Context, cmd *cli.Command, source completionSource) []completion {
+	manager, err := config.NewManager(cmd.String("config"))
+	if err != nil {
+		return nil
+	}
+	manager.SetVaultKeyFunc(vault.KeyFunc(nil))
+	found, name, err := manager.FindInstance(cmd.String("instance"))
+	if err != nil || found.APIKeyCommand != "" {
+		return nil
+	}
+
+	path := filepath.Join(completionCacheDir(manager, found.VeID), source.kind+".json")
+	if values, ok := loadCompletionCache(path); ok {
+		return values
+	}
+
+	instance, err := manager.OpenInstance(name)
+	if err != nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, completionTimeout)
+	defer cancel()
+	values, err := source.fetch(ctx, newInstanceClient(instance))
+	if err != nil {
+		return nil
+	}
+	saveCompletionCache(path, values)
+	return values
+}
+
+// completionCacheDir returns the cache directory for the VPS with veid. It
+// sits next to the config file and is keyed by the config path as well, so
+// that two configs naming different servers alike never share entries.
+func completionCacheDir(manager *config.Manager, veid string) string {
+	configPath := manager.Path()
+	if abs, err := filepath.Abs(configPath); err == nil {
+		configPath = abs
+	}
+	sum := sha256.Sum256([]byte(configPath + "\x00" + veid))
+	return filepath.Join(filepath.Dir(configPath), "cache", "completion", hex.EncodeToString(sum[:8]))
+}
+
+func loadCompletionCache(path string) ([]completion, bool) {
+	stat, err := os.Stat(path)
+	if err != nil || time.Since(stat.ModTime()) > completionCacheTTL {
+		return nil, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	var values []completion
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, false
+	}
+	return values, true
+}
+
+func saveCompletionCache(path string, values []completion) {
+	data, err := json.Marshal(values)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return
+	}
+	os.WriteFile(path, data, 0o600) //nolint:errcheck
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/strahe/bwh/internal/config"
+	"github.com/urfave/cli/v3"
+)
+
+func TestShellCompletion(t *testing.T) {
+	var snapshotCalls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/snapshot/list"):
+			snapshotCalls.Add(1)
+			w.Write([]byte(`{"error":0,"snapshots":[{"fileName":"a.tar.gz","description":"weekly"},{"fileName":"b.tar.gz"}]}`)) //nolint:errcheck
+		case strings.HasSuffix(r.URL.Path, "/getAvailableOS"):
+			w.Write([]byte(`{"error":0,"templates":["debian-12-x86_64","ubuntu-24.04-x86_64"]}`)) //nolint:errcheck
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("SHELL", "/bin/bash")
+	t.Setenv(config.NoProjectConfigEnv, "1")
+	t.Setenv("BWH_API_KEY", "env-api-key-123456789")
+	t.Setenv("BWH_VEID", "1")
+	t.Setenv("BWH_ENDPOINT", server.URL)
+
+	complete := func(args ...string) []string {
+		t.Helper()
+		root := &cli.Command{
+			Name:                  "bwh",
+			EnableShellCompletion: true,
+			Flags: []cli.Flag{
+				&cli.StringFlag{Name: "config"},
+				&cli.StringFlag{Name: "instance", Aliases: []string{"i"}},
+			},
+			Commands: []*cli.Command{
+				{Name: "delete", ShellComplete: completeArgs(fromAPI(snapshotCompletions)), Flags: writeFlags()},
+				{Name: "pin", ShellComplete: completeArgs(snapshotIndexes)},
+				{Name: "set", ShellComplete: completeArgs(nil, fixedValues("on", "off"))},
+				{Name: "reinstall", ShellComplete: completeFlag("os", osCompletions), Flags: []cli.Flag{&cli.StringFlag{Name: "os"}}},
+			},
+		}
+		setShellComplete(root)
+
+		oldArgs := os.Args
+		os.Args = append([]string{"bwh"}, append(args, "--generate-shell-completion")...)
+		defer func() { os.Args = oldArgs }()
+		out := captureStdout(t, func() {
+			if err := root.Run(context.Background(), os.Args); err != nil {
+				t.Fatalf("Run(%v) error = %v", args, err)
+			}
+		})
+		return strings.Fields(out)
+	}
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"delete"}, "a.tar.gz b.tar.gz"},
+		{[]string{"delete", "--dry-run"}, "a.tar.gz b.tar.gz"},
+		{[]string{"delete", "a.tar.gz"}, "help"},
+		{[]string{"pin"}, "a.tar.gz b.tar.gz 1 2"},
+		{[]string{"set"}, "help"},
+		{[]string{"set", "x"}, "on off"},
+		{[]string{"reinstall", "--os"}, "debian-12-x86_64 ubuntu-24.04-x86_64"},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(complete(tt.args...), " "); got != tt.want {
+			t.Errorf("completion of %v = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+	if calls := snapshotCalls.Load(); calls != 1 {
+		t.Errorf("snapshot list fetched %d times, want 1 (cached)", calls)
+	}
+
+	// A config naming another server "env" must not see the cached values.
+	dir := t.TempDir()
+	other := filepath.Join(dir, "config.yaml")
+	marker := filepath.Join(dir, "helper-ran")
+	if err := os.WriteFile(other, []byte(fmt.Sprintf(`instances:
+  env:
+    api_key: other-api-key-123456789
+    veid: "2"
+    endpoint: %s
+  helper:
+    api_key_command: touch %s
+    veid: "3"
+    endpoint: %s
+`, server.URL, marker, server.URL)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(complete("--config", other, "-i", "env", "delete"), " "); got != "a.tar.gz b.tar.gz" || snapshotCalls.Load() != 2 {
+		t.Errorf("completion with another config = %q after %d calls, want a fresh fetch", got, snapshotCalls.Load())
+	}
+
+	// Tab never runs an api_key_command helper.
+	if got := complete("--config", other, "-i", "helper", "delete"); len(got) != 0 {
+		t.Errorf("completion for a command key = %v, want none", got)
+	}
+	if _, err := os.Stat(marker); !os.IsNotExist(err) {
+		t.Errorf("api_key_command ran during completion: %v", err)
+	}
+}
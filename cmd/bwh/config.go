@@ -0,0 +1,358 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/strahe/bwh/internal/config"
+	"github.com/strahe/bwh/internal/vault"
+	"github.com/urfave/cli/v3"
+	"golang.org/x/term"
+)
+
+var configCmd = &cli.Command{
+	Name:  "config",
+	Usage: "manage the config file",
+	Description: `The config file has a version key. Files written by older versions of bwh
+are upgraded when they are loaded; the original is kept next to it as
+config.yaml.v<old version>.bak.
+
+API keys can be stored encrypted with a passphrase ('bwh config encrypt').
+The vault is unlocked once per command, trying in order:
+
+  1. the BWH_VAULT_PASSPHRASE environment variable
+  2. an agent started by 'bwh config unlock' (socket ~/.bwh/vault.sock or
+     $BWH_VAULT_SOCK)
+  3. a passphrase prompt on the terminal`,
+	Commands: []*cli.Command{
+		configValidateCmd,
+		configEncryptCmd,
+		configDecryptCmd,
+		configUnlockCmd,
+		configLockCmd,
+		configAgentCmd,
+	},
+}
+
+var configValidateCmd = &cli.Command{
+	Name:  "validate",
+	Usage: "check the config files for unknown keys, invalid endpoints, duplicate VEIDs and unused tags",
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		manager, err := createConfigManager(cmd)
+		if err != nil {
+			return err
+		}
+		return runConfigValidate(manager)
+	},
+}
+
+type configValidator interface {
+	Validate() []config.Issue
+}
+
+// runConfigValidate prints the issues found in the config and fails if any
+// of them is an error.
+func runConfigValidate(validator configValidator) error {
+	issues := validator.Validate()
+	if len(issues) == 0 {
+		fmt.Println("✅ Config is valid")
+		return nil
+	}
+
+	errorCount := 0
+	for _, issue := range issues {
+		icon := "⚠️ "
+		if issue.Severity == config.SeverityError {
+			icon = "❌"
+			errorCount++
+		}
+		if issue.File != "" {
+			fmt.Printf("%s %s: %s\n", icon, issue.File, issue.Message)
+		} else {
+			fmt.Printf("%s %s\n", icon, issue.Message)
+		}
+	}
+
+	warnings := len(issues) - errorCount
+	fmt.Printf("\n%d error(s), %d warning(s)\n", errorCount, warnings)
+	if errorCount > 0 {
+		return fmt.Errorf("config has %d error(s)", errorCount)
+	}
+	return nil
+}
+
+var configEncryptCmd = &cli.Command{
+	Name:  "encrypt",
+	Usage: "encrypt the API keys in the config file with a passphrase",
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		manager, err := createConfigManager(cmd)
+		if err != nil {
+			return err
+		}
+		if manager.VaultEnabled() {
+			return fmt.Errorf("API keys are already encrypted")
+		}
+
+		passphrase := os.Getenv(vault.PassphraseEnv)
+		if passphrase == "" {
+			passphrase, err = promptNewPassphrase()
+			if err != nil {
+				return err
+			}
+		}
+
+		count, err := manager.EncryptVault(passphrase)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt config: %w", err)
+		}
+		fmt.Printf("🔐 Encrypted %d API key(s). New nodes are encrypted too.\n", count)
+		fmt.Printf("   Run 'bwh config unlock' to enter the passphrase once per session.\n")
+		return nil
+	},
+}
+
+var configDecryptCmd = &cli.Command{
+	Name:  "decrypt",
+	Usage: "decrypt the API keys in the config file and disable the passphrase",
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		manager, err := createConfigManager(cmd)
+		if err != nil {
+			return err
+		}
+		if !manager.VaultEnabled() {
+			return fmt.Errorf("API keys are not encrypted")
+		}
+
+		count, err := manager.DecryptVault()
+		if err != nil {
+			return fmt.Errorf("failed to decrypt config: %w", err)
+		}
+		fmt.Printf("🔓 Decrypted %d API key(s); they are stored in plain text again\n", count)
+		return nil
+	},
+}
+
+var configUnlockCmd = &cli.Command{
+	Name:  "unlock",
+	Usage: "start an agent that keeps the vault unlocked for a while",
+	Description: `Asks for the passphrase once and starts a background agent that hands the
+derived key to bwh commands over a private socket until --ttl expires or
+'bwh config lock' is run. The agent keeps running after the terminal is
+closed.`,
+	Flags: []cli.Flag{
+		&cli.DurationFlag{
+			Name:  "ttl",
+			Usage: "how long the vault stays unlocked",
+			Value: 15 * time.Minute,
+		},
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		ttl := cmd.Duration("ttl")
+		if ttl <= 0 {
+			return fmt.Errorf("--ttl must be positive")
+		}
+
+		manager, err := createConfigManager(cmd)
+		if err != nil {
+			return err
+		}
+		v := manager.Vault()
+		if v == nil {
+			return fmt.Errorf("API keys are not encrypted. Run 'bwh config encrypt' first")
+		}
+
+		passphrase := os.Getenv(vault.PassphraseEnv)
+		if passphrase == "" {
+			passphrase, err = promptPassphrase("Vault passphrase: ")
+			if err != nil {
+				return err
+			}
+		}
+		key, err := v.DeriveKey(passphrase)
+		if err != nil {
+			return err
+		}
+
+		path, err := vault.SocketPath()
+		if err != nil {
+			return err
+		}
+		pid, err := startVaultAgent(key, ttl)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("🔓 Vault unlocked for %s (agent pid %d, socket %s)\n", ttl, pid, path)
+		return nil
+	},
+}
+
+var configLockCmd = &cli.Command{
+	Name:  "lock",
+	Usage: "stop the agent started by 'bwh config unlock'",
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		path, err := vault.SocketPath()
+		if err != nil {
+			return err
+		}
+		if err := vault.Lock(path); err != nil {
+			if errors.Is(err, vault.ErrNoAgent) {
+				fmt.Println("No vault agent running")
+				return nil
+			}
+			return fmt.Errorf("failed to lock vault: %w", err)
+		}
+		fmt.Println("🔒 Vault locked")
+		return nil
+	},
+}
+
+// configAgentCmd is the agent process started by 'bwh config unlock'. It
+// reads the hex encoded key from stdin and reports readiness on stdout.
+var configAgentCmd = &cli.Command{
+	Name:   "agent",
+	Usage:  "run the vault agent (started by 'bwh config unlock')",
+	Hidden: true,
+	Flags: []cli.Flag{
+		&cli.DurationFlag{
+			Name:  "ttl",
+			Value: 15 * time.Minute,
+		},
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil {
+			return fmt.Errorf("failed to read vault key: %w", err)
+		}
+		key, err := hex.DecodeString(strings.TrimSpace(line))
+		if err != nil {
+			return fmt.Errorf("invalid vault key: %w", err)
+		}
+
+		path, err := vault.SocketPath()
+		if err != nil {
+			return err
+		}
+		ln, err := vault.Listen(path)
+		if err != nil {
+			return err
+		}
+		defer os.Remove(path) //nolint:errcheck
+
+		fmt.Println("ready")
+		os.Stdout.Close() //nolint:errcheck
+
+		ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		return vault.Serve(ctx, ln, key, cmd.Duration("ttl"))
+	},
+}
+
+// startVaultAgent starts 'bwh config agent' in the background, hands it key
+// and waits until its socket is ready.
+func startVaultAgent(key []byte, ttl time.Duration) (int, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return 0, fmt.Errorf("failed to find bwh executable: %w", err)
+	}
+
+	agent := exec.Command(exe, "config", "agent", "--ttl", ttl.String())
+	stdin, err := agent.StdinPipe()
+	if err != nil {
+		return 0, err
+	}
+	stdout, err := agent.StdoutPipe()
+	if err != nil {
+		return 0, err
+	}
+	var stderr strings.Builder
+	agent.Stderr = &stderr
+	if err := agent.Start(); err != nil {
+		return 0, fmt.Errorf("failed to start vault agent: %w", err)
+	}
+
+	fmt.Fprintln(stdin, hex.EncodeToString(key)) //nolint:errcheck
+	stdin.Close()                                //nolint:errcheck
+
+	ready, _ := bufio.NewReader(stdout).ReadString('\n')
+	if strings.TrimSpace(ready) != "ready" {
+		agent.Wait() //nolint:errcheck
+		return 0, fmt.Errorf("vault agent failed to start: %s", strings.TrimSpace(stderr.String()))
+	}
+
+	pid := agent.Process.Pid
+	agent.Process.Release() //nolint:errcheck
+	return pid, nil
+}
+
+// vaultKeyFunc unlocks the vault for commands run from a terminal.
+var vaultKeyFunc = vault.KeyFunc(func() (string, error) {
+	return promptPassphrase("Vault passphrase: ")
+})
+
+// promptNewPassphrase asks for a new passphrase twice.
+func promptNewPassphrase() (string, error) {
+	passphrase, err := promptPassphrase("New vault passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if passphrase == "" {
+		return "", fmt.Errorf("vault passphrase cannot be empty")
+	}
+	confirm, err := promptPassphrase("Repeat passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if confirm != passphrase {
+		return "", fmt.Errorf("passphrases do not match")
+	}
+	return passphrase, nil
+}
+
+// promptPassphrase reads a passphrase from the terminal without echoing it.
+// The prompt goes to stderr so that it does not mix with command output.
+func promptPassphrase(prompt string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", fmt.Errorf("%w: no terminal to ask for the passphrase; set %s or run 'bwh config unlock'", config.ErrVaultLocked, vault.PassphraseEnv)
+	}
+	state, err := term.GetState(fd)
+	if err != nil {
+		return "", fmt.Errorf("failed to hide passphrase input (set %s instead): %w", vault.PassphraseEnv, err)
+	}
+
+	// ReadPassword leaves signals enabled, so restore echo before Ctrl+C
+	// ends the process.
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	done := make(chan struct{})
+	defer func() {
+		signal.Stop(sigs)
+		close(done)
+	}()
+	go func() {
+		select {
+		case <-sigs:
+			term.Restore(fd, state) //nolint:errcheck
+			fmt.Fprintln(os.Stderr) //nolint:errcheck
+			os.Exit(130)
+		case <-done:
+		}
+	}()
+
+	fmt.Fprint(os.Stderr, prompt) //nolint:errcheck
+	passphrase, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr) //nolint:errcheck
+	if err != nil {
+		return "", fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	return string(passphrase), nil
+}
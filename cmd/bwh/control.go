@@ -13,41 +13,41 @@ import (
 var startCmd = &cli.Command{
 	Name:  "start",
 	Usage: "start the VPS",
-	Flags: []cli.Flag{
+	Flags: append([]cli.Flag{
 		dryRunFlag(),
-	},
+	}, powerWaitFlags()...),
 	Action: func(ctx context.Context, cmd *cli.Command) error {
 		bwhClient, resolvedName, err := createBWHClient(cmd)
 		if err != nil {
 			return err
 		}
-		return runVPSAction(ctx, bwhClient, resolvedName, "start", cmd.Bool("dry-run"), true, promptConfirmation)
+		return runVPSAction(ctx, bwhClient, resolvedName, "start", cmd.Bool("dry-run"), true, promptConfirmation, newPowerWait(cmd, bwhClient, resolvedName))
 	},
 }
 
 var stopCmd = &cli.Command{
 	Name:  "stop",
 	Usage: "stop the VPS",
-	Flags: writeFlags(),
+	Flags: writeFlags(powerWaitFlags()...),
 	Action: func(ctx context.Context, cmd *cli.Command) error {
 		bwhClient, resolvedName, err := createBWHClient(cmd)
 		if err != nil {
 			return err
 		}
-		return runVPSAction(ctx, bwhClient, resolvedName, "stop", cmd.Bool("dry-run"), skipConfirm(cmd), promptConfirmation)
+		return runVPSAction(ctx, bwhClient, resolvedName, "stop", cmd.Bool("dry-run"), skipConfirm(cmd), promptConfirmation, newPowerWait(cmd, bwhClient, resolvedName))
 	},
 }
 
 var restartCmd = &cli.Command{
 	Name:  "restart",
 	Usage: "restart the VPS",
-	Flags: writeFlags(),
+	Flags: writeFlags(powerWaitFlags()...),
 	Action: func(ctx context.Context, cmd *cli.Command) error {
 		bwhClient, resolvedName, err := createBWHClient(cmd)
 		if err != nil {
 			return err
 		}
-		return runVPSAction(ctx, bwhClient, resolvedName, "restart", cmd.Bool("dry-run"), skipConfirm(cmd), promptConfirmation)
+		return runVPSAction(ctx, bwhClient, resolvedName, "restart", cmd.Bool("dry-run"), skipConfirm(cmd), promptConfirmation, newPowerWait(cmd, bwhClient, resolvedName))
 	},
 }
 
@@ -64,7 +64,7 @@ var killCmd = &cli.Command{
 		if err != nil {
 			return err
 		}
-		return runVPSAction(ctx, bwhClient, resolvedName, "kill", cmd.Bool("dry-run"), skipConfirmOrForce(cmd), confirmKill)
+		return runVPSAction(ctx, bwhClient, resolvedName, "kill", cmd.Bool("dry-run"), skipConfirmOrForce(cmd), confirmKill, nil)
 	},
 }
 
@@ -130,12 +130,14 @@ type powerAPI interface {
 	Kill(context.Context) error
 }
 
-func runVPSAction(ctx context.Context, api powerAPI, resolvedName, action string, dryRun, skipConfirm bool, confirm confirmationFunc) error {
+func runVPSAction(ctx context.Context, api powerAPI, resolvedName, action string, dryRun, skipConfirm bool, confirm confirmationFunc, wait powerWaitFunc) error {
 	status := ""
 	if info, err := api.GetLiveServiceInfo(ctx); err == nil {
-		status = strings.ToLower(strings.TrimSpace(info.VeStatus))
+		if status = client.PowerState(info); status == client.PowerStateUnknown {
+			status = ""
+		}
 		if status != "" {
-			fmt.Printf("Current VPS status for instance %s: %s\n", resolvedName, info.VeStatus)
+			fmt.Printf("Current VPS status for instance %s: %s\n", resolvedName, status)
 		}
 	} else {
 		fmt.Printf("Warning: failed to read current VPS status: %v\n", err)
@@ -187,7 +189,18 @@ func runVPSAction(ctx context.Context, api powerAPI, resolvedName, action string
 	}
 
 	fmt.Printf("✅ VPS %s completed successfully\n", action)
-	return nil
+	return wait.run(ctx, powerActionTarget(action))
+}
+
+// powerActionTarget returns the power state a VPS reaches after action.
+func powerActionTarget(action string) string {
+	switch action {
+	case "stop", "kill":
+		return client.PowerStateStopped
+	case "restart":
+		return powerTargetRestarted
+	}
+	return client.PowerStateRunning
 }
 
 type hostnameAPI interface {
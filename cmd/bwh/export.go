@@ -0,0 +1,375 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/strahe/bwh/internal/history"
+	"github.com/strahe/bwh/pkg/client"
+	"github.com/urfave/cli/v3"
+)
+
+// Export formats supported by usage export and audit export.
+const (
+	exportFormatCSV   = "csv"
+	exportFormatJSONL = "jsonl"
+)
+
+// exportFlags returns the flags shared by the export commands.
+func exportFlags(extra ...cli.Flag) []cli.Flag {
+	flags := []cli.Flag{
+		&cli.StringFlag{
+			Name:  "format",
+			Usage: "output format: csv or jsonl",
+			Value: exportFormatCSV,
+		},
+		&cli.StringFlag{
+			Name:    "output",
+			Aliases: []string{"o"},
+			Usage:   "write to a file instead of stdout",
+		},
+		&cli.StringFlag{
+			Name:  "since",
+			Usage: "only include records at or after this time (RFC3339, YYYY-MM-DD or an age such as 7d, 12h)",
+		},
+		&cli.StringFlag{
+			Name:  "until",
+			Usage: "only include records before this time (RFC3339, YYYY-MM-DD or an age such as 7d, 12h)",
+		},
+	}
+	return append(flags, extra...)
+}
+
+// exportOptions holds the parsed values of exportFlags.
+type exportOptions struct {
+	format string
+	output string
+	since  time.Time
+	until  time.Time
+}
+
+func parseExportOptions(cmd *cli.Command, now time.Time) (*exportOptions, error) {
+	opts := &exportOptions{format: cmd.String("format"), output: cmd.String("output")}
+	if opts.format != exportFormatCSV && opts.format != exportFormatJSONL {
+		return nil, fmt.Errorf("unsupported format %q: use csv or jsonl", opts.format)
+	}
+	var err error
+	if opts.since, err = parseTimeFlag(cmd.String("since"), now); err != nil {
+		return nil, fmt.Errorf("invalid --since: %w", err)
+	}
+	if opts.until, err = parseTimeFlag(cmd.String("until"), now); err != nil {
+		return nil, fmt.Errorf("invalid --until: %w", err)
+	}
+	if !opts.since.IsZero() && !opts.until.IsZero() && !opts.since.Before(opts.until) {
+		return nil, fmt.Errorf("--since must be before --until")
+	}
+	return opts, nil
+}
+
+// inRange reports whether a Unix timestamp falls within [since, until).
+// Zero bounds are open.
+func (o *exportOptions) inRange(ts int64) bool {
+	t := time.Unix(ts, 0)
+	if !o.since.IsZero() && t.Before(o.since) {
+		return false
+	}
+	if !o.until.IsZero() && !t.Before(o.until) {
+		return false
+	}
+	return true
+}
+
+// parseTimeFlag parses an absolute time (RFC3339 or YYYY-MM-DD in local time)
+// or an age such as 90d, 12h or 30m counted back from now. An empty value
+// yields the zero time.
+func parseTimeFlag(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+	if days, ok := parseDayPeriod(value); ok {
+		return now.Add(-time.Duration(days) * 24 * time.Hour), nil
+	}
+	if d, err := time.ParseDuration(value); err == nil && d > 0 {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("%q is not a time (RFC3339, YYYY-MM-DD) or an age (7d, 12h)", value)
+}
+
+// exportRow is one record of an export. csvRecord must return the values in
+// the order of the matching header.
+type exportRow interface {
+	csvRecord() []string
+}
+
+// writeExport writes rows as CSV with header, or as one JSON object per line.
+func writeExport[T exportRow](w io.Writer, format string, header []string, rows []T) error {
+	if format == exportFormatJSONL {
+		encoder := json.NewEncoder(w)
+		for _, row := range rows {
+			if err := encoder.Encode(row); err != nil {
+				return fmt.Errorf("failed to encode record: %w", err)
+			}
+		}
+		return nil
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(header); err != nil {
+		return fmt.Errorf("failed to write CSV header: %w", err)
+	}
+	for _, row := range rows {
+		if err := writer.Write(row.csvRecord()); err != nil {
+			return fmt.Errorf("failed to write CSV record: %w", err)
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// exportTo writes an export to opts.output, or to stdout when it is empty.
+func exportTo(opts *exportOptions, write func(io.Writer) error) error {
+	if opts.output == "" {
+		return write(os.Stdout)
+	}
+	f, err := os.OpenFile(opts.output, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+	return nil
+}
+
+func formatExportTime(ts int64) string {
+	return time.Unix(ts, 0).UTC().Format(time.RFC3339)
+}
+
+func formatExportFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// usageExportHeader is the CSV header of usage exports. Columns are only ever
+// appended so existing spreadsheets keep working.
+var usageExportHeader = []string{
+	"timestamp",
+	"time",
+	"cpu_usage",
+	"network_in_bytes",
+	"network_out_bytes",
+	"disk_read_bytes",
+	"disk_write_bytes",
+	"network_in_bytes_per_sec",
+	"network_out_bytes_per_sec",
+	"disk_read_bytes_per_sec",
+	"disk_write_bytes_per_sec",
+}
+
+// usageExportRow is one usage data point with rates derived from the
+// 5-minute sample period.
+type usageExportRow struct {
+	Timestamp       int64   `json:"timestamp"`
+	Time            string  `json:"time"`
+	CPUUsage        int     `json:"cpu_usage"`
+	NetworkInBytes  int64   `json:"network_in_bytes"`
+	NetworkOutBytes int64   `json:"network_out_bytes"`
+	DiskReadBytes   int64   `json:"disk_read_bytes"`
+	DiskWriteBytes  int64   `json:"disk_write_bytes"`
+	NetworkInRate   float64 `json:"network_in_bytes_per_sec"`
+	NetworkOutRate  float64 `json:"network_out_bytes_per_sec"`
+	DiskReadRate    float64 `json:"disk_read_bytes_per_sec"`
+	DiskWriteRate   float64 `json:"disk_write_bytes_per_sec"`
+}
+
+func newUsageExportRow(p client.UsageDataPoint) usageExportRow {
+	rate := func(bytes int64) float64 { return float64(bytes) / usageSampleSeconds }
+	return usageExportRow{
+		Timestamp:       p.Timestamp,
+		Time:            formatExportTime(p.Timestamp),
+		CPUUsage:        p.CPUUsage,
+		NetworkInBytes:  p.NetworkInBytes,
+		NetworkOutBytes: p.NetworkOutBytes,
+		DiskReadBytes:   p.DiskReadBytes,
+		DiskWriteBytes:  p.DiskWriteBytes,
+		NetworkInRate:   rate(p.NetworkInBytes),
+		NetworkOutRate:  rate(p.NetworkOutBytes),
+		DiskReadRate:    rate(p.DiskReadBytes),
+		DiskWriteRate:   rate(p.DiskWriteBytes),
+	}
+}
+
+func (r usageExportRow) csvRecord() []string {
+	return []string{
+		strconv.FormatInt(r.Timestamp, 10),
+		r.Time,
+		strconv.Itoa(r.CPUUsage),
+		strconv.FormatInt(r.NetworkInBytes, 10),
+		strconv.FormatInt(r.NetworkOutBytes, 10),
+		strconv.FormatInt(r.DiskReadBytes, 10),
+		strconv.FormatInt(r.DiskWriteBytes, 10),
+		formatExportFloat(r.NetworkInRate),
+		formatExportFloat(r.NetworkOutRate),
+		formatExportFloat(r.DiskReadRate),
+		formatExportFloat(r.DiskWriteRate),
+	}
+}
+
+// auditExportHeader is the CSV header of audit exports. Columns are only ever
+// appended so existing spreadsheets keep working.
+var auditExportHeader = []string{
+	"timestamp",
+	"time",
+	"requestor_ip",
+	"type",
+	"summary",
+}
+
+type auditExportRow struct {
+	Timestamp   int64  `json:"timestamp"`
+	Time        string `json:"time"`
+	RequestorIP string `json:"requestor_ip"`
+	Type        int    `json:"type"`
+	Summary     string `json:"summary"`
+}
+
+func newAuditExportRow(e client.AuditLogEntry) auditExportRow {
+	return auditExportRow{
+		Timestamp:   e.Timestamp,
+		Time:        formatExportTime(e.Timestamp),
+		RequestorIP: intToIP(e.RequestorIPv4),
+		Type:        e.Type,
+		Summary:     e.Summary,
+	}
+}
+
+func (r auditExportRow) csvRecord() []string {
+	return []string{
+		strconv.FormatInt(r.Timestamp, 10),
+		r.Time,
+		r.RequestorIP,
+		strconv.Itoa(r.Type),
+		r.Summary,
+	}
+}
+
+var usageExportCmd = &cli.Command{
+	Name:  "export",
+	Usage: "export usage data points as CSV or JSON lines",
+	Flags: exportFlags(
+		&cli.BoolFlag{
+			Name:  "no-history",
+			Usage: "ignore data points stored by 'bwh usage collect'",
+		},
+	),
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		opts, err := parseExportOptions(cmd, time.Now())
+		if err != nil {
+			return err
+		}
+		bwhClient, resolvedName, err := createBWHClient(cmd)
+		if err != nil {
+			return err
+		}
+
+		var store *history.UsageStore
+		if !cmd.Bool("no-history") {
+			dir, err := history.DefaultDir()
+			if err != nil {
+				return err
+			}
+			store = history.NewUsageStore(dir, resolvedName)
+		}
+		return runUsageExport(ctx, bwhClient, store, opts)
+	},
+}
+
+// runUsageExport exports live usage data merged with the stored history.
+// A nil store exports live data only.
+func runUsageExport(ctx context.Context, api usageStatsAPI, store *history.UsageStore, opts *exportOptions) error {
+	stats, err := api.GetRawUsageStats(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get usage statistics: %w", err)
+	}
+	data := history.MergeUsage(stats.Data)
+	if store != nil {
+		if data, _, err = withUsageHistory(store, data); err != nil {
+			return err
+		}
+	}
+
+	var rows []usageExportRow
+	for _, p := range data {
+		if opts.inRange(p.Timestamp) {
+			rows = append(rows, newUsageExportRow(p))
+		}
+	}
+	return exportTo(opts, func(w io.Writer) error {
+		return writeExport(w, opts.format, usageExportHeader, rows)
+	})
+}
+
+var auditExportCmd = &cli.Command{
+	Name:  "export",
+	Usage: "export audit log entries as CSV or JSON lines",
+	Flags: exportFlags(auditMatchFlags()...),
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		now := time.Now()
+		opts, err := parseExportOptions(cmd, now)
+		if err != nil {
+			return err
+		}
+		filter, err := parseAuditFilter(cmd, now)
+		if err != nil {
+			return err
+		}
+		bwhClient, _, err := createBWHClient(cmd)
+		if err != nil {
+			return err
+		}
+		return runAuditExport(ctx, bwhClient, opts, filter)
+	},
+}
+
+type auditLogAPI interface {
+	GetAuditLog(context.Context) (*client.AuditLogResponse, error)
+}
+
+// runAuditExport exports the audit entries within the time range of opts
+// that match filter.
+func runAuditExport(ctx context.Context, api auditLogAPI, opts *exportOptions, filter *auditFilter) error {
+	auditLog, err := api.GetAuditLog(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get audit log: %w", err)
+	}
+	entries := append([]client.AuditLogEntry(nil), auditLog.LogEntries...)
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Timestamp < entries[j].Timestamp
+	})
+
+	var rows []auditExportRow
+	for _, e := range entries {
+		if opts.inRange(e.Timestamp) && filter.match(e) {
+			rows = append(rows, newAuditExportRow(e))
+		}
+	}
+	return exportTo(opts, func(w io.Writer) error {
+		return writeExport(w, opts.format, auditExportHeader, rows)
+	})
+}
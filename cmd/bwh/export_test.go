@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/strahe/bwh/internal/history"
+	"github.com/strahe/bwh/pkg/client"
+)
+
+type fakeAuditAPI struct {
+	entries []client.AuditLogEntry
+}
+
+func (f *fakeAuditAPI) GetAuditLog(context.Context) (*client.AuditLogResponse, error) {
+	return &client.AuditLogResponse{LogEntries: f.entries}, nil
+}
+
+func readExport(t *testing.T, path string) []string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
+
+func TestParseTimeFlag(t *testing.T) {
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	tests := map[string]time.Time{
+		"":                     {},
+		"2025-03-01T00:00:00Z": time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC),
+		"7d":                   now.Add(-7 * 24 * time.Hour),
+		"90m":                  now.Add(-90 * time.Minute),
+	}
+	for input, want := range tests {
+		got, err := parseTimeFlag(input, now)
+		if err != nil || !got.Equal(want) {
+			t.Fatalf("parseTimeFlag(%q) = %v, %v; want %v", input, got, err, want)
+		}
+	}
+
+	day, err := parseTimeFlag("2025-03-01", now)
+	if err != nil || day.Format("2006-01-02 15:04") != "2025-03-01 00:00" || day.Location() != time.Local {
+		t.Fatalf("parseTimeFlag(date) = %v, %v", day, err)
+	}
+
+	for _, input := range []string{"yesterday", "-5h", "0d"} {
+		if _, err := parseTimeFlag(input, now); err == nil {
+			t.Fatalf("parseTimeFlag(%q) error = nil, want error", input)
+		}
+	}
+}
+
+func TestRunUsageExport(t *testing.T) {
+	dir := t.TempDir()
+	store := history.NewUsageStore(dir, "web")
+	if _, err := store.Append([]client.UsageDataPoint{{Timestamp: 1000, CPUUsage: 1}}); err != nil {
+		t.Fatal(err)
+	}
+	api := &fakeUsageAPI{stats: &client.UsageStatsResponse{Data: []client.UsageDataPoint{
+		{Timestamp: 1600, CPUUsage: 3, NetworkInBytes: 600, NetworkOutBytes: 300, DiskReadBytes: 30, DiskWriteBytes: 3},
+		{Timestamp: 1300, CPUUsage: 2},
+	}}}
+
+	t.Run("csv with history and stable header", func(t *testing.T) {
+		opts := &exportOptions{format: exportFormatCSV, output: filepath.Join(dir, "usage.csv")}
+		if err := runUsageExport(context.Background(), api, store, opts); err != nil {
+			t.Fatalf("runUsageExport() error = %v", err)
+		}
+		lines := readExport(t, opts.output)
+		want := []string{
+			"timestamp,time,cpu_usage,network_in_bytes,network_out_bytes,disk_read_bytes,disk_write_bytes,network_in_bytes_per_sec,network_out_bytes_per_sec,disk_read_bytes_per_sec,disk_write_bytes_per_sec",
+			"1000,1970-01-01T00:16:40Z,1,0,0,0,0,0,0,0,0",
+			"1300,1970-01-01T00:21:40Z,2,0,0,0,0,0,0,0,0",
+			"1600,1970-01-01T00:26:40Z,3,600,300,30,3,2,1,0.1,0.01",
+		}
+		if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+			t.Fatalf("csv =\n%s\nwant\n%s", strings.Join(lines, "\n"), strings.Join(want, "\n"))
+		}
+	})
+
+	t.Run("jsonl with time filter and live data only", func(t *testing.T) {
+		opts := &exportOptions{
+			format: exportFormatJSONL,
+			output: filepath.Join(dir, "usage.jsonl"),
+			since:  time.Unix(1300, 0),
+			until:  time.Unix(1600, 0),
+		}
+		if err := runUsageExport(context.Background(), api, nil, opts); err != nil {
+			t.Fatalf("runUsageExport() error = %v", err)
+		}
+		lines := readExport(t, opts.output)
+		if len(lines) != 1 {
+			t.Fatalf("lines = %v, want only the 1300 record", lines)
+		}
+		var row map[string]any
+		if err := json.Unmarshal([]byte(lines[0]), &row); err != nil {
+			t.Fatalf("invalid JSON line: %v", err)
+		}
+		if len(row) != len(usageExportHeader) || row["timestamp"] != float64(1300) {
+			t.Fatalf("row = %v", row)
+		}
+	})
+}
+
+func TestRunAuditExport(t *testing.T) {
+	dir := t.TempDir()
+	api := &fakeAuditAPI{entries: []client.AuditLogEntry{
+		{Timestamp: 2000, RequestorIPv4: 0x01020304, Type: 7, Summary: "Hostname changed, \"web\""},
+		{Timestamp: 1000, RequestorIPv4: 0xC0A80001, Type: 1, Summary: "VPS started"},
+	}}
+
+	opts := &exportOptions{format: exportFormatCSV, output: filepath.Join(dir, "audit.csv")}
+	if err := runAuditExport(context.Background(), api, opts, &auditFilter{}); err != nil {
+		t.Fatalf("runAuditExport() error = %v", err)
+	}
+	lines := readExport(t, opts.output)
+	want := []string{
+		"timestamp,time,requestor_ip,type,summary",
+		"1000,1970-01-01T00:16:40Z,192.168.0.1,1,VPS started",
+		`2000,1970-01-01T00:33:20Z,1.2.3.4,7,"Hostname changed, ""web"""`,
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("csv =\n%s", strings.Join(lines, "\n"))
+	}
+
+	opts = &exportOptions{format: exportFormatJSONL, output: filepath.Join(dir, "audit.jsonl"), since: time.Unix(1500, 0)}
+	if err := runAuditExport(context.Background(), api, opts, &auditFilter{}); err != nil {
+		t.Fatalf("runAuditExport() error = %v", err)
+	}
+	lines = readExport(t, opts.output)
+	if len(lines) != 1 || !strings.Contains(lines[0], `"requestor_ip":"1.2.3.4"`) {
+		t.Fatalf("jsonl = %v", lines)
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+	"time"
+
+	"github.com/strahe/bwh/internal/config"
+	"github.com/strahe/bwh/internal/exporter"
+	"github.com/urfave/cli/v3"
+)
+
+var exporterCmd = &cli.Command{
+	Name:  "exporter",
+	Usage: "serve Prometheus metrics for all configured instances",
+	Description: `Polls every configured instance and serves the results at /metrics.
+
+Each poll spends 4 API calls. The delay between polls starts at --interval and
+grows so that polling uses at most --budget of the API points left in the
+15-minute and 24-hour rate limit windows. Use --instance to export a single
+instance.`,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "listen",
+			Usage: "address to serve metrics on",
+			Value: ":9101",
+		},
+		&cli.DurationFlag{
+			Name:  "interval",
+			Usage: "shortest delay between two polls of one instance",
+			Value: 5 * time.Minute,
+		},
+		&cli.FloatFlag{
+			Name:  "budget",
+			Usage: "share of the remaining API points the exporter may use (0-1]",
+			Value: 0.5,
+		},
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		interval := cmd.Duration("interval")
+		if interval < time.Minute {
+			return fmt.Errorf("--interval must be at least 1m")
+		}
+		budget := cmd.Float("budget")
+		if budget <= 0 || budget > 1 {
+			return fmt.Errorf("--budget must be greater than 0 and at most 1")
+		}
+
+		manager, err := createConfigManager(cmd)
+		if err != nil {
+			return err
+		}
+		targets, err := exporterTargets(manager, cmd.String("instance"))
+		if err != nil {
+			return err
+		}
+
+		ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		logger := log.New(os.Stderr, "bwh exporter: ", log.LstdFlags)
+		exp := exporter.New(targets, exporter.Options{
+			Interval: interval,
+			Budget:   budget,
+			Logger:   logger,
+		})
+
+		server := &http.Server{
+			Addr:              cmd.String("listen"),
+			Handler:           exp.Handler(),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		serveErr := make(chan error, 1)
+		go func() {
+			serveErr <- server.ListenAndServe()
+		}()
+		go exp.Run(ctx)
+
+		logger.Printf("serving metrics for %d instance(s) on %s/metrics", len(targets), server.Addr)
+
+		select {
+		case err := <-serveErr:
+			return fmt.Errorf("failed to serve metrics: %w", err)
+		case <-ctx.Done():
+		}
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return fmt.Errorf("failed to stop metrics server: %w", err)
+		}
+		return nil
+	},
+}
+
+// exporterTargets builds one exporter target per configured instance, or only
+// for instanceName when it is set. instanceName is resolved like --instance
+// of any other command, and without configured instances the instance from
+// the environment is used.
+func exporterTargets(manager *config.Manager, instanceName string) ([]exporter.Target, error) {
+	names := slices.Sorted(maps.Keys(manager.ListInstances()))
+	if instanceName != "" || len(names) == 0 {
+		instance, name, err := resolveInstanceWithFallback(manager, instanceName)
+		if err != nil {
+			return nil, err
+		}
+		return []exporter.Target{{Name: name, API: newInstanceClient(instance)}}, nil
+	}
+
+	targets := make([]exporter.Target, 0, len(names))
+	for _, name := range names {
+		instance, err := manager.OpenInstance(name)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, exporter.Target{Name: name, API: newInstanceClient(instance)})
+	}
+	return targets, nil
+}
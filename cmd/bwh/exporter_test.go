@@ -0,0 +1,43 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/strahe/bwh/internal/config"
+)
+
+func TestExporterTargets(t *testing.T) {
+	t.Setenv(config.NoProjectConfigEnv, "1")
+	t.Setenv("BWH_INSTANCE", "")
+	t.Setenv("BWH_API_KEY", "env-api-key-123456789")
+	t.Setenv("BWH_VEID", "9")
+
+	manager, err := config.NewManager(filepath.Join(t.TempDir(), "config.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	targets, err := exporterTargets(manager, "")
+	if err != nil || len(targets) != 1 || targets[0].Name != config.EnvInstanceName {
+		t.Fatalf("exporterTargets() without configured instances = %+v, %v; want the env instance", targets, err)
+	}
+
+	for name, veid := range map[string]string{"prod-web": "100", "prod-db": "200"} {
+		if err := manager.AddInstance(name, &config.Instance{APIKey: name + "-api-key-123456789", VeID: veid}, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if targets, err := exporterTargets(manager, ""); err != nil || len(targets) != 2 || targets[0].Name != "prod-db" {
+		t.Errorf("exporterTargets() = %+v, %v; want both instances", targets, err)
+	}
+
+	for query, want := range map[string]string{"200": "prod-db", "pweb": "prod-web", config.EnvInstanceName: config.EnvInstanceName} {
+		targets, err := exporterTargets(manager, query)
+		if err != nil || len(targets) != 1 || targets[0].Name != want {
+			t.Errorf("exporterTargets(%q) = %+v, %v; want %s", query, targets, err, want)
+		}
+	}
+	if _, err := exporterTargets(manager, "missing"); err == nil {
+		t.Error("exporterTargets(missing) error = nil")
+	}
+}
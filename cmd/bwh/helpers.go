@@ -32,7 +32,17 @@ func getConfigManager(configPath string) (*config.Manager, error) {
 
 // resolveInstanceWithFallback resolves the instance to use with helpful error messages
 func resolveInstanceWithFallback(manager *config.Manager, instanceName string) (*config.Instance, string, error) {
-	instance, resolvedName, err := manager.ResolveInstance(instanceName)
+	return resolveInstanceWith(manager, instanceName, manager.ResolveInstance)
+}
+
+// resolveWriteInstance resolves the instance of a write command, which
+// refuses fuzzy name matches.
+func resolveWriteInstance(manager *config.Manager, instanceName string) (*config.Instance, string, error) {
+	return resolveInstanceWith(manager, instanceName, manager.ResolveInstanceForWrite)
+}
+
+func resolveInstanceWith(manager *config.Manager, instanceName string, resolve func(string) (*config.Instance, string, error)) (*config.Instance, string, error) {
+	instance, resolvedName, err := resolve(instanceName)
 	if err != nil {
 		switch err {
 		case config.ErrNoInstances:
@@ -47,6 +57,9 @@ func resolveInstanceWithFallback(manager *config.Manager, instanceName string) (
 			return nil, "", err
 		}
 	}
+	if instanceName != "" && resolvedName != instanceName {
+		fmt.Fprintf(os.Stderr, "Using instance '%s' for '%s'\n", resolvedName, instanceName) //nolint:errcheck
+	}
 	return instance, resolvedName, nil
 }
 
@@ -91,11 +104,19 @@ func validateBackupToken(token string) error {
 	return nil
 }
 
+// stdinReader is the one buffered reader of standard input. Every prompt
+// reads through it, so input buffered by one prompt is not lost to the next.
+var stdinReader = bufio.NewReader(os.Stdin)
+
 // promptConfirmation prompts user for yes/no confirmation with better error handling
 func promptConfirmation(prompt string) (bool, error) {
+	return confirmFrom(stdinReader, prompt)
+}
+
+// confirmFrom asks a yes/no question and reads the answer from reader.
+func confirmFrom(reader *bufio.Reader, prompt string) (bool, error) {
 	fmt.Printf("%s [y/N]: ", prompt)
 
-	reader := bufio.NewReader(os.Stdin)
 	response, err := reader.ReadString('\n')
 	if err != nil {
 		if err == io.EOF {
@@ -112,9 +133,18 @@ func promptConfirmation(prompt string) (bool, error) {
 // createConfigManager creates a config manager from CLI command flags.
 // It extracts the config path from the command and initializes a new config.Manager.
 // Returns the configured manager and any error encountered during initialization.
+// Encrypted API keys are unlocked on first use with vaultKeyFunc.
 func createConfigManager(cmd *cli.Command) (*config.Manager, error) {
 	configPath := cmd.String("config")
-	return getConfigManager(configPath)
+	manager, err := getConfigManager(configPath)
+	if err != nil {
+		return nil, err
+	}
+	manager.SetVaultKeyFunc(vaultKeyFunc)
+	if from, backupPath, migrated := manager.Migration(); migrated {
+		fmt.Fprintf(os.Stderr, "ℹ️  Upgraded config file from version %d to %d; the original is saved as %s\n", from, config.CurrentVersion, backupPath) //nolint:errcheck
+	}
+	return manager, nil
 }
 
 // createBWHClient creates a BWH API client with configuration resolution.
@@ -145,16 +175,23 @@ func createBWHClientWithInstance(cmd *cli.Command) (*client.Client, *config.Inst
 		return nil, nil, "", fmt.Errorf("failed to create config manager: %w", err)
 	}
 
-	instanceName := cmd.String("instance")
-	instance, resolvedName, err := resolveInstanceWithFallback(manager, instanceName)
+	resolve := resolveInstanceWithFallback
+	if isWriteCommand(cmd) {
+		resolve = resolveWriteInstance
+	}
+	instance, resolvedName, err := resolve(manager, cmd.String("instance"))
 	if err != nil {
 		return nil, nil, "", fmt.Errorf("failed to resolve instance: %w", err)
 	}
 
+	return newInstanceClient(instance), instance, resolvedName, nil
+}
+
+// newInstanceClient creates a BWH API client for a configured instance.
+func newInstanceClient(instance *config.Instance) *client.Client {
 	bwhClient := client.NewClient(instance.APIKey, instance.VeID)
 	if instance.Endpoint != "" {
 		bwhClient.SetBaseURL(instance.Endpoint)
 	}
-
-	return bwhClient, instance, resolvedName, nil
+	return bwhClient
 }
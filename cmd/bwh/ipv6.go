@@ -35,10 +35,11 @@ var ipv6AddCmd = &cli.Command{
 }
 
 var ipv6DeleteCmd = &cli.Command{
-	Name:      "delete",
-	Usage:     "release an IPv6 /64 subnet",
-	ArgsUsage: "<subnet>",
-	Flags:     writeFlags(),
+	Name:          "delete",
+	Usage:         "release an IPv6 /64 subnet",
+	ArgsUsage:     "<subnet>",
+	ShellComplete: completeArgs(fromAPI(ipv6Completions)),
+	Flags:         writeFlags(),
 	Action: func(ctx context.Context, cmd *cli.Command) error {
 		if cmd.Args().Len() != 1 {
 			return fmt.Errorf("IPv6 subnet is required")
@@ -53,18 +53,20 @@ var isoCmd = &cli.Command{
 				}
 
 				fmt.Printf("\n💡 Usage:\n")
-				fmt.Printf("  bwh iso mount <image_name>   # Mount an ISO image\n")
-				fmt.Printf("  bwh iso unmount              # Unmount current ISO\n")
+				fmt.Printf("  bwh iso mount <image_name>           # Mount an ISO image\n")
+				fmt.Printf("  bwh iso mount <image_name> --cycle   # Stop, mount and start in one go\n")
+				fmt.Printf("  bwh iso unmount                      # Unmount current ISO\n")
 				fmt.Printf("\n⚠️  Note: VPS must be completely shut down and restarted after mount/unmount operations\n")
 
 				return nil
 			},
 		},
 		{
-			Name:      "mount",
-			Usage:     "mount ISO image to boot from (requires VPS shutdown and restart)",
-			ArgsUsage: "<iso>",
-			Flags:     writeFlags(),
+			Name:          "mount",
+			Usage:         "mount ISO image to boot from (requires VPS shutdown and restart)",
+			ArgsUsage:     "<iso>",
+			ShellComplete: completeArgs(fromAPI(isoCompletions)),
+			Flags:         waitWriteFlags(isoCycleFlag()),
 			Action: func(ctx context.Context, cmd *cli.Command) error {
 				if cmd.Args().Len() != 1 {
 					return fmt.Errorf("iso mount command requires exactly one argument: <iso>")
@@ -80,32 +82,51 @@ var isoCmd = &cli.Command{
 					return err
 				}
 
-				return runMountISO(ctx, bwhClient, resolvedName, iso, cmd.Bool("dry-run"), skipConfirm(cmd), promptConfirmation)
+				return runMountISO(ctx, bwhClient, resolvedName, iso, cmd.Bool("dry-run"), skipConfirm(cmd), promptConfirmation, newUnlockWait(cmd, bwhClient, resolvedName), newISOCycle(cmd, bwhClient, resolvedName))
 			},
 		},
 		{
 			Name:  "unmount",
 			Usage: "unmount ISO image and boot from primary storage (requires VPS shutdown and restart)",
-			Flags: writeFlags(),
+			Flags: waitWriteFlags(isoCycleFlag()),
 			Action: func(ctx context.Context, cmd *cli.Command) error {
 				bwhClient, resolvedName, err := createBWHClient(cmd)
 				if err != nil {
 					return err
 				}
 
-				return runUnmountISO(ctx, bwhClient, resolvedName, cmd.Bool("dry-run"), skipConfirm(cmd), promptConfirmation)
+				return runUnmountISO(ctx, bwhClient, resolvedName, cmd.Bool("dry-run"), skipConfirm(cmd), promptConfirmation, newUnlockWait(cmd, bwhClient, resolvedName), newISOCycle(cmd, bwhClient, resolvedName))
 			},
 		},
 	},
 }
 
+func isoCycleFlag() cli.Flag {
+	return &cli.BoolFlag{
+		Name:  "cycle",
+		Usage: "stop the VPS, apply the change and start it again, waiting for each step",
+	}
+}
+
+// newISOCycle returns a powerWaitFunc when --cycle is set, or nil otherwise.
+// The power waits share --wait-timeout with the unlock wait.
+func newISOCycle(cmd *cli.Command, api powerStateWaiter, resolvedName string) powerWaitFunc {
+	if !cmd.Bool("cycle") {
+		return nil
+	}
+	return powerWaitWithTimeout(api, resolvedName, cmd.Duration("wait-timeout"))
+}
+
 type isoAPI interface {
 	GetServiceInfo(context.Context) (*client.ServiceInfo, error)
+	GetLiveServiceInfo(context.Context) (*client.LiveServiceInfo, error)
 	MountISO(context.Context, string) error
 	UnmountISO(context.Context) error
+	Start(context.Context) error
+	Stop(context.Context) error
 }
 
-func runMountISO(ctx context.Context, api isoAPI, resolvedName, iso string, dryRun, skipConfirm bool, confirm confirmationFunc) error {
+func runMountISO(ctx context.Context, api isoAPI, resolvedName, iso string, dryRun, skipConfirm bool, confirm confirmationFunc, wait unlockWaitFunc, cycle powerWaitFunc) error {
 	info, err := api.GetServiceInfo(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to get service info: %w", err)
@@ -118,10 +139,18 @@ func runMountISO(ctx context.Context, api isoAPI, resolvedName, iso string, dryR
 		return nil
 	}
 	if dryRun {
-		printDryRun("iso/mount", resolvedName, fmt.Sprintf("iso: %s", iso))
+		details := []string{fmt.Sprintf("iso: %s", iso)}
+		if cycle != nil {
+			details = append(details, "power cycle: stop -> iso/mount -> start")
+		}
+		printDryRun("iso/mount", resolvedName, details...)
 		return nil
 	}
-	confirmed, err := confirmWrite(fmt.Sprintf("Mount ISO '%s' for VPS '%s'?", iso, resolvedName), skipConfirm, confirm)
+	prompt := fmt.Sprintf("Mount ISO '%s' for VPS '%s'?", iso, resolvedName)
+	if cycle != nil {
+		prompt = fmt.Sprintf("Stop VPS '%s', mount ISO '%s' and start it again?", resolvedName, iso)
+	}
+	confirmed, err := confirmWrite(prompt, skipConfirm, confirm)
 	if err != nil {
 		return err
 	}
@@ -129,17 +158,28 @@ func runMountISO(ctx context.Context, api isoAPI, resolvedName, iso string, dryR
 		return nil
 	}
 
+	if cycle != nil {
+		return runISOCycle(ctx, api, resolvedName, cycle, func() error {
+			fmt.Printf("Mounting ISO '%s' for instance: %s\n", iso, resolvedName)
+			if err := api.MountISO(ctx, iso); err != nil {
+				return fmt.Errorf("failed to mount ISO: %w", err)
+			}
+			fmt.Printf("✅ ISO '%s' mounted successfully\n", iso)
+			return wait.run(ctx)
+		}, fmt.Sprintf("VPS is booting from ISO '%s'", iso))
+	}
+
 	fmt.Printf("Mounting ISO '%s' for instance: %s\n", iso, resolvedName)
 	fmt.Printf("⚠️  Remember: VPS must be completely shut down and restarted after this operation\n")
 	if err := api.MountISO(ctx, iso); err != nil {
 		return fmt.Errorf("failed to mount ISO: %w", err)
 	}
 	fmt.Printf("✅ ISO '%s' mounted successfully\n", iso)
-	fmt.Printf("📝 Next steps: shutdown VPS completely and restart to boot from ISO\n")
-	return nil
+	fmt.Printf("📝 Next steps: shutdown VPS completely and restart to boot from ISO (or use --cycle)\n")
+	return wait.run(ctx)
 }
 
-func runUnmountISO(ctx context.Context, api isoAPI, resolvedName string, dryRun, skipConfirm bool, confirm confirmationFunc) error {
+func runUnmountISO(ctx context.Context, api isoAPI, resolvedName string, dryRun, skipConfirm bool, confirm confirmationFunc, wait unlockWaitFunc, cycle powerWaitFunc) error {
 	info, err := api.GetServiceInfo(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to get service info: %w", err)
@@ -149,10 +189,18 @@ func runUnmountISO(ctx context.Context, api isoAPI, resolvedName string, dryRun,
 		return nil
 	}
 	if dryRun {
-		printDryRun("iso/unmount", resolvedName, fmt.Sprintf("mounted ISO1: %s", info.ISO1))
+		details := []string{fmt.Sprintf("mounted ISO1: %s", info.ISO1)}
+		if cycle != nil {
+			details = append(details, "power cycle: stop -> iso/unmount -> start")
+		}
+		printDryRun("iso/unmount", resolvedName, details...)
 		return nil
 	}
-	confirmed, err := confirmWrite(fmt.Sprintf("Unmount ISO for VPS '%s'?", resolvedName), skipConfirm, confirm)
+	prompt := fmt.Sprintf("Unmount ISO for VPS '%s'?", resolvedName)
+	if cycle != nil {
+		prompt = fmt.Sprintf("Stop VPS '%s', unmount ISO and start it again?", resolvedName)
+	}
+	confirmed, err := confirmWrite(prompt, skipConfirm, confirm)
 	if err != nil {
 		return err
 	}
@@ -160,12 +208,55 @@ func runUnmountISO(ctx context.Context, api isoAPI, resolvedName string, dryRun,
 		return nil
 	}
 
+	if cycle != nil {
+		return runISOCycle(ctx, api, resolvedName, cycle, func() error {
+			fmt.Printf("Unmounting ISO for instance: %s\n", resolvedName)
+			if err := api.UnmountISO(ctx); err != nil {
+				return fmt.Errorf("failed to unmount ISO: %w", err)
+			}
+			fmt.Printf("✅ ISO unmounted successfully\n")
+			return wait.run(ctx)
+		}, "VPS is booting from primary storage")
+	}
+
 	fmt.Printf("Unmounting ISO for instance: %s\n", resolvedName)
 	fmt.Printf("⚠️  Remember: VPS must be completely shut down and restarted after this operation\n")
 	if err := api.UnmountISO(ctx); err != nil {
 		return fmt.Errorf("failed to unmount ISO: %w", err)
 	}
 	fmt.Printf("✅ ISO unmounted successfully\n")
-	fmt.Printf("📝 Next steps: shutdown VPS completely and restart to boot from primary storage\n")
+	fmt.Printf("📝 Next steps: shutdown VPS completely and restart to boot from primary storage (or use --cycle)\n")
+	return wait.run(ctx)
+}
+
+// runISOCycle stops the VPS, applies an ISO change and starts the VPS again,
+// waiting for each power state before moving on. A VPS that is already
+// stopped is not stopped again.
+func runISOCycle(ctx context.Context, api isoAPI, resolvedName string, wait powerWaitFunc, change func() error, doneMessage string) error {
+	if info, err := api.GetLiveServiceInfo(ctx); err == nil && client.PowerState(info) == client.PowerStateStopped {
+		fmt.Printf("Instance %s is already stopped\n", resolvedName)
+	} else {
+		fmt.Printf("Stopping instance: %s\n", resolvedName)
+		if err := api.Stop(ctx); err != nil {
+			return fmt.Errorf("failed to stop VPS: %w", err)
+		}
+		if err := wait(ctx, client.PowerStateStopped); err != nil {
+			return err
+		}
+	}
+
+	if err := change(); err != nil {
+		fmt.Printf("⚠️  VPS %s was left stopped; run 'bwh start' once the problem is resolved\n", resolvedName)
+		return err
+	}
+
+	fmt.Printf("Starting instance: %s\n", resolvedName)
+	if err := api.Start(ctx); err != nil {
+		return fmt.Errorf("failed to start VPS: %w", err)
+	}
+	if err := wait(ctx, client.PowerStateRunning); err != nil {
+		return err
+	}
+	fmt.Printf("✅ %s\n", doneMessage)
 	return nil
 }
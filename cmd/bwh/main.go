@@ -5,8 +5,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/strahe/bwh/internal/config"
@@ -21,7 +24,6 @@ func main() {
 		Usage:                 "manage your BWH instances",
 		Version:               version.GetVersion(),
 		EnableShellCompletion: true,
-		ShellComplete:         shellComplete,
 		Before:                showUpdateNotificationHook,
 		After:                 checkForUpdatesHook,
 		Flags: []cli.Flag{
@@ -38,6 +40,7 @@ func main() {
 		},
 		Commands: []*cli.Command{
 			nodeCmd,
+			configCmd,
 			infoCmd,
 			rateLimitCmd,
 			connectCmd,
@@ -60,32 +63,72 @@ func main() {
 			migrateCmd,
 			ipv6Cmd,
 			privateIPCmd,
+			networkCmd,
+			stateCmd,
+			planCmd,
+			applyCmd,
+			checkCmd,
+			exporterCmd,
+			notifyCmd,
 			mcpCmd,
+			uiCmd,
 			updateCmd,
 		},
 	}
 
+	setShellComplete(cmd)
+
 	if err := cmd.Run(context.Background(), os.Args); err != nil {
 		log.Fatal(err)
 	}
 }
 
-func shellComplete(ctx context.Context, cmd *cli.Command) {
-	args := os.Args
+// setShellComplete gives every command without its own completion the
+// default one, which also completes --instance values.
+func setShellComplete(cmd *cli.Command) {
+	if cmd.ShellComplete == nil {
+		cmd.ShellComplete = completeDefault
+	}
+	for _, sub := range cmd.Commands {
+		setShellComplete(sub)
+	}
+}
 
-	// Check if completing instance flag value
-	for i, arg := range args {
-		if (arg == "--instance" || arg == "-i") && i+1 < len(args) {
-			configManager, err := config.NewManager(cmd.String("config"))
-			if err != nil {
-				return
-			}
-			for _, instance := range configManager.GetAvailableInstances() {
-				fmt.Println(instance)
+// instanceCompletions returns the values --instance accepts: instance names,
+// then VEIDs and the hostnames and IPv4 addresses cached by 'bwh node
+// refresh'. IPv6 entries are /64 subnets, not addresses to type.
+func instanceCompletions(manager *config.Manager) []string {
+	instances := manager.ListInstances()
+	names := slices.Sorted(maps.Keys(instances))
+	cache, err := config.LoadMetadataCache(manager.MetadataPath())
+	if err != nil {
+		return names
+	}
+
+	candidates := slices.Clone(names)
+	seen := make(map[string]bool)
+	for _, name := range names {
+		seen[name] = true
+	}
+	add := func(value string) {
+		if value != "" && !seen[value] {
+			seen[value] = true
+			candidates = append(candidates, value)
+		}
+	}
+	for _, name := range names {
+		instance := instances[name]
+		add(instance.VeID)
+		if metadata := cache.Get(name, instance); metadata != nil {
+			add(metadata.Hostname)
+			for _, ip := range metadata.IPAddresses {
+				if !strings.Contains(ip, ":") {
+					add(ip)
+				}
 			}
-			return
 		}
 	}
+	return candidates
 }
 
 func showUpdateNotificationHook(ctx context.Context, cmd *cli.Command) (context.Context, error) {
@@ -69,9 +69,10 @@ func splitIPsByFamily(ips []string) (ipv4 []string, ipv6 []string) {
 }
 
 var migrateStartCmd = &cli.Command{
-	Name:      "start",
-	Usage:     "start VPS migration to new location (IPv4 will be replaced)",
-	ArgsUsage: "<location_id>",
+	Name:          "start",
+	Usage:         "start VPS migration to new location (IPv4 will be replaced)",
+	ArgsUsage:     "<location_id>",
+	ShellComplete: completeArgs(fromAPI(locationCompletions)),
 	Flags: writeFlags(
 		&cli.StringFlag{
 			Name:  "timeout",
@@ -203,43 +204,42 @@ func runMigrateStartWait(ctx context.Context, api migrationStartAPI, locationID
 		resultCh <- resp
 	}()
 
-	ticker := time.NewTicker(5 * time.Second)
-	defer ticker.Stop()
+	currentLocation := ""
+	op, err := client.NewOperation(migCtx, client.WaitOptions{
+		Probe: func(ctx context.Context) error {
+			resp, err := api.GetMigrateLocations(ctx)
+			if err == nil {
+				currentLocation = resp.CurrentLocation
+			}
+			return err
+		},
+	})
+	if err != nil {
+		return err
+	}
 
-	lastPercent := -1
-	lastMsg := ""
-	lastOperation := ""
+	printer := &lockProgressPrinter{}
+	events := op.Events()
 	var acceptResp *client.MigrateStartResponse
 
 	for {
 		select {
-		case <-ticker.C:
-			if resp, perr := api.GetMigrateLocations(ctx); perr != nil {
-				if bwhErr, ok := client.GetBWHError(perr); ok && client.IsLockedError(perr) {
-					if bwhErr.AdditionalErrorInfo != "" && bwhErr.AdditionalErrorInfo != lastOperation {
-						fmt.Printf("%s\n", bwhErr.AdditionalErrorInfo)
-						lastOperation = bwhErr.AdditionalErrorInfo
-					}
-					if info := bwhErr.AdditionalLockingInfo; info != nil {
-						p := info.CompletedPercent
-						msg := info.FriendlyProgressMessage
-						updated := info.LastStatusUpdateSecondsAgo
-						if p != lastPercent || msg != lastMsg {
-							if updated > 0 {
-								fmt.Printf("Progress: %d%% complete - %s (updated %ds ago)\n", p, msg, updated)
-							} else {
-								fmt.Printf("Progress: %d%% complete - %s\n", p, msg)
-							}
-							lastPercent = p
-							lastMsg = msg
-						}
-					}
+		case event, ok := <-events:
+			if !ok {
+				events = nil
+				continue
+			}
+			printer.print(event)
+		case <-op.Done():
+			if err := op.Err(); err != nil {
+				if migCtx.Err() != nil {
+					return fmt.Errorf("migration timed out after %s", timeout)
 				}
-			} else {
-				fmt.Printf("\n✅ VE unlocked. Current location: %s\n", resp.CurrentLocation)
-				printMigrationNewIPs(acceptResp)
-				return nil
+				return fmt.Errorf("migration failed: %w", err)
 			}
+			fmt.Printf("\n✅ VE unlocked. Current location: %s\n", currentLocation)
+			printMigrationNewIPs(acceptResp)
+			return nil
 		case resp := <-resultCh:
 			acceptResp = resp
 			printMigrationAccepted(resp, false)
@@ -247,9 +247,8 @@ func runMigrateStartWait(ctx context.Context, api migrationStartAPI, locationID
 			if client.IsLockedError(e) {
 				continue
 			}
+			op.Cancel()
 			return fmt.Errorf("migration failed: %w", e)
-		case <-migCtx.Done():
-			return fmt.Errorf("migration timed out after %s", timeout)
 		}
 	}
 }
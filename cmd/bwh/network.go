@@ -0,0 +1,310 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/strahe/bwh/internal/history"
+	"github.com/strahe/bwh/internal/notify"
+	"github.com/strahe/bwh/pkg/client"
+	"github.com/urfave/cli/v3"
+)
+
+var networkCmd = &cli.Command{
+	Name:  "network",
+	Usage: "show network status",
+	Commands: []*cli.Command{
+		networkNullroutesCmd,
+	},
+}
+
+var networkNullroutesCmd = &cli.Command{
+	Name:  "nullroutes",
+	Usage: "show nullrouted IP addresses and the time until they are released",
+	Commands: []*cli.Command{
+		nullroutesWatchCmd,
+		nullroutesHistoryCmd,
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		bwhClient, resolvedName, err := createBWHClient(cmd)
+		if err != nil {
+			return err
+		}
+		info, err := bwhClient.GetServiceInfo(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get service info: %w", err)
+		}
+		displayNullroutes(info.IPNullroutes, resolvedName, time.Now())
+		return nil
+	},
+}
+
+var nullroutesWatchCmd = &cli.Command{
+	Name:  "watch",
+	Usage: "poll for nullroute changes, record them and send notifications",
+	Description: `Polls the service info every --interval and compares the nullrouted IPs with
+the last recorded state. Every IP that gets nullrouted or released is printed,
+appended to ~/.bwh/history/<instance>/nullroutes.jsonl (or $BWH_HISTORY_DIR)
+and, with --notify, sent to the named notifiers: nullroutes as critical
+events, releases as info events.
+
+Run it as a service, or from cron with --once.`,
+	Flags: []cli.Flag{
+		&cli.DurationFlag{
+			Name:  "interval",
+			Usage: "delay between polls",
+			Value: 5 * time.Minute,
+		},
+		&cli.BoolFlag{
+			Name:  "once",
+			Usage: "poll once and exit (for cron)",
+		},
+		notifyFlag(),
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		interval := cmd.Duration("interval")
+		if interval < time.Minute {
+			return fmt.Errorf("--interval must be at least 1m")
+		}
+		notifier, err := flagNotifier(cmd)
+		if err != nil {
+			return err
+		}
+		bwhClient, resolvedName, err := createBWHClient(cmd)
+		if err != nil {
+			return err
+		}
+		dir, err := history.DefaultDir()
+		if err != nil {
+			return err
+		}
+		store := history.NewNullrouteStore(dir, resolvedName)
+
+		if cmd.Bool("once") {
+			_, err := pollNullroutes(ctx, bwhClient, store, notifier, resolvedName, time.Now())
+			return err
+		}
+
+		ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		fmt.Printf("👀 Watching nullroutes for instance %s every %s (Ctrl+C to stop)\n", resolvedName, interval)
+		for {
+			if _, err := pollNullroutes(ctx, bwhClient, store, notifier, resolvedName, time.Now()); err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				fmt.Printf("⚠️  %v\n", err)
+			}
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(interval):
+			}
+		}
+	},
+}
+
+var nullroutesHistoryCmd = &cli.Command{
+	Name:  "history",
+	Usage: "show nullroute events recorded by 'bwh network nullroutes watch'",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "since",
+			Usage: "only show events at or after this time (RFC3339, YYYY-MM-DD or an age such as 30d)",
+		},
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		since, err := parseTimeFlag(cmd.String("since"), time.Now())
+		if err != nil {
+			return fmt.Errorf("invalid --since: %w", err)
+		}
+		manager, err := createConfigManager(cmd)
+		if err != nil {
+			return err
+		}
+		_, resolvedName, err := resolveInstanceWithFallback(manager, cmd.String("instance"))
+		if err != nil {
+			return err
+		}
+		dir, err := history.DefaultDir()
+		if err != nil {
+			return err
+		}
+		return runNullroutesHistory(history.NewNullrouteStore(dir, resolvedName), resolvedName, since)
+	},
+}
+
+// nullrouteRemaining returns how long a nullroute still lasts. ok is false if
+// KiwiVM did not report a duration.
+func nullrouteRemaining(n client.IPNullrouteInfo, now time.Time) (remaining time.Duration, ok bool) {
+	if n.NullrouteTimestamp == 0 || n.NullrouteDurationSeconds <= 0 {
+		return 0, false
+	}
+	end := time.Unix(n.NullrouteTimestamp, 0).Add(time.Duration(n.NullrouteDurationSeconds) * time.Second)
+	return end.Sub(now), true
+}
+
+func formatNullrouteRemaining(n client.IPNullrouteInfo, now time.Time) string {
+	remaining, ok := nullrouteRemaining(n, now)
+	switch {
+	case !ok:
+		return "unknown"
+	case remaining <= 0:
+		return "release pending"
+	default:
+		return formatDuration(remaining) + " remaining"
+	}
+}
+
+func displayNullroutes(nullroutes client.IPNullroutes, instanceName string, now time.Time) {
+	if len(nullroutes) == 0 {
+		fmt.Printf("✅ No nullrouted IPs for instance %s\n", instanceName)
+		return
+	}
+
+	fmt.Printf("\n⚠️  Nullrouted IPs: %s - %d IP(s)\n", instanceName, len(nullroutes))
+	for _, ip := range sortedNullrouteIPs(nullroutes) {
+		n := nullroutes[ip]
+		fmt.Printf("\n   %s\n", ip)
+		if n.NullrouteTimestamp > 0 {
+			fmt.Printf("      Since     : %s\n", time.Unix(n.NullrouteTimestamp, 0).Local().Format("2006-01-02 15:04:05"))
+		}
+		if n.NullrouteDurationSeconds > 0 {
+			fmt.Printf("      Duration  : %s\n", formatDuration(time.Duration(n.NullrouteDurationSeconds)*time.Second))
+		}
+		fmt.Printf("      Remaining : %s\n", formatNullrouteRemaining(n, now))
+		if n.Log != "" {
+			fmt.Printf("      Log       : %s\n", n.Log)
+		}
+	}
+}
+
+// diffNullroutes returns the events that turn the recorded active nullroutes
+// into current. An IP that was released and nullrouted again between two
+// polls shows up as a new nullroute timestamp.
+func diffNullroutes(active map[string]history.NullrouteEvent, current client.IPNullroutes, now time.Time) []history.NullrouteEvent {
+	var events []history.NullrouteEvent
+	for _, ip := range sortedNullrouteIPs(current) {
+		n := current[ip]
+		if prev, ok := active[ip]; ok && prev.NullrouteTimestamp == n.NullrouteTimestamp {
+			continue
+		}
+		events = append(events, history.NullrouteEvent{
+			Time:               now,
+			IP:                 ip,
+			Event:              history.NullrouteStarted,
+			NullrouteTimestamp: n.NullrouteTimestamp,
+			DurationSeconds:    n.NullrouteDurationSeconds,
+			Log:                n.Log,
+		})
+	}
+
+	for _, ip := range slices.Sorted(maps.Keys(active)) {
+		if _, ok := current[ip]; !ok {
+			events = append(events, history.NullrouteEvent{Time: now, IP: ip, Event: history.NullrouteReleased})
+		}
+	}
+	return events
+}
+
+type serviceInfoAPI interface {
+	GetServiceInfo(context.Context) (*client.ServiceInfo, error)
+}
+
+type eventNotifier interface {
+	Send(ctx context.Context, e notify.Event) error
+}
+
+// pollNullroutes records and reports the nullroute changes since the last
+// poll and sends them to notifier if there is one. Notification failures are
+// printed to stderr but do not fail the poll.
+func pollNullroutes(ctx context.Context, api serviceInfoAPI, store *history.NullrouteStore, notifier eventNotifier, resolvedName string, now time.Time) ([]history.NullrouteEvent, error) {
+	info, err := api.GetServiceInfo(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get service info: %w", err)
+	}
+	recorded, err := store.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read nullroute history: %w", err)
+	}
+
+	events := diffNullroutes(history.ActiveNullroutes(recorded), info.IPNullroutes, now)
+	if err := store.Append(events...); err != nil {
+		return nil, fmt.Errorf("failed to update nullroute history: %w", err)
+	}
+
+	for _, e := range events {
+		event := nullrouteNotification(e, resolvedName)
+		if e.Event == history.NullrouteStarted {
+			remaining := formatNullrouteRemaining(info.IPNullroutes[e.IP], now)
+			fmt.Printf("🚨 [%s] %s: %s nullrouted (%s)\n", now.Local().Format("2006-01-02 15:04:05"), resolvedName, e.IP, remaining)
+		} else {
+			fmt.Printf("✅ [%s] %s: %s released\n", now.Local().Format("2006-01-02 15:04:05"), resolvedName, e.IP)
+		}
+		sendNotification(ctx, notifier, event)
+	}
+	return events, nil
+}
+
+func nullrouteNotification(e history.NullrouteEvent, instanceName string) notify.Event {
+	event := notify.Event{
+		Instance: instanceName,
+		Source:   "nullroute",
+		Time:     e.Time,
+		Fields:   map[string]string{"ip": e.IP},
+	}
+	if e.Event == history.NullrouteReleased {
+		event.Title = "IP released from nullroute: " + e.IP
+		event.Message = fmt.Sprintf("%s is no longer nullrouted.", e.IP)
+		event.Severity = notify.Info
+		return event
+	}
+
+	event.Title = "IP nullrouted: " + e.IP
+	event.Message = fmt.Sprintf("%s was nullrouted, most likely because of a (D)DoS attack.", e.IP)
+	event.Severity = notify.Critical
+	if e.NullrouteTimestamp > 0 {
+		event.Fields["since"] = time.Unix(e.NullrouteTimestamp, 0).UTC().Format(time.RFC3339)
+	}
+	if e.DurationSeconds > 0 {
+		event.Fields["duration_s"] = strconv.Itoa(e.DurationSeconds)
+	}
+	if e.Log != "" {
+		event.Fields["log"] = e.Log
+	}
+	return event
+}
+
+func runNullroutesHistory(store *history.NullrouteStore, resolvedName string, since time.Time) error {
+	events, err := store.Load()
+	if err != nil {
+		return fmt.Errorf("failed to read nullroute history: %w", err)
+	}
+
+	var shown []history.NullrouteEvent
+	for _, e := range events {
+		if since.IsZero() || !e.Time.Before(since) {
+			shown = append(shown, e)
+		}
+	}
+	if len(shown) == 0 {
+		fmt.Printf("No nullroute events recorded for instance %s. Run 'bwh network nullroutes watch' to record them.\n", resolvedName)
+		return nil
+	}
+
+	fmt.Printf("\nNullroute History: %s\n", resolvedName)
+	fmt.Printf("├─ %d events (oldest first)\n", len(shown))
+	for _, e := range shown {
+		fmt.Printf("├─ [%s] %-10s %s\n", e.Time.Local().Format("2006-01-02 15:04"), e.Event, e.IP)
+	}
+	fmt.Printf("└─ End of nullroute history\n")
+	return nil
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/strahe/bwh/internal/history"
+	"github.com/strahe/bwh/internal/notify"
+	"github.com/strahe/bwh/pkg/client"
+)
+
+type fakeEventNotifier struct {
+	events []notify.Event
+	err    error
+}
+
+func (f *fakeEventNotifier) Send(_ context.Context, e notify.Event) error {
+	f.events = append(f.events, e)
+	return f.err
+}
+
+func TestPollNullroutes(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	store := history.NewNullrouteStore(t.TempDir(), "web")
+	api := &fakeUsageAPI{info: &client.ServiceInfo{}}
+	notifier := &fakeEventNotifier{}
+
+	poll := func(nullroutes client.IPNullroutes) []history.NullrouteEvent {
+		t.Helper()
+		api.info.IPNullroutes = nullroutes
+		var events []history.NullrouteEvent
+		captureStdout(t, func() {
+			var err error
+			if events, err = pollNullroutes(context.Background(), api, store, notifier, "web", now); err != nil {
+				t.Fatalf("pollNullroutes() error = %v", err)
+			}
+		})
+		return events
+	}
+
+	started := now.Add(-time.Hour).Unix()
+	first := client.IPNullroutes{"203.0.113.5": {NullrouteTimestamp: started, NullrouteDurationSeconds: 7200, Log: "UDP flood"}}
+	if events := poll(first); len(events) != 1 || events[0].Event != history.NullrouteStarted || events[0].Log != "UDP flood" {
+		t.Fatalf("first poll = %+v", events)
+	}
+	if len(notifier.events) != 1 || notifier.events[0].Severity != notify.Critical || notifier.events[0].Fields["ip"] != "203.0.113.5" {
+		t.Fatalf("notifications = %+v", notifier.events)
+	}
+
+	if events := poll(first); len(events) != 0 {
+		t.Fatalf("unchanged poll = %+v", events)
+	}
+
+	second := client.IPNullroutes{"203.0.113.6": {NullrouteTimestamp: now.Unix()}}
+	events := poll(second)
+	if len(events) != 2 || events[0].IP != "203.0.113.6" || events[1].Event != history.NullrouteReleased || events[1].IP != "203.0.113.5" {
+		t.Fatalf("third poll = %+v", events)
+	}
+	if notifier.events[2].Severity != notify.Info {
+		t.Fatalf("release notification = %+v", notifier.events[2])
+	}
+
+	// Released and nullrouted again between two polls.
+	again := client.IPNullroutes{"203.0.113.6": {NullrouteTimestamp: now.Unix() + 600}}
+	if events := poll(again); len(events) != 1 || events[0].Event != history.NullrouteStarted {
+		t.Fatalf("renewed nullroute = %+v", events)
+	}
+
+	recorded, err := store.Load()
+	if err != nil || len(recorded) != 4 {
+		t.Fatalf("Load() = %+v, %v", recorded, err)
+	}
+
+	// Notification failures do not lose the recorded event.
+	notifier.err = errors.New("webhook down")
+	var out string
+	errOut := captureStderr(t, func() {
+		out = captureStdout(t, func() {
+			api.info.IPNullroutes = nil
+			if _, err := pollNullroutes(context.Background(), api, store, notifier, "web", now); err != nil {
+				t.Fatalf("pollNullroutes() error = %v", err)
+			}
+		})
+	})
+	if !strings.Contains(errOut, "Failed to send notification: webhook down") || strings.Contains(out, "Failed") {
+		t.Fatalf("stdout = %s, stderr = %s", out, errOut)
+	}
+	if recorded, _ := store.Load(); len(recorded) != 5 {
+		t.Fatalf("recorded %d events, want 5", len(recorded))
+	}
+
+	// Without --notify the changes are only printed and recorded.
+	captureStdout(t, func() {
+		api.info.IPNullroutes = first
+		if _, err := pollNullroutes(context.Background(), api, store, nil, "web", now); err != nil {
+			t.Fatalf("pollNullroutes() without notifier error = %v", err)
+		}
+	})
+	if recorded, _ := store.Load(); len(recorded) != 6 {
+		t.Fatalf("recorded %d events, want 6", len(recorded))
+	}
+}
+
+func TestFormatNullrouteRemaining(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	tests := []struct {
+		info client.IPNullrouteInfo
+		want string
+	}{
+		{client.IPNullrouteInfo{NullrouteTimestamp: now.Add(-time.Hour).Unix(), NullrouteDurationSeconds: 3 * 3600}, "2h remaining"},
+		{client.IPNullrouteInfo{NullrouteTimestamp: now.Add(-time.Hour).Unix(), NullrouteDurationSeconds: 60}, "release pending"},
+		{client.IPNullrouteInfo{NullrouteTimestamp: now.Unix()}, "unknown"},
+	}
+	for _, tt := range tests {
+		if got := formatNullrouteRemaining(tt.info, now); got != tt.want {
+			t.Errorf("formatNullrouteRemaining(%+v) = %q, want %q", tt.info, got, tt.want)
+		}
+	}
+}
@@ -3,7 +3,10 @@ package main
 import (
 	"context"
 	"fmt"
+	"maps"
 	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"text/tabwriter"
 
@@ -21,6 +24,9 @@ var nodeCmd = &cli.Command{
 		nodeSetDefaultCmd,
 		nodeShowCmd,
 		nodeValidateCmd,
+		nodeImportCmd,
+		nodeExportCmd,
+		nodeRefreshCmd,
 	},
 }
 
@@ -30,9 +36,20 @@ var nodeAddCmd = &cli.Command{
 	ArgsUsage: "<name>",
 	Flags: []cli.Flag{
 		&cli.StringFlag{
-			Name:     "api-key",
-			Usage:    "BWH API key",
-			Required: true,
+			Name:  "api-key",
+			Usage: "BWH API key",
+		},
+		&cli.StringFlag{
+			Name:  "api-key-env",
+			Usage: "read the API key from this environment variable when the node is used",
+		},
+		&cli.StringFlag{
+			Name:  "api-key-file",
+			Usage: "read the API key from this file when the node is used",
+		},
+		&cli.StringFlag{
+			Name:  "api-key-command",
+			Usage: "run this shell command and use its first output line as the API key, e.g. 'pass show bwh/prod'",
 		},
 		&cli.StringFlag{
 			Name:     "veid",
@@ -51,6 +68,10 @@ var nodeAddCmd = &cli.Command{
 			Name:  "tags",
 			Usage: "node tags (can be specified multiple times)",
 		},
+		&cli.StringSliceFlag{
+			Name:  "trusted-cidr",
+			Usage: "IPv4 address or CIDR API requests are expected from, checked by 'bwh audit check' (can be specified multiple times)",
+		},
 		&cli.BoolFlag{
 			Name:  "default",
 			Usage: "set this node as default",
@@ -72,18 +93,29 @@ var nodeAddCmd = &cli.Command{
 		}
 		name := args[0]
 		instance := &config.Instance{
-			APIKey:      cmd.String("api-key"),
-			VeID:        cmd.String("veid"),
-			Description: cmd.String("description"),
-			Endpoint:    cmd.String("endpoint"),
-			Tags:        cmd.StringSlice("tags"),
+			APIKey:        cmd.String("api-key"),
+			APIKeyEnv:     cmd.String("api-key-env"),
+			APIKeyFile:    cmd.String("api-key-file"),
+			APIKeyCommand: cmd.String("api-key-command"),
+			VeID:          cmd.String("veid"),
+			Description:   cmd.String("description"),
+			Endpoint:      cmd.String("endpoint"),
+			Tags:          cmd.StringSlice("tags"),
+			TrustedCIDRs:  cmd.StringSlice("trusted-cidr"),
+		}
+		if instance.APIKey == "" && instance.APIKeyEnv == "" && instance.APIKeyFile == "" && instance.APIKeyCommand == "" {
+			return fmt.Errorf("one of --api-key, --api-key-env, --api-key-file or --api-key-command is required")
 		}
 
 		if err := manager.AddInstance(name, instance, cmd.Bool("default")); err != nil {
 			return fmt.Errorf("failed to add node: %w", err)
 		}
 
-		fmt.Printf("Node '%s' added successfully\n", name)
+		if manager.VaultEnabled() {
+			fmt.Printf("Node '%s' added successfully (API key encrypted)\n", name)
+		} else {
+			fmt.Printf("Node '%s' added successfully\n", name)
+		}
 
 		if cmd.Bool("validate") {
 			fmt.Printf("Validating node '%s'...\n", name)
@@ -148,66 +180,158 @@ var nodeListCmd = &cli.Command{
 	Name:    "list",
 	Usage:   "list all configured BWH VPS nodes",
 	Aliases: []string{"ls"},
+	Description: `Hostname, plan, location, OS and IPs come from the cache written by
+'bwh node refresh'; listing never calls the API. The filters match
+case-insensitive substrings of the cached values, so nodes that were never
+refreshed do not match.`,
 	Flags: []cli.Flag{
 		&cli.StringFlag{
 			Name:  "format",
-			Usage: "output format (table, json, yaml)",
+			Usage: "output format (table, json)",
 			Value: "table",
 		},
+		&cli.StringFlag{
+			Name:  "location",
+			Usage: "only list nodes whose location or location ID contains this",
+		},
+		&cli.StringFlag{
+			Name:  "plan",
+			Usage: "only list nodes whose plan contains this",
+		},
+		&cli.StringFlag{
+			Name:  "os",
+			Usage: "only list nodes whose OS contains this",
+		},
 	},
 	Action: func(ctx context.Context, cmd *cli.Command) error {
 		manager, err := createConfigManager(cmd)
 		if err != nil {
 			return err
 		}
+		cache, err := config.LoadMetadataCache(manager.MetadataPath())
+		if err != nil {
+			return err
+		}
 
-		instances := manager.ListInstances()
-		if len(instances) == 0 {
-			fmt.Println("No nodes configured. Use 'bwh node add' to add one.")
-			return nil
+		filter := nodeFilter{
+			Location: cmd.String("location"),
+			Plan:     cmd.String("plan"),
+			OS:       cmd.String("os"),
 		}
+		return runNodeList(manager, cache, filter, cmd.String("format"))
+	},
+}
 
-		defaultInstance := manager.GetDefaultInstance()
+type nodeLister interface {
+	ListInstances() map[string]*config.Instance
+	GetDefaultInstance() string
+	InstanceOrigins(name string) []string
+}
 
-		switch cmd.String("format") {
-		case "table":
-			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-			fmt.Fprintln(w, "NAME\tDESCRIPTION\tVEID\tTAGS\tDEFAULT") //nolint:errcheck
-			for name, instance := range instances {
-				isDefault := ""
-				if name == defaultInstance {
-					isDefault = "*"
-				}
-				tags := strings.Join(instance.Tags, ",")
-				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", //nolint:errcheck
-					name, instance.Description, instance.VeID, tags, isDefault)
-			}
-			w.Flush() //nolint:errcheck
-		case "json":
-			// For JSON output, we need to mask sensitive data
-			maskedInstances := make(map[string]interface{})
-			for name, instance := range instances {
-				maskedInstances[name] = map[string]interface{}{
-					"description": instance.Description,
-					"veid":        instance.VeID,
-					"endpoint":    instance.Endpoint,
-					"tags":        instance.Tags,
-					"api_key":     maskAPIKey(instance.APIKey),
-				}
-			}
-			output := map[string]interface{}{
-				"default_node": defaultInstance,
-				"nodes":        maskedInstances,
-			}
-			if err := printJSON(output); err != nil {
-				return err
+// nodeFilter selects nodes by their cached metadata.
+type nodeFilter struct {
+	Location string
+	Plan     string
+	OS       string
+}
+
+func (f nodeFilter) active() bool {
+	return f.Location != "" || f.Plan != "" || f.OS != ""
+}
+
+func (f nodeFilter) matches(metadata *config.Metadata) bool {
+	if !f.active() {
+		return true
+	}
+	if metadata == nil {
+		return false
+	}
+	return (f.Location == "" || containsFold(metadata.Location, f.Location) || containsFold(metadata.LocationID, f.Location)) &&
+		(f.Plan == "" || containsFold(metadata.Plan, f.Plan)) &&
+		(f.OS == "" || containsFold(metadata.OS, f.OS))
+}
+
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+func runNodeList(lister nodeLister, cache *config.MetadataCache, filter nodeFilter, format string) error {
+	if format != "table" && format != "json" {
+		return fmt.Errorf("unsupported format: %s", format)
+	}
+
+	instances := lister.ListInstances()
+	if len(instances) == 0 {
+		fmt.Println("No nodes configured. Use 'bwh node add' to add one.")
+		return nil
+	}
+	defaultInstance := lister.GetDefaultInstance()
+
+	var names []string
+	unrefreshed := 0
+	for _, name := range slices.Sorted(maps.Keys(instances)) {
+		metadata := cache.Get(name, instances[name])
+		if metadata == nil {
+			unrefreshed++
+		}
+		if filter.matches(metadata) {
+			names = append(names, name)
+		}
+	}
+	if filter.active() && unrefreshed > 0 {
+		fmt.Fprintf(os.Stderr, "⚠️  %d node(s) have no cached metadata and were skipped; run 'bwh node refresh --all'\n", unrefreshed) //nolint:errcheck
+	}
+
+	if format == "json" {
+		// For JSON output, we need to mask sensitive data
+		maskedInstances := make(map[string]interface{})
+		for _, name := range names {
+			instance := instances[name]
+			maskedInstances[name] = map[string]interface{}{
+				"description": instance.Description,
+				"veid":        instance.VeID,
+				"endpoint":    instance.Endpoint,
+				"tags":        instance.Tags,
+				"api_key":     displayAPIKey(instance),
+				"origin":      lister.InstanceOrigins(name),
+				"metadata":    cache.Get(name, instance),
 			}
-		default:
-			return fmt.Errorf("unsupported format: %s", cmd.String("format"))
 		}
+		output := map[string]interface{}{
+			"default_node": defaultInstance,
+			"nodes":        maskedInstances,
+		}
+		return printJSON(output)
+	}
 
+	if len(names) == 0 {
+		fmt.Println("No nodes match the filters.")
 		return nil
-	},
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tDESCRIPTION\tVEID\tHOSTNAME\tPLAN\tLOCATION\tOS\tIP\tTAGS\tDEFAULT\tORIGIN") //nolint:errcheck
+	for _, name := range names {
+		instance := instances[name]
+		isDefault := ""
+		if name == defaultInstance {
+			isDefault = "*"
+		}
+		hostname, plan, location, osName, ip := "-", "-", "-", "-", "-"
+		if metadata := cache.Get(name, instance); metadata != nil {
+			hostname, plan, location, osName = metadata.Hostname, metadata.Plan, metadata.Location, metadata.OS
+			if len(metadata.IPAddresses) > 0 {
+				ip = metadata.IPAddresses[0]
+				if len(metadata.IPAddresses) > 1 {
+					ip += fmt.Sprintf(" (+%d)", len(metadata.IPAddresses)-1)
+				}
+			}
+		}
+		tags := strings.Join(instance.Tags, ",")
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", //nolint:errcheck
+			name, instance.Description, instance.VeID, hostname, plan, location, osName, ip, tags, isDefault,
+			displayOrigins(lister.InstanceOrigins(name)))
+	}
+	return w.Flush()
 }
 
 var nodeSetDefaultCmd = &cli.Command{
@@ -240,33 +364,35 @@ var nodeShowCmd = &cli.Command{
 			return err
 		}
 
-		var instanceName string
-		args := cmd.Args().Slice()
-		if len(args) > 0 {
-			instanceName = args[0]
-		} else {
-			// Show default node if no name specified
-			instanceName = manager.GetDefaultInstance()
-			if instanceName == "" {
-				return fmt.Errorf("no default node set and no node name provided")
-			}
-		}
-
-		instance, err := manager.GetInstance(instanceName)
+		// Show the node commands would use if no name is specified
+		instance, instanceName, err := manager.FindInstance(cmd.Args().First())
 		if err != nil {
 			return err
 		}
+		sources := manager.Sources(instanceName, instance)
 
 		fmt.Printf("Node: %s\n", instanceName)
 		fmt.Printf("Description: %s\n", instance.Description)
-		fmt.Printf("VeID: %s\n", instance.VeID)
-		fmt.Printf("API Key: %s\n", maskAPIKey(instance.APIKey))
+		fmt.Printf("VeID: %s (from %s)\n", instance.VeID, sources.VeID)
+		switch {
+		case sources.APIKey == "":
+			fmt.Printf("API Key: not configured\n")
+		case instance.APIKey != "":
+			fmt.Printf("API Key: %s (from %s)\n", maskAPIKey(instance.APIKey), sources.APIKey)
+		default:
+			fmt.Printf("API Key: from %s\n", sources.APIKey)
+		}
 		if instance.Endpoint != "" {
-			fmt.Printf("Endpoint: %s\n", instance.Endpoint)
+			fmt.Printf("Endpoint: %s (from %s)\n", instance.Endpoint, sources.Endpoint)
+		} else {
+			fmt.Printf("Endpoint: default\n")
 		}
 		if len(instance.Tags) > 0 {
 			fmt.Printf("Tags: %s\n", strings.Join(instance.Tags, ", "))
 		}
+		if len(instance.TrustedCIDRs) > 0 {
+			fmt.Printf("Trusted CIDRs: %s\n", strings.Join(instance.TrustedCIDRs, ", "))
+		}
 		if instanceName == manager.GetDefaultInstance() {
 			fmt.Printf("Default: Yes\n")
 		}
@@ -311,3 +437,36 @@ var nodeValidateCmd = &cli.Command{
 func maskAPIKey(apiKey string) string {
 	return maskSecret(apiKey)
 }
+
+// displayOrigins shortens and joins the config files an instance is defined
+// in.
+func displayOrigins(paths []string) string {
+	homeDir, _ := os.UserHomeDir()
+	shown := make([]string, 0, len(paths))
+	for _, path := range paths {
+		if homeDir != "" {
+			if rest, ok := strings.CutPrefix(path, homeDir+string(os.PathSeparator)); ok {
+				path = "~/" + filepath.ToSlash(rest)
+			}
+		}
+		shown = append(shown, path)
+	}
+	return strings.Join(shown, " + ")
+}
+
+// displayAPIKey masks the API key of instance, or describes where it is read
+// from.
+func displayAPIKey(instance *config.Instance) string {
+	switch {
+	case instance.APIKeyEncrypted != "":
+		return "(encrypted)"
+	case instance.APIKeyEnv != "":
+		return "(from $" + instance.APIKeyEnv + ")"
+	case instance.APIKeyFile != "":
+		return "(from file " + instance.APIKeyFile + ")"
+	case instance.APIKeyCommand != "":
+		return "(from command: " + instance.APIKeyCommand + ")"
+	default:
+		return maskAPIKey(instance.APIKey)
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+
+	"github.com/strahe/bwh/internal/config"
+	"github.com/urfave/cli/v3"
+	"gopkg.in/yaml.v3"
+)
+
+var nodeExportCmd = &cli.Command{
+	Name:  "export",
+	Usage: "export the configured nodes as YAML, JSON or CSV",
+	Description: `YAML output has the layout of the config file and can be checked into a
+project as bwh.yaml; JSON and CSV output can be read by 'bwh node import'.
+
+Without --redact the output contains the API keys, decrypted if the config
+is encrypted. api_key_env, api_key_file and api_key_command references are
+exported as they are.`,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "format",
+			Usage: "output format: yaml, json or csv",
+			Value: "yaml",
+		},
+		&cli.BoolFlag{
+			Name:  "redact",
+			Usage: "leave out API keys to produce a shareable inventory",
+		},
+		&cli.StringFlag{
+			Name:    "output",
+			Aliases: []string{"o"},
+			Usage:   "write to a file instead of stdout",
+		},
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		format := cmd.String("format")
+		if format != "yaml" && format != "json" && format != exportFormatCSV {
+			return fmt.Errorf("unsupported format %q: use yaml, json or csv", format)
+		}
+
+		manager, err := createConfigManager(cmd)
+		if err != nil {
+			return err
+		}
+		redact := cmd.Bool("redact")
+		instances, err := exportInstances(manager, manager.ListInstances(), redact)
+		if err != nil {
+			return err
+		}
+
+		var buf bytes.Buffer
+		if err := writeNodeExport(&buf, format, manager.GetDefaultInstance(), instances); err != nil {
+			return err
+		}
+		if !redact && hasPlainAPIKey(instances) {
+			fmt.Fprintln(os.Stderr, "⚠️  The export contains API keys; use --redact to leave them out") //nolint:errcheck
+		}
+
+		output := cmd.String("output")
+		if output == "" {
+			fmt.Print(buf.String())
+			return nil
+		}
+		mode := os.FileMode(0o644)
+		if !redact {
+			mode = 0o600
+		}
+		if err := os.WriteFile(output, buf.Bytes(), mode); err != nil {
+			return fmt.Errorf("failed to write export: %w", err)
+		}
+		fmt.Printf("✅ Exported %d node(s) to %s\n", len(instances), output)
+		return nil
+	},
+}
+
+type instanceOpener interface {
+	OpenInstance(name string) (*config.Instance, error)
+}
+
+// exportInstances copies instances for export. Redacted copies have no API
+// key; otherwise encrypted keys are decrypted, since the vault key does not
+// travel with the export.
+func exportInstances(opener instanceOpener, instances map[string]*config.Instance, redact bool) (map[string]*config.Instance, error) {
+	exported := make(map[string]*config.Instance, len(instances))
+	for name, instance := range instances {
+		copied := *instance
+		switch {
+		case redact:
+			copied.APIKey = ""
+			copied.APIKeyEncrypted = ""
+		case instance.APIKeyEncrypted != "":
+			opened, err := opener.OpenInstance(name)
+			if err != nil {
+				return nil, err
+			}
+			copied.APIKey = opened.APIKey
+			copied.APIKeyEncrypted = ""
+		}
+		exported[name] = &copied
+	}
+	return exported, nil
+}
+
+func hasPlainAPIKey(instances map[string]*config.Instance) bool {
+	for _, instance := range instances {
+		if instance.APIKey != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func writeNodeExport(w io.Writer, format, defaultInstance string, instances map[string]*config.Instance) error {
+	if format == "yaml" {
+		data, err := yaml.Marshal(&config.Config{
+			Version:         config.CurrentVersion,
+			DefaultInstance: defaultInstance,
+			Instances:       instances,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to marshal nodes: %w", err)
+		}
+		_, err = w.Write(data)
+		return err
+	}
+
+	records := make([]nodeRecord, 0, len(instances))
+	for _, name := range slices.Sorted(maps.Keys(instances)) {
+		instance := instances[name]
+		records = append(records, nodeRecord{
+			Name:          name,
+			VeID:          instance.VeID,
+			APIKey:        instance.APIKey,
+			APIKeyEnv:     instance.APIKeyEnv,
+			APIKeyFile:    instance.APIKeyFile,
+			APIKeyCommand: instance.APIKeyCommand,
+			Description:   instance.Description,
+			Endpoint:      instance.Endpoint,
+			Tags:          instance.Tags,
+			TrustedCIDRs:  instance.TrustedCIDRs,
+		})
+	}
+	if format == "json" {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(records)
+	}
+	return writeExport(w, exportFormatCSV, nodeRecordColumns, records)
+}
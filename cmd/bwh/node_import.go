@@ -0,0 +1,304 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/strahe/bwh/internal/config"
+	"github.com/urfave/cli/v3"
+)
+
+var nodeImportCmd = &cli.Command{
+	Name:  "import",
+	Usage: "add nodes in bulk from a CSV or JSON file",
+	Description: `CSV files need a header row naming the columns, in any order:
+
+  name,veid,api_key,api_key_env,api_key_file,api_key_command,description,endpoint,tags,trusted_cidrs
+
+Only name, veid and one API key column are required. tags and trusted_cidrs
+hold several values separated by ";". JSON files hold an array of objects
+with the same keys, tags and trusted_cidrs as arrays. 'bwh node export'
+writes both formats.
+
+Every row is validated like 'bwh node add'; with --validate the API
+connection of the added nodes is checked concurrently.`,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "file",
+			Aliases:  []string{"f"},
+			Usage:    "CSV or JSON file to import ('-' for stdin)",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:  "format",
+			Usage: "file format: csv or json (default: from the file extension)",
+		},
+		&cli.BoolFlag{
+			Name:  "validate",
+			Usage: "check the API connection of the imported nodes",
+		},
+		&cli.IntFlag{
+			Name:  "concurrency",
+			Usage: "number of connection checks run at once",
+			Value: 8,
+		},
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		path := cmd.String("file")
+		format := cmd.String("format")
+		if format == "" {
+			format = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+		}
+
+		var data []byte
+		var err error
+		if path == "-" {
+			data, err = io.ReadAll(os.Stdin)
+		} else {
+			data, err = os.ReadFile(path)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read import file: %w", err)
+		}
+		records, err := parseNodeRecords(data, format)
+		if err != nil {
+			return err
+		}
+
+		manager, err := createConfigManager(cmd)
+		if err != nil {
+			return err
+		}
+		var check connectivityCheck
+		if cmd.Bool("validate") {
+			check = checkInstanceConnectivity
+		}
+		return runNodeImport(ctx, manager, records, check, int(cmd.Int("concurrency")))
+	},
+}
+
+// nodeRecord is one node in an import or export file.
+type nodeRecord struct {
+	Name          string   `json:"name"`
+	VeID          string   `json:"veid"`
+	APIKey        string   `json:"api_key,omitempty"`
+	APIKeyEnv     string   `json:"api_key_env,omitempty"`
+	APIKeyFile    string   `json:"api_key_file,omitempty"`
+	APIKeyCommand string   `json:"api_key_command,omitempty"`
+	Description   string   `json:"description,omitempty"`
+	Endpoint      string   `json:"endpoint,omitempty"`
+	Tags          []string `json:"tags,omitempty"`
+	TrustedCIDRs  []string `json:"trusted_cidrs,omitempty"`
+
+	// source locates the record in the import file for error reports.
+	source string
+}
+
+// nodeRecordColumns is the CSV header of node records.
+var nodeRecordColumns = []string{"name", "veid", "api_key", "api_key_env", "api_key_file", "api_key_command", "description", "endpoint", "tags", "trusted_cidrs"}
+
+// csvListSeparator separates the values of list columns in CSV files.
+const csvListSeparator = ";"
+
+func (r nodeRecord) csvRecord() []string {
+	return []string{r.Name, r.VeID, r.APIKey, r.APIKeyEnv, r.APIKeyFile, r.APIKeyCommand, r.Description, r.Endpoint,
+		strings.Join(r.Tags, csvListSeparator), strings.Join(r.TrustedCIDRs, csvListSeparator)}
+}
+
+func (r nodeRecord) instance() *config.Instance {
+	return &config.Instance{
+		APIKey:        r.APIKey,
+		APIKeyEnv:     r.APIKeyEnv,
+		APIKeyFile:    r.APIKeyFile,
+		APIKeyCommand: r.APIKeyCommand,
+		VeID:          r.VeID,
+		Description:   r.Description,
+		Endpoint:      r.Endpoint,
+		Tags:          r.Tags,
+		TrustedCIDRs:  r.TrustedCIDRs,
+	}
+}
+
+func parseNodeRecords(data []byte, format string) ([]nodeRecord, error) {
+	switch format {
+	case "csv":
+		return parseNodeCSV(data)
+	case "json":
+		var records []nodeRecord
+		if err := json.Unmarshal(data, &records); err != nil {
+			return nil, fmt.Errorf("failed to parse JSON import file: %w", err)
+		}
+		for i := range records {
+			records[i].source = fmt.Sprintf("entry %d", i+1)
+		}
+		return records, nil
+	default:
+		return nil, fmt.Errorf("unsupported import format %q: use csv or json", format)
+	}
+}
+
+func parseNodeCSV(data []byte) ([]nodeRecord, error) {
+	reader := csv.NewReader(strings.NewReader(string(data)))
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	for i, column := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(column))
+		if !slices.Contains(nodeRecordColumns, header[i]) {
+			return nil, fmt.Errorf("unknown CSV column %q; known columns: %s", column, strings.Join(nodeRecordColumns, ", "))
+		}
+	}
+
+	var records []nodeRecord
+	for {
+		fields, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse CSV import file: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+		record := nodeRecord{source: fmt.Sprintf("line %d", line)}
+		for i, value := range fields {
+			value = strings.TrimSpace(value)
+			switch header[i] {
+			case "name":
+				record.Name = value
+			case "veid":
+				record.VeID = value
+			case "api_key":
+				record.APIKey = value
+			case "api_key_env":
+				record.APIKeyEnv = value
+			case "api_key_file":
+				record.APIKeyFile = value
+			case "api_key_command":
+				record.APIKeyCommand = value
+			case "description":
+				record.Description = value
+			case "endpoint":
+				record.Endpoint = value
+			case "tags":
+				record.Tags = splitCSVList(value)
+			case "trusted_cidrs":
+				record.TrustedCIDRs = splitCSVList(value)
+			}
+		}
+		records = append(records, record)
+	}
+}
+
+func splitCSVList(value string) []string {
+	var values []string
+	for _, v := range strings.Split(value, csvListSeparator) {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+type nodeImporter interface {
+	AddInstance(name string, instance *config.Instance, setDefault bool) error
+	OpenInstance(name string) (*config.Instance, error)
+}
+
+// connectivityCheck tests the API connection of an instance.
+type connectivityCheck func(ctx context.Context, instance *config.Instance) error
+
+func checkInstanceConnectivity(ctx context.Context, instance *config.Instance) error {
+	_, err := newInstanceClient(instance).GetServiceInfo(ctx)
+	return err
+}
+
+// runNodeImport adds records one by one and reports the result of each. With
+// a non-nil check, the connection of every added node is then tested with up
+// to concurrency checks at once; failures are reported but the nodes are
+// kept, like 'bwh node add --validate'.
+func runNodeImport(ctx context.Context, importer nodeImporter, records []nodeRecord, check connectivityCheck, concurrency int) error {
+	if len(records) == 0 {
+		return fmt.Errorf("no nodes found in the import file")
+	}
+
+	seen := map[string]string{}
+	results := make([]error, len(records))
+	var added []int
+	for i, record := range records {
+		if prev, ok := seen[record.Name]; ok && record.Name != "" {
+			results[i] = fmt.Errorf("duplicate of %s", prev)
+			continue
+		}
+		seen[record.Name] = record.source
+		if err := importer.AddInstance(record.Name, record.instance(), false); err != nil {
+			results[i] = err
+			continue
+		}
+		added = append(added, i)
+	}
+
+	warnings := make([]error, len(records))
+	if check != nil && len(added) > 0 {
+		if concurrency < 1 {
+			concurrency = 1
+		}
+		// API keys are resolved one at a time: the vault may prompt once and
+		// api_key_command helpers may be interactive.
+		instances := make([]*config.Instance, len(records))
+		for _, i := range added {
+			instances[i], warnings[i] = importer.OpenInstance(records[i].Name)
+		}
+
+		var wg sync.WaitGroup
+		sem := make(chan struct{}, concurrency)
+		for _, i := range added {
+			if instances[i] == nil {
+				continue
+			}
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				sem <- struct{}{}
+				defer func() { <-sem }()
+				warnings[i] = check(ctx, instances[i])
+			}(i)
+		}
+		wg.Wait()
+	}
+
+	failed := 0
+	for i, record := range records {
+		name := record.Name
+		if name == "" {
+			name = "(no name)"
+		}
+		switch {
+		case results[i] != nil:
+			failed++
+			fmt.Printf("❌ %s %s: %v\n", record.source, name, results[i])
+		case warnings[i] != nil:
+			fmt.Printf("⚠️  %s %s: added, but connection failed: %v\n", record.source, name, warnings[i])
+		case check != nil:
+			fmt.Printf("✅ %s %s: added, connection OK\n", record.source, name)
+		default:
+			fmt.Printf("✅ %s %s: added\n", record.source, name)
+		}
+	}
+
+	fmt.Printf("\nImported %d of %d node(s)\n", len(added), len(records))
+	if failed > 0 {
+		return fmt.Errorf("failed to import %d node(s)", failed)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/strahe/bwh/internal/config"
+)
+
+func TestParseNodeCSV(t *testing.T) {
+	data := []byte(`name, VEID, api_key, tags, trusted_cidrs
+prod,100,prod-api-key-123456789,web; eu ,10.0.0.0/8;192.0.2.1
+ci,200,,,
+`)
+	records, err := parseNodeRecords(data, "csv")
+	if err != nil {
+		t.Fatalf("parseNodeRecords() error = %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("parseNodeRecords() returned %d records, want 2", len(records))
+	}
+	prod := records[0]
+	if prod.Name != "prod" || prod.VeID != "100" || prod.APIKey != "prod-api-key-123456789" || prod.source != "line 2" {
+		t.Errorf("record 0 = %+v", prod)
+	}
+	if !slices.Equal(prod.Tags, []string{"web", "eu"}) || !slices.Equal(prod.TrustedCIDRs, []string{"10.0.0.0/8", "192.0.2.1"}) {
+		t.Errorf("record 0 lists = %v, %v", prod.Tags, prod.TrustedCIDRs)
+	}
+	if records[1].Tags != nil || records[1].source != "line 3" {
+		t.Errorf("record 1 = %+v", records[1])
+	}
+
+	if _, err := parseNodeRecords([]byte("name,veid,apikey\n"), "csv"); err == nil || !strings.Contains(err.Error(), "apikey") {
+		t.Errorf("parseNodeRecords() with unknown column error = %v", err)
+	}
+	if _, err := parseNodeRecords(nil, "xml"); err == nil {
+		t.Errorf("parseNodeRecords() with unknown format succeeded")
+	}
+}
+
+func TestRunNodeImport(t *testing.T) {
+	t.Setenv(config.NoProjectConfigEnv, "1")
+	manager, err := config.NewManager(filepath.Join(t.TempDir(), "config.yaml"))
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+
+	records, err := parseNodeRecords([]byte(`[
+  {"name": "prod", "veid": "1", "api_key": "prod-api-key-123456789"},
+  {"name": "dev", "veid": "2", "api_key": "short"},
+  {"name": "prod", "veid": "3", "api_key": "other-api-key-123456789"},
+  {"name": "down", "veid": "4", "api_key": "down-api-key-123456789", "tags": ["lab"]}
+]`), "json")
+	if err != nil {
+		t.Fatalf("parseNodeRecords() error = %v", err)
+	}
+
+	var checks atomic.Int32
+	check := func(ctx context.Context, instance *config.Instance) error {
+		checks.Add(1)
+		if instance.VeID == "4" {
+			return errors.New("connection refused")
+		}
+		return nil
+	}
+
+	var runErr error
+	out := captureStdout(t, func() {
+		runErr = runNodeImport(context.Background(), manager, records, check, 2)
+	})
+	if runErr == nil || !strings.Contains(runErr.Error(), "2 node(s)") {
+		t.Errorf("runNodeImport() error = %v, want 2 failed nodes", runErr)
+	}
+	for _, want := range []string{
+		"✅ entry 1 prod: added, connection OK",
+		"❌ entry 2 dev: invalid API key format",
+		"❌ entry 3 prod: duplicate of entry 1",
+		"⚠️  entry 4 down: added, but connection failed: connection refused",
+		"Imported 2 of 4 node(s)",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output does not contain %q:\n%s", want, out)
+		}
+	}
+	if checks.Load() != 2 {
+		t.Errorf("connectivity checked %d times, want 2", checks.Load())
+	}
+	if down, err := manager.GetInstance("down"); err != nil || !slices.Equal(down.Tags, []string{"lab"}) {
+		t.Errorf("GetInstance(down) = %+v, %v", down, err)
+	}
+}
+
+func TestNodeExport(t *testing.T) {
+	instances := map[string]*config.Instance{
+		"prod": {APIKey: "prod-api-key-123456789", VeID: "1", Tags: []string{"web", "eu"}},
+		"ci":   {APIKeyEnv: "BWH_CI_KEY", VeID: "2"},
+	}
+
+	redacted, err := exportInstances(nil, instances, true)
+	if err != nil {
+		t.Fatalf("exportInstances() error = %v", err)
+	}
+	if hasPlainAPIKey(redacted) || instances["prod"].APIKey == "" {
+		t.Errorf("redaction failed or modified the input: %+v", redacted["prod"])
+	}
+
+	var buf bytes.Buffer
+	if err := writeNodeExport(&buf, "yaml", "prod", redacted); err != nil {
+		t.Fatalf("writeNodeExport(yaml) error = %v", err)
+	}
+	if out := buf.String(); strings.Contains(out, "prod-api-key") || !strings.Contains(out, "api_key_env: BWH_CI_KEY") || !strings.Contains(out, "default_instance: prod") {
+		t.Errorf("YAML export:\n%s", out)
+	}
+
+	buf.Reset()
+	if err := writeNodeExport(&buf, exportFormatCSV, "", instances); err != nil {
+		t.Fatalf("writeNodeExport(csv) error = %v", err)
+	}
+	records, err := parseNodeRecords(buf.Bytes(), "csv")
+	if err != nil {
+		t.Fatalf("parseNodeRecords() of the CSV export error = %v", err)
+	}
+	if len(records) != 2 || records[1].Name != "prod" || records[1].APIKey != "prod-api-key-123456789" || !slices.Equal(records[1].Tags, []string{"web", "eu"}) {
+		t.Errorf("CSV round trip = %+v", records)
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/strahe/bwh/internal/config"
+	"github.com/urfave/cli/v3"
+)
+
+var nodeRefreshCmd = &cli.Command{
+	Name:      "refresh",
+	Usage:     "fetch and cache hostname, plan, location, IPs and OS of nodes",
+	ArgsUsage: "[name]",
+	Description: `The metadata is cached in metadata.json next to the config file, where
+'bwh node list' reads it to show and filter nodes without calling the API.
+Without a name the node commands would use is refreshed.`,
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "all",
+			Usage: "refresh every configured node",
+		},
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		manager, err := createConfigManager(cmd)
+		if err != nil {
+			return err
+		}
+		cache, err := config.LoadMetadataCache(manager.MetadataPath())
+		if err != nil {
+			return err
+		}
+
+		if !cmd.Bool("all") {
+			name := cmd.Args().First()
+			if name == "" {
+				name = cmd.String("instance")
+			}
+			instance, name, err := resolveInstanceWithFallback(manager, name)
+			if err != nil {
+				return err
+			}
+			if err := runNodeRefresh(ctx, newInstanceClient(instance), cache, name, instance); err != nil {
+				return err
+			}
+			return cache.Save()
+		}
+
+		instances := manager.ListInstances()
+		if len(instances) == 0 {
+			return fmt.Errorf("no instances configured. Run 'bwh node add <name>' to add one")
+		}
+		cache.Prune(instances)
+
+		var failed []string
+		for _, name := range slices.Sorted(maps.Keys(instances)) {
+			instance, err := manager.OpenInstance(name)
+			if err == nil {
+				err = runNodeRefresh(ctx, newInstanceClient(instance), cache, name, instance)
+			}
+			if err != nil {
+				fmt.Printf("❌ %s: %v\n", name, err)
+				failed = append(failed, name)
+			}
+		}
+		if err := cache.Save(); err != nil {
+			return err
+		}
+		if len(failed) > 0 {
+			return fmt.Errorf("failed to refresh %d node(s): %v", len(failed), failed)
+		}
+		return nil
+	},
+}
+
+func runNodeRefresh(ctx context.Context, api serviceInfoAPI, cache *config.MetadataCache, name string, instance *config.Instance) error {
+	info, err := api.GetServiceInfo(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get service info: %w", err)
+	}
+	cache.Set(name, &config.Metadata{
+		VeID:        instance.VeID,
+		Hostname:    info.Hostname,
+		Plan:        info.Plan,
+		LocationID:  info.NodeLocationID,
+		Location:    info.NodeLocation,
+		IPAddresses: info.IPAddresses,
+		VMType:      info.VMType,
+		OS:          info.OS,
+		RefreshedAt: time.Now().UTC(),
+	})
+	fmt.Printf("✅ %s: %s, %s, %s\n", name, info.Hostname, info.Plan, info.NodeLocation)
+	return nil
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/strahe/bwh/internal/config"
+	"github.com/strahe/bwh/pkg/client"
+)
+
+type fakeNodeInfoAPI struct {
+	info *client.ServiceInfo
+	err  error
+}
+
+func (f *fakeNodeInfoAPI) GetServiceInfo(context.Context) (*client.ServiceInfo, error) {
+	return f.info, f.err
+}
+
+type fakeNodeLister struct {
+	instances map[string]*config.Instance
+}
+
+func (f *fakeNodeLister) ListInstances() map[string]*config.Instance { return f.instances }
+func (f *fakeNodeLister) GetDefaultInstance() string                 { return "la" }
+func (f *fakeNodeLister) InstanceOrigins(string) []string            { return []string{"/etc/bwh.yaml"} }
+
+func TestNodeRefreshAndList(t *testing.T) {
+	cache, err := config.LoadMetadataCache(filepath.Join(t.TempDir(), config.MetadataFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lister := &fakeNodeLister{instances: map[string]*config.Instance{
+		"la":  {VeID: "1"},
+		"ams": {VeID: "2"},
+		"new": {VeID: "3"},
+	}}
+
+	refresh := func(name string, info *client.ServiceInfo) {
+		t.Helper()
+		captureStdout(t, func() {
+			if err := runNodeRefresh(context.Background(), &fakeNodeInfoAPI{info: info}, cache, name, lister.instances[name]); err != nil {
+				t.Fatalf("runNodeRefresh(%s) error = %v", name, err)
+			}
+		})
+	}
+	refresh("la", &client.ServiceInfo{Hostname: "la.example.com", Plan: "KVM 20G", NodeLocationID: "USCA_8",
+		NodeLocation: "US, California", OS: "debian-12-x86_64", IPAddresses: []string{"192.0.2.1", "2001:db8::/64"}})
+	refresh("ams", &client.ServiceInfo{Hostname: "ams.example.com", Plan: "KVM 40G", NodeLocationID: "EUNL_3",
+		NodeLocation: "EU, Netherlands", OS: "ubuntu-24.04-x86_64", IPAddresses: []string{"198.51.100.1"}})
+
+	err = runNodeRefresh(context.Background(), &fakeNodeInfoAPI{err: errors.New("boom")}, cache, "new", lister.instances["new"])
+	if err == nil || cache.Get("new", lister.instances["new"]) != nil {
+		t.Errorf("runNodeRefresh() with an API error = %v", err)
+	}
+
+	out := captureStdout(t, func() {
+		if err := runNodeList(lister, cache, nodeFilter{}, "table"); err != nil {
+			t.Fatalf("runNodeList() error = %v", err)
+		}
+	})
+	for _, want := range []string{"la.example.com", "192.0.2.1 (+1)", "EU, Netherlands", "debian-12-x86_64"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("table does not contain %q:\n%s", want, out)
+		}
+	}
+	if lines := strings.Split(strings.TrimSpace(out), "\n"); len(lines) != 4 || !strings.HasPrefix(lines[1], "ams") {
+		t.Errorf("table rows are not sorted by name:\n%s", out)
+	}
+
+	tests := []struct {
+		filter nodeFilter
+		want   []string
+	}{
+		{nodeFilter{Location: "usca"}, []string{"la"}},
+		{nodeFilter{Location: "netherlands"}, []string{"ams"}},
+		{nodeFilter{Plan: "kvm"}, []string{"la", "ams"}},
+		{nodeFilter{OS: "ubuntu", Plan: "20G"}, nil},
+	}
+	for _, tt := range tests {
+		out := captureStdout(t, func() {
+			if err := runNodeList(lister, cache, tt.filter, "table"); err != nil {
+				t.Fatalf("runNodeList(%+v) error = %v", tt.filter, err)
+			}
+		})
+		for _, name := range []string{"la", "ams", "new"} {
+			listed := strings.Contains(out, "\n"+name+" ")
+			if want := slices.Contains(tt.want, name); listed != want {
+				t.Errorf("runNodeList(%+v) listed %s = %v, want %v:\n%s", tt.filter, name, listed, want, out)
+			}
+		}
+	}
+}
+
+func TestInstanceCompletions(t *testing.T) {
+	t.Setenv(config.NoProjectConfigEnv, "1")
+	manager, err := config.NewManager(filepath.Join(t.TempDir(), "config.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, veid := range map[string]string{"la": "1", "ams": "2"} {
+		if err := manager.AddInstance(name, &config.Instance{APIKey: name + "-api-key-123456789", VeID: veid}, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cache, err := config.LoadMetadataCache(manager.MetadataPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache.Set("la", &config.Metadata{VeID: "1", Hostname: "la.example.com", IPAddresses: []string{"192.0.2.1", "2001:db8::/64"}})
+	if err := cache.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"ams", "la", "2", "1", "la.example.com", "192.0.2.1"}
+	if got := instanceCompletions(manager); !slices.Equal(got, want) {
+		t.Errorf("instanceCompletions() = %v, want %v", got, want)
+	}
+}
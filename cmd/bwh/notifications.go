@@ -41,10 +41,11 @@ var notificationsListCmd = &cli.Command{
 }
 
 var notificationsSetCmd = &cli.Command{
-	Name:      "set",
-	Usage:     "set a KiwiVM notification preference",
-	ArgsUsage: "<preference_id> <on|off>",
-	Flags:     writeFlags(),
+	Name:          "set",
+	Usage:         "set a KiwiVM notification preference",
+	ArgsUsage:     "<preference_id> <on|off>",
+	ShellComplete: completeArgs(fromAPI(notificationCompletions), fixedValues("on", "off")),
+	Flags:         writeFlags(),
 	Action: func(ctx context.Context, cmd *cli.Command) error {
 		if cmd.Args().Len() != 2 {
 			return fmt.Errorf("notifications set requires exactly two arguments: <preference_id> <on|off>")
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/strahe/bwh/internal/config"
+	"github.com/strahe/bwh/internal/notify"
+	"github.com/urfave/cli/v3"
+)
+
+var notifyCmd = &cli.Command{
+	Name:  "notify",
+	Usage: "manage alert notification sinks",
+	Description: `Alerts from bwh are sent to the sinks configured under notifiers in the
+config file:
+
+  notifiers:
+    ops:
+      type: webhook        # JSON webhook; also slack, discord, smtp, command
+      url: https://example.com/hooks/bwh
+      min_severity: warning`,
+	Commands: []*cli.Command{
+		notifyTestCmd,
+	},
+}
+
+var notifyTestCmd = &cli.Command{
+	Name:      "test",
+	Usage:     "send a test notification to configured sinks",
+	ArgsUsage: "[name...]",
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		manager, err := createConfigManager(cmd)
+		if err != nil {
+			return err
+		}
+		dispatcher, err := createNotifier(manager)
+		if err != nil {
+			return err
+		}
+
+		names := cmd.Args().Slice()
+		if len(names) == 0 {
+			names = dispatcher.Names()
+		}
+		return runNotifyTest(ctx, dispatcher, names, time.Now())
+	},
+}
+
+// createNotifier builds the notification dispatcher from the config file. It
+// fails if no sinks are configured.
+func createNotifier(manager *config.Manager) (*notify.Dispatcher, error) {
+	dispatcher, err := notify.New(manager.ListNotifiers())
+	if err != nil {
+		return nil, fmt.Errorf("invalid notifier configuration: %w", err)
+	}
+	if len(dispatcher.Names()) == 0 {
+		return nil, fmt.Errorf("no notifiers configured. Add a notifiers section to the config file (see 'bwh notify --help')")
+	}
+	return dispatcher, nil
+}
+
+func notifyFlag() cli.Flag {
+	return &cli.StringSliceFlag{
+		Name:  "notify",
+		Usage: "send alerts to this notifier from the config file (repeatable)",
+	}
+}
+
+// flagNotifier returns a notifier for the sinks named by --notify, or nil
+// when the flag is not set.
+func flagNotifier(cmd *cli.Command) (eventNotifier, error) {
+	names := cmd.StringSlice("notify")
+	if len(names) == 0 {
+		return nil, nil
+	}
+	manager, err := createConfigManager(cmd)
+	if err != nil {
+		return nil, err
+	}
+	dispatcher, err := createNotifier(manager)
+	if err != nil {
+		return nil, err
+	}
+	only, err := dispatcher.Only(names...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --notify: %w", err)
+	}
+	return only, nil
+}
+
+// sendNotification sends e through notifier, if there is one. Failures are
+// printed to stderr and do not fail the command.
+func sendNotification(ctx context.Context, notifier eventNotifier, e notify.Event) {
+	if notifier == nil {
+		return
+	}
+	if err := notifier.Send(ctx, e); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Failed to send notification: %v\n", err) //nolint:errcheck
+	}
+}
+
+type notifySender interface {
+	SendTo(ctx context.Context, name string, e notify.Event) error
+}
+
+func runNotifyTest(ctx context.Context, sender notifySender, names []string, now time.Time) error {
+	event := notify.Event{
+		Title:    "bwh test notification",
+		Message:  "This is a test notification sent by 'bwh notify test'.",
+		Severity: notify.Info,
+		Source:   "test",
+		Time:     now,
+	}
+
+	var failed []string
+	for _, name := range names {
+		if err := sender.SendTo(ctx, name, event); err != nil {
+			fmt.Printf("❌ %s: %v\n", name, err)
+			failed = append(failed, name)
+			continue
+		}
+		fmt.Printf("✅ %s: test notification sent\n", name)
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to notify %d of %d sink(s): %v", len(failed), len(names), failed)
+	}
+	return nil
+}
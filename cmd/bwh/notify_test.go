@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/strahe/bwh/internal/config"
+	"github.com/strahe/bwh/internal/notify"
+)
+
+type fakeNotifySender struct {
+	failing map[string]bool
+	sent    []notify.Event
+}
+
+func (f *fakeNotifySender) SendTo(_ context.Context, name string, e notify.Event) error {
+	if f.failing[name] {
+		return errors.New("connection refused")
+	}
+	f.sent = append(f.sent, e)
+	return nil
+}
+
+func TestRunNotifyTest(t *testing.T) {
+	sender := &fakeNotifySender{failing: map[string]bool{"mail": true}}
+	var err error
+	out := captureStdout(t, func() {
+		err = runNotifyTest(context.Background(), sender, []string{"hook", "mail"}, time.Unix(0, 0))
+	})
+	if err == nil || !strings.Contains(err.Error(), "failed to notify 1 of 2 sink(s): [mail]") {
+		t.Fatalf("runNotifyTest() error = %v", err)
+	}
+	if !strings.Contains(out, "✅ hook") || !strings.Contains(out, "❌ mail: connection refused") {
+		t.Fatalf("output = %s", out)
+	}
+	if len(sender.sent) != 1 || sender.sent[0].Source != "test" {
+		t.Fatalf("sent = %+v", sender.sent)
+	}
+}
+
+func TestCreateNotifier(t *testing.T) {
+	manager, err := config.NewManager(filepath.Join(t.TempDir(), "config.yaml"))
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	if _, err := createNotifier(manager); err == nil || !strings.Contains(err.Error(), "no notifiers configured") {
+		t.Fatalf("createNotifier() error = %v, want no notifiers", err)
+	}
+}
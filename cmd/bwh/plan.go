@@ -0,0 +1,373 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/strahe/bwh/pkg/client"
+	"github.com/urfave/cli/v3"
+)
+
+func manifestFileFlag() cli.Flag {
+	return &cli.StringFlag{
+		Name:     "file",
+		Aliases:  []string{"f"},
+		Usage:    "desired-state manifest (as written by 'bwh state export')",
+		Required: true,
+	}
+}
+
+var planCmd = &cli.Command{
+	Name:  "plan",
+	Usage: "show the changes needed to converge an instance to a desired-state manifest",
+	Flags: []cli.Flag{
+		manifestFileFlag(),
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		desired, err := loadStateManifest(cmd.String("file"))
+		if err != nil {
+			return err
+		}
+
+		bwhClient, resolvedName, err := createBWHClient(cmd)
+		if err != nil {
+			return err
+		}
+
+		return runPlan(ctx, bwhClient, resolvedName, desired)
+	},
+}
+
+var applyCmd = &cli.Command{
+	Name:  "apply",
+	Usage: "converge an instance to a desired-state manifest, confirming each step",
+	Flags: writeFlags(manifestFileFlag()),
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		desired, err := loadStateManifest(cmd.String("file"))
+		if err != nil {
+			return err
+		}
+
+		bwhClient, resolvedName, err := createBWHClient(cmd)
+		if err != nil {
+			return err
+		}
+
+		return runApply(ctx, bwhClient, resolvedName, desired, cmd.Bool("dry-run"), skipConfirm(cmd), promptConfirmation)
+	},
+}
+
+type stateApplyAPI interface {
+	stateReadAPI
+	SetHostname(context.Context, string) error
+	SetPTR(context.Context, string, string) error
+	UpdateSshKeys(context.Context, []string) error
+	SetNotificationPreferences(context.Context, map[string]bool) (*client.SetNotificationPreferencesResponse, error)
+	MountISO(context.Context, string) error
+	UnmountISO(context.Context) error
+	AddIPv6(context.Context) (*client.IPv6AddResponse, error)
+	AssignPrivateIP(context.Context, string) (*client.PrivateIPAssignResponse, error)
+}
+
+// planStep is a single write needed to converge live state to the manifest.
+type planStep struct {
+	symbol   string // "+" adds something, "~" changes it
+	resource string
+	detail   string
+	endpoint string
+	apply    func(context.Context, stateApplyAPI) error
+}
+
+// statePlan is the ordered list of steps plus anything apply will not touch.
+type statePlan struct {
+	steps    []planStep
+	warnings []string
+}
+
+// buildStatePlan diffs live state against the desired manifest. Steps are
+// ordered so that additive network changes come first, settings that depend
+// on them follow, and the ISO (which affects the next boot) is changed last.
+// Removing IPv6 subnets or private IPs is never planned; it is reported as a
+// warning instead.
+func buildStatePlan(live *liveState, desired *stateManifest) *statePlan {
+	plan := &statePlan{}
+	info := live.info
+	current := live.manifest("")
+
+	// 1. Private IPs
+	if desired.PrivateIPs != nil {
+		for _, ip := range desired.PrivateIPs {
+			if slices.Contains(current.PrivateIPs, ip) {
+				continue
+			}
+			if !info.PlanPrivateNetworkAvailable || !info.LocationPrivateNetworkAvailable {
+				plan.warnings = append(plan.warnings, fmt.Sprintf("private IP %s: private network is not available for this plan or location", ip))
+				continue
+			}
+			plan.steps = append(plan.steps, planStep{
+				symbol:   "+",
+				resource: "private_ip",
+				detail:   "assign " + ip,
+				endpoint: "privateIp/assign",
+				apply: func(ctx context.Context, api stateApplyAPI) error {
+					_, err := api.AssignPrivateIP(ctx, ip)
+					return err
+				},
+			})
+		}
+		for _, ip := range current.PrivateIPs {
+			if !slices.Contains(desired.PrivateIPs, ip) {
+				plan.warnings = append(plan.warnings, fmt.Sprintf("private IP %s is not in the manifest; remove it with 'bwh private-ip delete %s'", ip, ip))
+			}
+		}
+	}
+
+	// 2. IPv6 subnets
+	if desired.IPv6Subnets != nil {
+		have, want := *current.IPv6Subnets, *desired.IPv6Subnets
+		switch {
+		case want > have && !info.LocationIPv6Ready:
+			plan.warnings = append(plan.warnings, fmt.Sprintf("ipv6_subnets: IPv6 is not available at this location (%s)", info.NodeLocation))
+		case want > have:
+			if info.PlanMaxIPv6s > 0 && want > info.PlanMaxIPv6s {
+				plan.warnings = append(plan.warnings, fmt.Sprintf("ipv6_subnets: plan allows %d subnet(s), capping at the plan limit", info.PlanMaxIPv6s))
+				want = info.PlanMaxIPv6s
+			}
+			for n := have + 1; n <= want; n++ {
+				plan.steps = append(plan.steps, planStep{
+					symbol:   "+",
+					resource: "ipv6",
+					detail:   fmt.Sprintf("add /64 subnet (%d -> %d)", n-1, n),
+					endpoint: "ipv6/add",
+					apply: func(ctx context.Context, api stateApplyAPI) error {
+						_, err := api.AddIPv6(ctx)
+						return err
+					},
+				})
+			}
+		case want < have:
+			plan.warnings = append(plan.warnings, fmt.Sprintf("ipv6_subnets: %d assigned but %d desired; release extra subnets with 'bwh ipv6 delete'", have, want))
+		}
+	}
+
+	// 3. Hostname
+	if desired.Hostname != nil && *desired.Hostname != "" && *desired.Hostname != *current.Hostname {
+		hostname := *desired.Hostname
+		plan.steps = append(plan.steps, planStep{
+			symbol:   "~",
+			resource: "hostname",
+			detail:   fmt.Sprintf("%s -> %s", *current.Hostname, hostname),
+			endpoint: "setHostname",
+			apply: func(ctx context.Context, api stateApplyAPI) error {
+				return api.SetHostname(ctx, hostname)
+			},
+		})
+	}
+
+	// 4. PTR records
+	for _, ip := range slices.Sorted(maps.Keys(desired.PTR)) {
+		ptr := desired.PTR[ip]
+		if current.PTR[ip] == ptr {
+			continue
+		}
+		if !slices.Contains(info.IPAddresses, ip) {
+			plan.warnings = append(plan.warnings, fmt.Sprintf("ptr %s: IP address is not assigned to this instance", ip))
+			continue
+		}
+		if !info.RDNSAPIAvailable {
+			plan.warnings = append(plan.warnings, fmt.Sprintf("ptr %s: rDNS API is not available", ip))
+			continue
+		}
+		plan.steps = append(plan.steps, planStep{
+			symbol:   "~",
+			resource: "ptr",
+			detail:   fmt.Sprintf("%s: %s -> %s", ip, displayOrNone(current.PTR[ip]), ptr),
+			endpoint: "setPTR",
+			apply: func(ctx context.Context, api stateApplyAPI) error {
+				return api.SetPTR(ctx, ip, ptr)
+			},
+		})
+	}
+
+	// 5. VM SSH keys
+	if desired.SSHKeys != nil && !sameStringSlices(desired.SSHKeys, current.SSHKeys) {
+		keys := slices.Clone(desired.SSHKeys)
+		added, removed := diffStrings(current.SSHKeys, keys)
+		plan.steps = append(plan.steps, planStep{
+			symbol:   "~",
+			resource: "ssh_keys",
+			detail:   fmt.Sprintf("%d -> %d key(s) (+%d, -%d)", len(current.SSHKeys), len(keys), len(added), len(removed)),
+			endpoint: "updateSshKeys",
+			apply: func(ctx context.Context, api stateApplyAPI) error {
+				return api.UpdateSshKeys(ctx, keys)
+			},
+		})
+	}
+
+	// 6. Notification preferences
+	changes := map[string]bool{}
+	var details []string
+	for _, id := range slices.Sorted(maps.Keys(desired.Notifications)) {
+		enabled := desired.Notifications[id]
+		have, ok := current.Notifications[id]
+		if !ok {
+			plan.warnings = append(plan.warnings, fmt.Sprintf("notification %s: unknown preference ID", id))
+			continue
+		}
+		if have != enabled {
+			changes[id] = enabled
+			details = append(details, fmt.Sprintf("%s: %s -> %s", id, onOff(have), onOff(enabled)))
+		}
+	}
+	if len(changes) > 0 {
+		plan.steps = append(plan.steps, planStep{
+			symbol:   "~",
+			resource: "notifications",
+			detail:   strings.Join(details, ", "),
+			endpoint: "kiwivm/setNotificationPreferences",
+			apply: func(ctx context.Context, api stateApplyAPI) error {
+				_, err := api.SetNotificationPreferences(ctx, changes)
+				return err
+			},
+		})
+	}
+
+	// 7. ISO
+	if desired.ISO != nil && *desired.ISO != *current.ISO {
+		iso := *desired.ISO
+		switch {
+		case iso == "":
+			plan.steps = append(plan.steps, planStep{
+				symbol:   "~",
+				resource: "iso",
+				detail:   fmt.Sprintf("%s -> (none)", *current.ISO),
+				endpoint: "iso/unmount",
+				apply: func(ctx context.Context, api stateApplyAPI) error {
+					return api.UnmountISO(ctx)
+				},
+			})
+		case !slices.Contains(info.AvailableISOs, iso):
+			plan.warnings = append(plan.warnings, fmt.Sprintf("iso: image %q is not available for this instance", iso))
+		default:
+			plan.steps = append(plan.steps, planStep{
+				symbol:   "~",
+				resource: "iso",
+				detail:   fmt.Sprintf("%s -> %s", displayOrNone(*current.ISO), iso),
+				endpoint: "iso/mount",
+				apply: func(ctx context.Context, api stateApplyAPI) error {
+					return api.MountISO(ctx, iso)
+				},
+			})
+		}
+	}
+
+	return plan
+}
+
+func runPlan(ctx context.Context, api stateApplyAPI, resolvedName string, desired *stateManifest) error {
+	live, err := fetchLiveState(ctx, api)
+	if err != nil {
+		return err
+	}
+	plan := buildStatePlan(live, desired)
+	printStatePlan(resolvedName, desired, plan)
+	return nil
+}
+
+func runApply(ctx context.Context, api stateApplyAPI, resolvedName string, desired *stateManifest, dryRun, skipConfirm bool, confirm confirmationFunc) error {
+	live, err := fetchLiveState(ctx, api)
+	if err != nil {
+		return err
+	}
+	plan := buildStatePlan(live, desired)
+	printStatePlan(resolvedName, desired, plan)
+	if len(plan.steps) == 0 {
+		return nil
+	}
+
+	if dryRun {
+		for _, step := range plan.steps {
+			printDryRun(step.endpoint, resolvedName, fmt.Sprintf("%s: %s", step.resource, step.detail))
+		}
+		return nil
+	}
+
+	applied, skipped := 0, 0
+	for i, step := range plan.steps {
+		prompt := fmt.Sprintf("Step %d/%d: %s %s?", i+1, len(plan.steps), step.resource, step.detail)
+		confirmed, err := confirmWrite(prompt, skipConfirm, confirm)
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			skipped++
+			continue
+		}
+
+		if err := step.apply(ctx, api); err != nil {
+			fmt.Printf("❌ %s: %s failed\n", step.resource, step.detail)
+			return fmt.Errorf("apply stopped at step %d/%d (%d applied): failed to update %s: %w", i+1, len(plan.steps), applied, step.resource, err)
+		}
+		fmt.Printf("✅ %s: %s\n", step.resource, step.detail)
+		applied++
+	}
+
+	fmt.Printf("\nApply complete: %d applied, %d skipped.\n", applied, skipped)
+	return nil
+}
+
+func printStatePlan(resolvedName string, desired *stateManifest, plan *statePlan) {
+	fmt.Printf("📋 Plan for instance: %s\n", resolvedName)
+	if desired.Instance != "" && desired.Instance != resolvedName {
+		fmt.Printf("   (manifest was exported from instance '%s')\n", desired.Instance)
+	}
+
+	if len(plan.steps) == 0 {
+		fmt.Printf("\n✅ Live state matches the manifest (no change needed)\n")
+	} else {
+		fmt.Printf("\n")
+		for i, step := range plan.steps {
+			fmt.Printf("  %d. %s %-13s %s\n", i+1, step.symbol, step.resource, step.detail)
+		}
+		fmt.Printf("\nPlan: %d change(s).\n", len(plan.steps))
+	}
+
+	if len(plan.warnings) > 0 {
+		fmt.Printf("\n⚠️  Not changed by apply:\n")
+		for _, warning := range plan.warnings {
+			fmt.Printf("   • %s\n", warning)
+		}
+	}
+}
+
+// diffStrings returns the entries only in b (added) and only in a (removed).
+func diffStrings(a, b []string) (added, removed []string) {
+	for _, s := range b {
+		if !slices.Contains(a, s) {
+			added = append(added, s)
+		}
+	}
+	for _, s := range a {
+		if !slices.Contains(b, s) {
+			removed = append(removed, s)
+		}
+	}
+	return added, removed
+}
+
+func displayOrNone(value string) string {
+	if value == "" {
+		return "(none)"
+	}
+	return value
+}
+
+func onOff(enabled bool) string {
+	if enabled {
+		return "on"
+	}
+	return "off"
+}
@@ -12,24 +12,34 @@ import (
 
 func captureStdout(t *testing.T, fn func()) string {
 	t.Helper()
+	return captureFile(t, &os.Stdout, fn)
+}
+
+func captureStderr(t *testing.T, fn func()) string {
+	t.Helper()
+	return captureFile(t, &os.Stderr, fn)
+}
+
+func captureFile(t *testing.T, file **os.File, fn func()) string {
+	t.Helper()
 
-	old := os.Stdout
+	old := *file
 	r, w, err := os.Pipe()
 	if err != nil {
-		t.Fatalf("failed to create stdout pipe: %v", err)
+		t.Fatalf("failed to create pipe: %v", err)
 	}
-	os.Stdout = w
+	*file = w
 
 	fn()
 
 	if err := w.Close(); err != nil {
-		t.Fatalf("failed to close stdout writer: %v", err)
+		t.Fatalf("failed to close pipe writer: %v", err)
 	}
-	os.Stdout = old
+	*file = old
 
 	var buf bytes.Buffer
 	if _, err := io.Copy(&buf, r); err != nil {
-		t.Fatalf("failed to read stdout: %v", err)
+		t.Fatalf("failed to read pipe: %v", err)
 	}
 	return buf.String()
 }
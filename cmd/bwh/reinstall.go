@@ -11,8 +11,9 @@ import (
 )
 
 var reinstallCmd = &cli.Command{
-	Name:  "reinstall",
-	Usage: "reinstall the VPS operating system (WARNING: destroys all data)",
+	Name:          "reinstall",
+	Usage:         "reinstall the VPS operating system (WARNING: destroys all data)",
+	ShellComplete: completeFlag("os", osCompletions),
 	Flags: []cli.Flag{
 		&cli.StringFlag{
 			Name:  "os",
@@ -22,9 +23,15 @@ var reinstallCmd = &cli.Command{
 			Name:  "list",
 			Usage: "list available operating system templates",
 		},
+		&cli.BoolFlag{
+			Name:  "safe",
+			Usage: "create and pin a snapshot first, then restore hostname, PTR records and VM SSH keys after reinstall",
+		},
 		forceFlag(),
 		yesFlag(),
 		dryRunFlag(),
+		waitFlag(),
+		waitTimeoutFlag(),
 	},
 	Action: func(ctx context.Context, cmd *cli.Command) error {
 		osTemplate := cmd.String("os")
@@ -35,7 +42,12 @@ var reinstallCmd = &cli.Command{
 			return err
 		}
 
-		return runReinstall(ctx, bwhClient, resolvedName, osTemplate, listOnly, cmd.Bool("dry-run"), skipConfirmOrForce(cmd), confirmReinstall)
+		if cmd.Bool("safe") && !listOnly {
+			wait := unlockWaitWithTimeout(bwhClient, resolvedName, cmd.Duration("wait-timeout"))
+			return runReinstallSafe(ctx, bwhClient, resolvedName, osTemplate, cmd.Bool("dry-run"), skipConfirmOrForce(cmd), confirmReinstall, wait)
+		}
+
+		return runReinstall(ctx, bwhClient, resolvedName, osTemplate, listOnly, cmd.Bool("dry-run"), skipConfirmOrForce(cmd), confirmReinstall, newUnlockWait(cmd, bwhClient, resolvedName))
 	},
 }
 
@@ -46,7 +58,7 @@ type reinstallAPI interface {
 
 type reinstallConfirmationFunc func(instanceName, currentOS, targetOS string) (bool, error)
 
-func runReinstall(ctx context.Context, api reinstallAPI, resolvedName, osTemplate string, listOnly, dryRun, skipConfirm bool, confirm reinstallConfirmationFunc) error {
+func runReinstall(ctx context.Context, api reinstallAPI, resolvedName, osTemplate string, listOnly, dryRun, skipConfirm bool, confirm reinstallConfirmationFunc, wait unlockWaitFunc) error {
 	osInfo, err := api.GetAvailableOS(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to get available OS templates: %w", err)
@@ -105,7 +117,7 @@ func runReinstall(ctx context.Context, api reinstallAPI, resolvedName, osTemplat
 	fmt.Printf("📋 Your VPS is being reinstalled with %s\n", osTemplate)
 	fmt.Printf("⚠️  Note: The process may take 5-15 minutes to complete\n")
 
-	return nil
+	return wait.run(ctx)
 }
 
 func displayAvailableOS(osInfo *client.AvailableOSResponse, instanceName string) {
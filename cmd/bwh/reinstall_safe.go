@@ -0,0 +1,303 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/strahe/bwh/pkg/client"
+)
+
+type safeReinstallAPI interface {
+	GetAvailableOS(context.Context) (*client.AvailableOSResponse, error)
+	GetServiceInfo(context.Context) (*client.ServiceInfo, error)
+	GetSshKeys(context.Context) (*client.SshKeysResponse, error)
+	CreateSnapshot(context.Context, string) (*client.CreateSnapshotResponse, error)
+	ListSnapshots(context.Context) (*client.SnapshotListResponse, error)
+	ToggleSnapshotSticky(context.Context, string, bool) error
+	ReinstallOSWithResult(context.Context, string) (*client.ReinstallOSResponse, error)
+	SetHostname(context.Context, string) error
+	SetPTR(context.Context, string, string) error
+	UpdateSshKeys(context.Context, []string) error
+}
+
+// reinstallSettings holds the settings recorded before a safe reinstall.
+type reinstallSettings struct {
+	hostname string
+	ptr      map[string]string
+	rdns     bool
+	sshKeys  []string
+}
+
+// restoreResult describes how one recorded setting was reapplied.
+type restoreResult struct {
+	label  string
+	value  string
+	status string
+	err    error
+}
+
+// runReinstallSafe snapshots and pins the VPS, records its hostname, PTR
+// records and VM SSH keys, reinstalls, waits for the VE to unlock and then
+// reapplies the recorded settings. The wait function is required.
+func runReinstallSafe(ctx context.Context, api safeReinstallAPI, resolvedName, osTemplate string, dryRun, skipConfirm bool, confirm reinstallConfirmationFunc, wait unlockWaitFunc) error {
+	if wait == nil {
+		return fmt.Errorf("safe reinstall requires an unlock waiter")
+	}
+
+	osInfo, err := api.GetAvailableOS(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get available OS templates: %w", err)
+	}
+	if osTemplate == "" {
+		return fmt.Errorf("--safe requires --os; use --list to see available templates")
+	}
+	if !isValidOSTemplate(osTemplate, osInfo.Templates) {
+		return fmt.Errorf("invalid OS template: %s", osTemplate)
+	}
+
+	settings, err := recordReinstallSettings(ctx, api)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Instance: %s\n", resolvedName)
+	fmt.Printf("Current OS: %s\n", osInfo.Installed)
+	fmt.Printf("Target OS:  %s\n", osTemplate)
+	fmt.Printf("\n📋 Settings to restore after reinstall:\n")
+	printReinstallSettings(settings)
+	fmt.Printf("\n")
+
+	if dryRun {
+		printDryRun("reinstallOS (safe)", resolvedName,
+			"snapshot/create + snapshot/toggleSticky",
+			fmt.Sprintf("os: %s", osTemplate),
+			"restore hostname, PTR records and VM SSH keys",
+		)
+		return nil
+	}
+
+	if !skipConfirm {
+		confirmed, err := confirm(resolvedName, osInfo.Installed, osTemplate)
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			printOperationCancelled()
+			return nil
+		}
+	}
+
+	fmt.Printf("📸 Step 1/4: Creating safety snapshot for instance: %s\n", resolvedName)
+	snapshotName, err := createPinnedSnapshot(ctx, api, osInfo.Installed, osTemplate, wait)
+	if err != nil {
+		return fmt.Errorf("%w (OS was not reinstalled)", err)
+	}
+	fmt.Printf("✅ Snapshot '%s' created and pinned\n", snapshotName)
+
+	fmt.Printf("\n🔄 Step 2/4: Reinstalling %s on instance: %s\n", osTemplate, resolvedName)
+	result, err := api.ReinstallOSWithResult(ctx, osTemplate)
+	if err != nil {
+		return fmt.Errorf("failed to reinstall OS: %w (snapshot '%s' is pinned)", err, snapshotName)
+	}
+
+	fmt.Printf("\n⏳ Step 3/4: Waiting for reinstall to finish\n")
+	if err := wait(ctx); err != nil {
+		printReinstallReport(resolvedName, osInfo.Installed, osTemplate, snapshotName, result, nil)
+		return err
+	}
+
+	fmt.Printf("\n🔧 Step 4/4: Restoring recorded settings\n")
+	restored := restoreReinstallSettings(ctx, api, settings)
+
+	printReinstallReport(resolvedName, osInfo.Installed, osTemplate, snapshotName, result, restored)
+
+	failed := 0
+	for _, r := range restored {
+		if r.err != nil {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("OS reinstalled, but %d setting(s) could not be restored", failed)
+	}
+	return nil
+}
+
+func recordReinstallSettings(ctx context.Context, api safeReinstallAPI) (*reinstallSettings, error) {
+	info, err := api.GetServiceInfo(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get service info: %w", err)
+	}
+	keys, err := api.GetSshKeys(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get SSH keys: %w", err)
+	}
+
+	settings := &reinstallSettings{
+		hostname: info.Hostname,
+		ptr:      map[string]string{},
+		rdns:     info.RDNSAPIAvailable,
+		sshKeys:  keys.GetSshKeysVeidSlice(),
+	}
+	for ip, ptr := range info.PTR {
+		if ptr != "" {
+			settings.ptr[ip] = ptr
+		}
+	}
+	return settings, nil
+}
+
+func printReinstallSettings(settings *reinstallSettings) {
+	fmt.Printf("   Hostname     : %s\n", settings.hostname)
+	for _, ip := range slices.Sorted(maps.Keys(settings.ptr)) {
+		fmt.Printf("   PTR %-9s: %s\n", ip, settings.ptr[ip])
+	}
+	fmt.Printf("   VM SSH keys  : %d\n", len(settings.sshKeys))
+}
+
+// createPinnedSnapshot creates a snapshot, waits for it to finish and pins it
+// so it is never purged automatically.
+func createPinnedSnapshot(ctx context.Context, api safeReinstallAPI, currentOS, targetOS string, wait unlockWaitFunc) (string, error) {
+	before, err := api.ListSnapshots(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to list snapshots: %w", err)
+	}
+	existing := make(map[string]bool, len(before.Snapshots))
+	for _, s := range before.Snapshots {
+		existing[s.FileName] = true
+	}
+
+	description := fmt.Sprintf("Before reinstall %s -> %s on %s", currentOS, targetOS, time.Now().Format("2006-01-02 15:04:05"))
+	if _, err := api.CreateSnapshot(ctx, description); err != nil {
+		return "", fmt.Errorf("failed to create snapshot: %w", err)
+	}
+	if err := wait(ctx); err != nil {
+		return "", err
+	}
+
+	after, err := api.ListSnapshots(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to list snapshots: %w", err)
+	}
+	var snapshot *client.SnapshotInfo
+	for i := range after.Snapshots {
+		if existing[after.Snapshots[i].FileName] {
+			continue
+		}
+		if snapshot == nil || after.Snapshots[i].Description == description {
+			snapshot = &after.Snapshots[i]
+		}
+	}
+	if snapshot == nil {
+		return "", fmt.Errorf("safety snapshot was not found after creation")
+	}
+
+	if !snapshot.Sticky {
+		if err := api.ToggleSnapshotSticky(ctx, snapshot.FileName, true); err != nil {
+			return "", fmt.Errorf("failed to pin snapshot '%s': %w", snapshot.FileName, err)
+		}
+	}
+	return snapshot.FileName, nil
+}
+
+func restoreReinstallSettings(ctx context.Context, api safeReinstallAPI, settings *reinstallSettings) []restoreResult {
+	var results []restoreResult
+
+	info, err := api.GetServiceInfo(ctx)
+	if err != nil {
+		return []restoreResult{{label: "Settings", status: "failed", err: fmt.Errorf("failed to get service info: %w", err)}}
+	}
+
+	if settings.hostname != "" {
+		r := restoreResult{label: "Hostname", value: settings.hostname, status: "unchanged"}
+		if info.Hostname != settings.hostname {
+			if r.err = api.SetHostname(ctx, settings.hostname); r.err != nil {
+				r.status = "failed"
+			} else {
+				r.status = "restored"
+			}
+		}
+		results = append(results, r)
+	}
+
+	for _, ip := range slices.Sorted(maps.Keys(settings.ptr)) {
+		ptr := settings.ptr[ip]
+		r := restoreResult{label: "PTR " + ip, value: ptr, status: "unchanged"}
+		switch {
+		case !slices.Contains(info.IPAddresses, ip):
+			r.status = "failed"
+			r.err = fmt.Errorf("IP address is no longer assigned")
+		case !info.RDNSAPIAvailable:
+			r.status = "failed"
+			r.err = fmt.Errorf("rDNS API is not available")
+		case info.PTR[ip] != ptr:
+			if r.err = api.SetPTR(ctx, ip, ptr); r.err != nil {
+				r.status = "failed"
+			} else {
+				r.status = "restored"
+			}
+		}
+		results = append(results, r)
+	}
+
+	if len(settings.sshKeys) > 0 {
+		r := restoreResult{label: "VM SSH keys", value: fmt.Sprintf("%d key(s)", len(settings.sshKeys)), status: "unchanged"}
+		keys, err := api.GetSshKeys(ctx)
+		switch {
+		case err != nil:
+			r.status = "failed"
+			r.err = fmt.Errorf("failed to get SSH keys: %w", err)
+		case !sameStringSlices(keys.GetSshKeysVeidSlice(), settings.sshKeys):
+			if r.err = api.UpdateSshKeys(ctx, settings.sshKeys); r.err != nil {
+				r.status = "failed"
+			} else {
+				r.status = "restored"
+			}
+		}
+		results = append(results, r)
+	}
+
+	return results
+}
+
+func printReinstallReport(resolvedName, currentOS, targetOS, snapshotName string, result *client.ReinstallOSResponse, restored []restoreResult) {
+	fmt.Printf("\n📋 SAFE REINSTALL REPORT\n")
+	fmt.Printf("═══════════════════════════════════════════════════════════════════════════════\n")
+	fmt.Printf("   Instance     : %s\n", resolvedName)
+	fmt.Printf("   OS           : %s → %s\n", currentOS, targetOS)
+	fmt.Printf("   Snapshot     : %s (pinned)\n", snapshotName)
+
+	for _, r := range restored {
+		icon := "✅"
+		if r.err != nil {
+			icon = "❌"
+		}
+		line := fmt.Sprintf("   %-13s: %s %s", r.label, icon, r.status)
+		if r.value != "" {
+			line += fmt.Sprintf(" (%s)", r.value)
+		}
+		if r.err != nil {
+			line += fmt.Sprintf(": %v", r.err)
+		}
+		fmt.Printf("%s\n", line)
+	}
+	if restored == nil {
+		fmt.Printf("   Settings     : ⚠️  not restored (reinstall did not finish)\n")
+	}
+
+	if result != nil {
+		if result.RootPassword != "" {
+			fmt.Printf("   Root password: %s\n", result.RootPassword)
+		}
+		if result.SSHPort.Value > 0 {
+			fmt.Printf("   SSH port     : %d\n", result.SSHPort.Value)
+		}
+		if result.NotificationEmail != "" {
+			fmt.Printf("   Notification : %s\n", result.NotificationEmail)
+		}
+	}
+	fmt.Printf("\n💡 Restore the previous system with: bwh snapshot restore %s\n", snapshotName)
+}
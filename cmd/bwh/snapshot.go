@@ -39,7 +39,7 @@ var snapshotCmd = &cli.Command{
 var snapshotCreateCmd = &cli.Command{
 	Name:  "create",
 	Usage: "create a snapshot",
-	Flags: writeFlags(
+	Flags: waitWriteFlags(
 		&cli.StringFlag{
 			Name:    "description",
 			Aliases: []string{"d"},
@@ -57,7 +57,7 @@ var snapshotCreateCmd = &cli.Command{
 			description = fmt.Sprintf("Created via bwh CLI on %s", time.Now().Format("2006-01-02 15:04:05"))
 		}
 
-		return runSnapshotCreate(ctx, bwhClient, resolvedName, description, cmd.Bool("dry-run"), skipConfirm(cmd), promptConfirmation)
+		return runSnapshotCreate(ctx, bwhClient, resolvedName, description, cmd.Bool("dry-run"), skipConfirm(cmd), promptConfirmation, newUnlockWait(cmd, bwhClient, resolvedName))
 	},
 }
 
@@ -99,10 +99,11 @@ var snapshotListCmd = &cli.Command{
 }
 
 var snapshotDeleteCmd = &cli.Command{
-	Name:      "delete",
-	Usage:     "delete a snapshot",
-	ArgsUsage: "<filename>",
-	Flags:     writeFlags(),
+	Name:          "delete",
+	Usage:         "delete a snapshot",
+	ArgsUsage:     "<filename>",
+	ShellComplete: completeArgs(fromAPI(snapshotCompletions)),
+	Flags:         writeFlags(),
 	Action: func(ctx context.Context, cmd *cli.Command) error {
 		if cmd.Args().Len() != 1 {
 			return fmt.Errorf("snapshot filename is required")
@@ -119,10 +120,11 @@ var snapshotDeleteCmd = &cli.Command{
 }
 
 var snapshotRestoreCmd = &cli.Command{
-	Name:      "restore",
-	Usage:     "restore a snapshot (WARNING: overwrites all data)",
-	ArgsUsage: "<filename>",
-	Flags:     writeFlags(),
+	Name:          "restore",
+	Usage:         "restore a snapshot (WARNING: overwrites all data)",
+	ArgsUsage:     "<filename>",
+	ShellComplete: completeArgs(fromAPI(snapshotCompletions)),
+	Flags:         waitWriteFlags(),
 	Action: func(ctx context.Context, cmd *cli.Command) error {
 		if cmd.Args().Len() != 1 {
 			return fmt.Errorf("snapshot filename is required")
@@ -134,15 +136,16 @@ var snapshotRestoreCmd = &cli.Command{
 			return err
 		}
 
-		return runSnapshotRestore(ctx, bwhClient, resolvedName, fileName, cmd.Bool("dry-run"), skipConfirm(cmd), promptConfirmation)
+		return runSnapshotRestore(ctx, bwhClient, resolvedName, fileName, cmd.Bool("dry-run"), skipConfirm(cmd), promptConfirmation, newUnlockWait(cmd, bwhClient, resolvedName))
 	},
 }
 
 var snapshotPinCmd = &cli.Command{
-	Name:      "pin",
-	Usage:     "pin a snapshot (make it sticky - never purged)",
-	ArgsUsage: "<filename_or_index>",
-	Flags:     writeFlags(),
+	Name:          "pin",
+	Usage:         "pin a snapshot (make it sticky - never purged)",
+	ArgsUsage:     "<filename_or_index>",
+	ShellComplete: completeArgs(snapshotIndexes),
+	Flags:         writeFlags(),
 	Action: func(ctx context.Context, cmd *cli.Command) error {
 		if cmd.Args().Len() != 1 {
 			return fmt.Errorf("snapshot filename or index is required")
@@ -152,10 +155,11 @@ var snapshotPinCmd = &cli.Command{
 }
 
 var snapshotUnpinCmd = &cli.Command{
-	Name:      "unpin",
-	Usage:     "unpin a snapshot (remove sticky - can be purged)",
-	ArgsUsage: "<filename_or_index>",
-	Flags:     writeFlags(),
+	Name:          "unpin",
+	Usage:         "unpin a snapshot (remove sticky - can be purged)",
+	ArgsUsage:     "<filename_or_index>",
+	ShellComplete: completeArgs(snapshotIndexes),
+	Flags:         writeFlags(),
 	Action: func(ctx context.Context, cmd *cli.Command) error {
 		if cmd.Args().Len() != 1 {
 			return fmt.Errorf("snapshot filename or index is required")
@@ -165,10 +169,11 @@ var snapshotUnpinCmd = &cli.Command{
 }
 
 var snapshotExportCmd = &cli.Command{
-	Name:      "export",
-	Usage:     "export a snapshot for transfer to another instance",
-	ArgsUsage: "<filename>",
-	Flags:     writeFlags(),
+	Name:          "export",
+	Usage:         "export a snapshot for transfer to another instance",
+	ArgsUsage:     "<filename>",
+	ShellComplete: completeArgs(fromAPI(snapshotCompletions)),
+	Flags:         writeFlags(),
 	Action: func(ctx context.Context, cmd *cli.Command) error {
 		if cmd.Args().Len() != 1 {
 			return fmt.Errorf("snapshot filename is required")
@@ -188,7 +193,7 @@ var snapshotImportCmd = &cli.Command{
 	Name:      "import",
 	Usage:     "import a snapshot from another instance",
 	ArgsUsage: "<source_veid> <source_token>",
-	Flags:     writeFlags(),
+	Flags:     waitWriteFlags(),
 	Action: func(ctx context.Context, cmd *cli.Command) error {
 		if cmd.Args().Len() != 2 {
 			return fmt.Errorf("source VEID and source token are required")
@@ -207,14 +212,15 @@ var snapshotImportCmd = &cli.Command{
 			return err
 		}
 
-		return runSnapshotImport(ctx, bwhClient, resolvedName, sourceVeid, sourceToken, cmd.Bool("dry-run"), skipConfirm(cmd), promptConfirmation)
+		return runSnapshotImport(ctx, bwhClient, resolvedName, sourceVeid, sourceToken, cmd.Bool("dry-run"), skipConfirm(cmd), promptConfirmation, newUnlockWait(cmd, bwhClient, resolvedName))
 	},
 }
 
 var snapshotDownloadCmd = &cli.Command{
-	Name:      "download",
-	Usage:     "download a snapshot file",
-	ArgsUsage: "<filename_or_index> [output_path]",
+	Name:          "download",
+	Usage:         "download a snapshot file",
+	ArgsUsage:     "<filename_or_index> [output_path]",
+	ShellComplete: completeArgs(snapshotIndexes),
 	Flags: []cli.Flag{
 		&cli.StringFlag{
 			Name:    "output",
@@ -436,7 +442,7 @@ type snapshotImportAPI interface {
 	ImportSnapshot(context.Context, string, string) error
 }
 
-func runSnapshotCreate(ctx context.Context, api snapshotCreateAPI, resolvedName, description string, dryRun, skipConfirm bool, confirm confirmationFunc) error {
+func runSnapshotCreate(ctx context.Context, api snapshotCreateAPI, resolvedName, description string, dryRun, skipConfirm bool, confirm confirmationFunc, wait unlockWaitFunc) error {
 	if dryRun {
 		printDryRun("snapshot/create", resolvedName, fmt.Sprintf("description: %s", description))
 		return nil
@@ -468,7 +474,7 @@ func runSnapshotCreate(ctx context.Context, api snapshotCreateAPI, resolvedName,
 		fmt.Printf("📧 Notification will be sent to: %s\n", resp.NotificationEmail)
 	}
 
-	return nil
+	return wait.run(ctx)
 }
 
 func runSnapshotExport(ctx context.Context, api snapshotExportAPI, resolvedName, sourceVeid, fileName string, dryRun, skipConfirm bool, confirm confirmationFunc) error {
@@ -501,7 +507,7 @@ func runSnapshotExport(ctx context.Context, api snapshotExportAPI, resolvedName,
 	return nil
 }
 
-func runSnapshotImport(ctx context.Context, api snapshotImportAPI, resolvedName, sourceVeid, sourceToken string, dryRun, skipConfirm bool, confirm confirmationFunc) error {
+func runSnapshotImport(ctx context.Context, api snapshotImportAPI, resolvedName, sourceVeid, sourceToken string, dryRun, skipConfirm bool, confirm confirmationFunc, wait unlockWaitFunc) error {
 	sourceVeid = strings.TrimSpace(sourceVeid)
 	sourceToken = strings.TrimSpace(sourceToken)
 	if sourceVeid == "" {
@@ -529,7 +535,7 @@ func runSnapshotImport(ctx context.Context, api snapshotImportAPI, resolvedName,
 	}
 
 	fmt.Printf("✅ Snapshot import initiated successfully\n")
-	return nil
+	return wait.run(ctx)
 }
 
 func findSnapshotByName(snapshots []client.SnapshotInfo, fileName string) (*client.SnapshotInfo, bool) {
@@ -589,7 +595,7 @@ func runSnapshotDelete(ctx context.Context, api snapshotWriteAPI, resolvedName,
 	return nil
 }
 
-func runSnapshotRestore(ctx context.Context, api snapshotWriteAPI, resolvedName, fileName string, dryRun, skipConfirm bool, confirm confirmationFunc) error {
+func runSnapshotRestore(ctx context.Context, api snapshotWriteAPI, resolvedName, fileName string, dryRun, skipConfirm bool, confirm confirmationFunc, wait unlockWaitFunc) error {
 	resp, err := api.ListSnapshots(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to list snapshots: %w", err)
@@ -620,7 +626,7 @@ func runSnapshotRestore(ctx context.Context, api snapshotWriteAPI, resolvedName,
 		return fmt.Errorf("failed to restore snapshot: %w", err)
 	}
 	fmt.Printf("✅ Snapshot '%s' restoration initiated\n", fileName)
-	return nil
+	return wait.run(ctx)
 }
 
 // downloadFileWithFallback attempts to download using HTTPS first, then falls back to HTTP
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/strahe/bwh/pkg/client"
+	"github.com/urfave/cli/v3"
+	"gopkg.in/yaml.v3"
+)
+
+// stateManifestVersion is the schema version written to exported manifests.
+const stateManifestVersion = 1
+
+// stateManifest describes the configurable state of an instance.
+//
+// Pointer fields distinguish "not set" from empty values. Exported manifests
+// always write every field, using empty lists and maps for "none".
+type stateManifest struct {
+	Version       int               `yaml:"version"`
+	Instance      string            `yaml:"instance,omitempty"`
+	ExportedAt    string            `yaml:"exported_at,omitempty"`
+	Hostname      *string           `yaml:"hostname,omitempty"`
+	PTR           map[string]string `yaml:"ptr"`
+	SSHKeys       []string          `yaml:"ssh_keys"`
+	Notifications map[string]bool   `yaml:"notifications"`
+	ISO           *string           `yaml:"iso,omitempty"`
+	IPv6Subnets   *int              `yaml:"ipv6_subnets,omitempty"`
+	PrivateIPs    []string          `yaml:"private_ips"`
+}
+
+var stateCmd = &cli.Command{
+	Name:  "state",
+	Usage: "export the configurable state of an instance as a YAML manifest",
+	Commands: []*cli.Command{
+		stateExportCmd,
+	},
+}
+
+var stateExportCmd = &cli.Command{
+	Name:  "export",
+	Usage: "export hostname, PTR records, VM SSH keys, notifications, ISO, IPv6 and private IPs",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:    "output",
+			Aliases: []string{"o"},
+			Usage:   "write the manifest to a file instead of stdout",
+		},
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		bwhClient, resolvedName, err := createBWHClient(cmd)
+		if err != nil {
+			return err
+		}
+
+		manifest, err := captureState(ctx, bwhClient, resolvedName)
+		if err != nil {
+			return err
+		}
+		manifest.ExportedAt = time.Now().UTC().Format(time.RFC3339)
+
+		data, err := marshalStateManifest(manifest)
+		if err != nil {
+			return err
+		}
+
+		output := cmd.String("output")
+		if output == "" {
+			fmt.Print(string(data))
+			return nil
+		}
+		if err := os.WriteFile(output, data, 0o644); err != nil {
+			return fmt.Errorf("failed to write manifest: %w", err)
+		}
+		fmt.Printf("✅ State of instance '%s' exported to %s\n", resolvedName, output)
+		return nil
+	},
+}
+
+type stateReadAPI interface {
+	GetServiceInfo(context.Context) (*client.ServiceInfo, error)
+	GetSshKeys(context.Context) (*client.SshKeysResponse, error)
+	GetNotificationPreferences(context.Context) (*client.NotificationPreferencesResponse, error)
+}
+
+// liveState holds the API responses a manifest is built from.
+type liveState struct {
+	info  *client.ServiceInfo
+	keys  *client.SshKeysResponse
+	prefs *client.NotificationPreferencesResponse
+}
+
+func fetchLiveState(ctx context.Context, api stateReadAPI) (*liveState, error) {
+	info, err := api.GetServiceInfo(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get service info: %w", err)
+	}
+	keys, err := api.GetSshKeys(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get SSH keys: %w", err)
+	}
+	prefs, err := api.GetNotificationPreferences(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notification preferences: %w", err)
+	}
+	return &liveState{info: info, keys: keys, prefs: prefs}, nil
+}
+
+// captureState reads the live state of an instance into a manifest.
+func captureState(ctx context.Context, api stateReadAPI, resolvedName string) (*stateManifest, error) {
+	live, err := fetchLiveState(ctx, api)
+	if err != nil {
+		return nil, err
+	}
+	return live.manifest(resolvedName), nil
+}
+
+func (l *liveState) manifest(resolvedName string) *stateManifest {
+	hostname := l.info.Hostname
+	iso := l.info.ISO1
+	ipv6 := countIPv6Subnets(l.info.IPAddresses)
+
+	manifest := &stateManifest{
+		Version:       stateManifestVersion,
+		Instance:      resolvedName,
+		Hostname:      &hostname,
+		PTR:           map[string]string{},
+		SSHKeys:       l.keys.GetSshKeysVeidSlice(),
+		Notifications: map[string]bool{},
+		ISO:           &iso,
+		IPv6Subnets:   &ipv6,
+		PrivateIPs:    append([]string{}, l.info.PrivateIPAddresses...),
+	}
+	for ip, ptr := range l.info.PTR {
+		if ptr != "" {
+			manifest.PTR[ip] = ptr
+		}
+	}
+	if manifest.SSHKeys == nil {
+		manifest.SSHKeys = []string{}
+	}
+	for _, category := range l.prefs.EmailPreferences {
+		for id, pref := range category {
+			manifest.Notifications[id] = pref.IsEnabled == 1
+		}
+	}
+	sort.Strings(manifest.PrivateIPs)
+
+	return manifest
+}
+
+func marshalStateManifest(manifest *stateManifest) ([]byte, error) {
+	data, err := yaml.Marshal(manifest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	header := "# bwh state manifest\n"
+	if manifest.Instance != "" {
+		header = fmt.Sprintf("# bwh state manifest for instance %s\n", manifest.Instance)
+	}
+	return append([]byte(header), data...), nil
+}
+
+// loadStateManifest reads a desired-state manifest from path.
+func loadStateManifest(path string) (*stateManifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	return parseStateManifest(data)
+}
+
+func parseStateManifest(data []byte) (*stateManifest, error) {
+	var manifest stateManifest
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&manifest); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("manifest is empty")
+		}
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	if manifest.Version == 0 {
+		manifest.Version = stateManifestVersion
+	}
+	if manifest.Version != stateManifestVersion {
+		return nil, fmt.Errorf("unsupported manifest version %d (expected %d)", manifest.Version, stateManifestVersion)
+	}
+	if manifest.IPv6Subnets != nil && *manifest.IPv6Subnets < 0 {
+		return nil, fmt.Errorf("ipv6_subnets cannot be negative")
+	}
+	for _, ip := range manifest.PrivateIPs {
+		if parsed := net.ParseIP(ip); parsed == nil || parsed.To4() == nil {
+			return nil, fmt.Errorf("invalid private IPv4 address in manifest: %s", ip)
+		}
+	}
+	for ip := range manifest.PTR {
+		if net.ParseIP(ip) == nil {
+			return nil, fmt.Errorf("invalid IP address in ptr: %s", ip)
+		}
+	}
+	for _, key := range manifest.SSHKeys {
+		if !isValidSshKey(key) {
+			return nil, fmt.Errorf("invalid SSH key in manifest: %s", maskSSHKey(key))
+		}
+	}
+	return &manifest, nil
+}
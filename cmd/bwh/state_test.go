@@ -0,0 +1,298 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/strahe/bwh/pkg/client"
+	"gopkg.in/yaml.v3"
+)
+
+type fakeStateAPI struct {
+	service *client.ServiceInfo
+	keys    string
+	prefs   map[string]map[string]client.NotificationPreference
+}
+
+func (f *fakeStateAPI) GetServiceInfo(context.Context) (*client.ServiceInfo, error) {
+	info := *f.service
+	return &info, nil
+}
+
+func (f *fakeStateAPI) GetSshKeys(context.Context) (*client.SshKeysResponse, error) {
+	return &client.SshKeysResponse{SshKeysVeid: f.keys}, nil
+}
+
+func (f *fakeStateAPI) GetNotificationPreferences(context.Context) (*client.NotificationPreferencesResponse, error) {
+	return &client.NotificationPreferencesResponse{EmailPreferences: f.prefs}, nil
+}
+
+func newFakeStateAPI() *fakeStateAPI {
+	return &fakeStateAPI{
+		service: &client.ServiceInfo{
+			Hostname:           "web.example.com",
+			IPAddresses:        []string{"1.2.3.4", "2001:db8:1234:5678::"},
+			PrivateIPAddresses: []string{"10.0.0.9", "10.0.0.2"},
+			PTR:                map[string]string{"1.2.3.4": "web.example.com", "5.6.7.8": ""},
+			ISO1:               "ubuntu.iso",
+			RDNSAPIAvailable:   true,
+			LocationIPv6Ready:  true,
+		},
+		keys: "ssh-ed25519 AAAA one",
+		prefs: map[string]map[string]client.NotificationPreference{
+			"service": {
+				"maintenance": {IsEnabled: 1},
+				"billing":     {IsEnabled: 0},
+			},
+		},
+	}
+}
+
+func TestCaptureState(t *testing.T) {
+	manifest, err := captureState(context.Background(), newFakeStateAPI(), "web")
+	if err != nil {
+		t.Fatalf("captureState() error = %v", err)
+	}
+
+	if *manifest.Hostname != "web.example.com" || *manifest.ISO != "ubuntu.iso" || *manifest.IPv6Subnets != 1 {
+		t.Fatalf("manifest = %+v", manifest)
+	}
+	if len(manifest.PTR) != 1 || manifest.PTR["1.2.3.4"] != "web.example.com" {
+		t.Fatalf("PTR = %v, want only non-empty records", manifest.PTR)
+	}
+	if manifest.PrivateIPs[0] != "10.0.0.2" {
+		t.Fatalf("PrivateIPs = %v, want sorted", manifest.PrivateIPs)
+	}
+	if !manifest.Notifications["maintenance"] || manifest.Notifications["billing"] {
+		t.Fatalf("Notifications = %v", manifest.Notifications)
+	}
+
+	data, err := marshalStateManifest(manifest)
+	if err != nil {
+		t.Fatalf("marshalStateManifest() error = %v", err)
+	}
+	out := string(data)
+	if !strings.HasPrefix(out, "# bwh state manifest for instance web\n") {
+		t.Fatalf("manifest missing header:\n%s", out)
+	}
+
+	var decoded map[string]any
+	if err := yaml.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("yaml.Unmarshal() error = %v", err)
+	}
+	for _, key := range []string{"version", "hostname", "ptr", "ssh_keys", "notifications", "iso", "ipv6_subnets", "private_ips"} {
+		if _, ok := decoded[key]; !ok {
+			t.Fatalf("manifest missing key %q:\n%s", key, out)
+		}
+	}
+}
+
+type fakeStateApplyAPI struct {
+	*fakeStateAPI
+	calls []string
+}
+
+func (f *fakeStateApplyAPI) SetHostname(_ context.Context, hostname string) error {
+	f.calls = append(f.calls, "hostname "+hostname)
+	return nil
+}
+
+func (f *fakeStateApplyAPI) SetPTR(_ context.Context, ip, ptr string) error {
+	f.calls = append(f.calls, "ptr "+ip+" "+ptr)
+	return nil
+}
+
+func (f *fakeStateApplyAPI) UpdateSshKeys(_ context.Context, keys []string) error {
+	f.calls = append(f.calls, fmt.Sprintf("ssh_keys %d", len(keys)))
+	return nil
+}
+
+func (f *fakeStateApplyAPI) SetNotificationPreferences(_ context.Context, prefs map[string]bool) (*client.SetNotificationPreferencesResponse, error) {
+	for _, id := range slices.Sorted(maps.Keys(prefs)) {
+		f.calls = append(f.calls, fmt.Sprintf("notification %s %v", id, prefs[id]))
+	}
+	return &client.SetNotificationPreferencesResponse{}, nil
+}
+
+func (f *fakeStateApplyAPI) MountISO(_ context.Context, iso string) error {
+	f.calls = append(f.calls, "mount "+iso)
+	return nil
+}
+
+func (f *fakeStateApplyAPI) UnmountISO(context.Context) error {
+	f.calls = append(f.calls, "unmount")
+	return nil
+}
+
+func (f *fakeStateApplyAPI) AddIPv6(context.Context) (*client.IPv6AddResponse, error) {
+	f.calls = append(f.calls, "ipv6 add")
+	return &client.IPv6AddResponse{}, nil
+}
+
+func (f *fakeStateApplyAPI) AssignPrivateIP(_ context.Context, ip string) (*client.PrivateIPAssignResponse, error) {
+	f.calls = append(f.calls, "private_ip "+ip)
+	return &client.PrivateIPAssignResponse{}, nil
+}
+
+func newFakeStateApplyAPI() *fakeStateApplyAPI {
+	api := &fakeStateApplyAPI{fakeStateAPI: newFakeStateAPI()}
+	api.service.AvailableISOs = []string{"ubuntu.iso", "debian.iso"}
+	api.service.PlanPrivateNetworkAvailable = true
+	api.service.LocationPrivateNetworkAvailable = true
+	api.service.PlanMaxIPv6s = 3
+	return api
+}
+
+const desiredStateYAML = `version: 1
+instance: other
+hostname: new.example.com
+ptr:
+  1.2.3.4: new.example.com
+  9.9.9.9: missing.example.com
+ssh_keys:
+  - ssh-ed25519 AAAA one
+  - ssh-ed25519 BBBB two
+notifications:
+  maintenance: false
+  unknown-pref: true
+iso: debian.iso
+ipv6_subnets: 2
+private_ips:
+  - 10.0.0.2
+  - 10.0.0.5
+`
+
+func TestParseStateManifest(t *testing.T) {
+	manifest, err := parseStateManifest([]byte(desiredStateYAML))
+	if err != nil {
+		t.Fatalf("parseStateManifest() error = %v", err)
+	}
+	if *manifest.Hostname != "new.example.com" || *manifest.IPv6Subnets != 2 || len(manifest.SSHKeys) != 2 {
+		t.Fatalf("manifest = %+v", manifest)
+	}
+
+	partial, err := parseStateManifest([]byte("hostname: a.example.com\n"))
+	if err != nil {
+		t.Fatalf("parseStateManifest(partial) error = %v", err)
+	}
+	if partial.SSHKeys != nil || partial.ISO != nil || partial.PrivateIPs != nil {
+		t.Fatalf("absent fields should stay unset: %+v", partial)
+	}
+
+	for name, input := range map[string]string{
+		"empty":           "",
+		"unknown field":   "hostnme: typo.example.com\n",
+		"future version":  "version: 99\n",
+		"negative ipv6":   "ipv6_subnets: -1\n",
+		"bad private ip":  "private_ips: [\"not-an-ip\"]\n",
+		"bad ssh key":     "ssh_keys: [\"not a key\"]\n",
+		"bad ptr address": "ptr: {bogus: x.example.com}\n",
+	} {
+		if _, err := parseStateManifest([]byte(input)); err == nil {
+			t.Fatalf("parseStateManifest(%s) error = nil, want error", name)
+		}
+	}
+}
+
+func TestRunPlanAndApply(t *testing.T) {
+	desired, err := parseStateManifest([]byte(desiredStateYAML))
+	if err != nil {
+		t.Fatalf("parseStateManifest() error = %v", err)
+	}
+
+	t.Run("plan prints ordered steps and warnings", func(t *testing.T) {
+		api := newFakeStateApplyAPI()
+		out := captureStdout(t, func() {
+			if err := runPlan(context.Background(), api, "web", desired); err != nil {
+				t.Fatalf("runPlan() error = %v", err)
+			}
+		})
+		if len(api.calls) != 0 {
+			t.Fatalf("plan wrote: %v", api.calls)
+		}
+		for _, want := range []string{
+			"manifest was exported from instance 'other'",
+			"Plan: 7 change(s).",
+			"hostname      web.example.com -> new.example.com",
+			"ptr 9.9.9.9: IP address is not assigned",
+			"notification unknown-pref: unknown preference ID",
+		} {
+			if !strings.Contains(out, want) {
+				t.Fatalf("plan output missing %q:\n%s", want, out)
+			}
+		}
+		if strings.Index(out, "private_ip") > strings.Index(out, "hostname") || strings.Index(out, "iso") < strings.Index(out, "ssh_keys") {
+			t.Fatalf("plan steps out of order:\n%s", out)
+		}
+	})
+
+	t.Run("apply runs steps in safe order", func(t *testing.T) {
+		api := newFakeStateApplyAPI()
+		captureStdout(t, func() {
+			if err := runApply(context.Background(), api, "web", desired, false, true, confirmNo); err != nil {
+				t.Fatalf("runApply() error = %v", err)
+			}
+		})
+		want := []string{
+			"private_ip 10.0.0.5",
+			"ipv6 add",
+			"hostname new.example.com",
+			"ptr 1.2.3.4 new.example.com",
+			"ssh_keys 2",
+			"notification maintenance false",
+			"mount debian.iso",
+		}
+		if !slices.Equal(api.calls, want) {
+			t.Fatalf("calls = %v, want %v", api.calls, want)
+		}
+	})
+
+	t.Run("per-step confirmation skips declined steps", func(t *testing.T) {
+		api := newFakeStateApplyAPI()
+		prompts := 0
+		confirm := func(prompt string) (bool, error) {
+			prompts++
+			return strings.Contains(prompt, "hostname"), nil
+		}
+		captureStdout(t, func() {
+			if err := runApply(context.Background(), api, "web", desired, false, false, confirm); err != nil {
+				t.Fatalf("runApply() error = %v", err)
+			}
+		})
+		if prompts != 7 {
+			t.Fatalf("prompts = %d, want 7", prompts)
+		}
+		if !slices.Equal(api.calls, []string{"hostname new.example.com"}) {
+			t.Fatalf("calls = %v, want only hostname", api.calls)
+		}
+	})
+
+	t.Run("dry run and matching state do not write", func(t *testing.T) {
+		api := newFakeStateApplyAPI()
+		out := captureStdout(t, func() {
+			if err := runApply(context.Background(), api, "web", desired, true, false, confirmNo); err != nil {
+				t.Fatalf("runApply() error = %v", err)
+			}
+		})
+		if len(api.calls) != 0 || !strings.Contains(out, "DRY RUN") {
+			t.Fatalf("dry-run calls = %v, output:\n%s", api.calls, out)
+		}
+
+		current, err := captureState(context.Background(), api, "web")
+		if err != nil {
+			t.Fatalf("captureState() error = %v", err)
+		}
+		out = captureStdout(t, func() {
+			if err := runApply(context.Background(), api, "web", current, false, true, confirmNo); err != nil {
+				t.Fatalf("runApply() error = %v", err)
+			}
+		})
+		if len(api.calls) != 0 || !strings.Contains(out, "no change needed") {
+			t.Fatalf("round-trip calls = %v, output:\n%s", api.calls, out)
+		}
+	})
+}
@@ -0,0 +1,798 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"os/signal"
+	"slices"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"github.com/guptarohit/asciigraph"
+	"github.com/strahe/bwh/internal/config"
+	"github.com/strahe/bwh/internal/progress"
+	"github.com/strahe/bwh/pkg/client"
+	"github.com/urfave/cli/v3"
+	"golang.org/x/term"
+)
+
+// minUIRefresh keeps auto-refresh from eating the API rate limit.
+const minUIRefresh = 15 * time.Second
+
+// uiLowRatePoints is the number of API calls left in the 15-minute window
+// below which auto-refresh pauses.
+const uiLowRatePoints = 20
+
+var uiCmd = &cli.Command{
+	Name:  "ui",
+	Usage: "interactive dashboard of the configured instances",
+	Description: `Shows the instances on the left and, for the selected one, live status,
+usage charts, snapshots, backups or the audit log. Only the view on screen
+is refreshed, every --refresh interval; auto-refresh pauses while fewer
+than 20 API calls are left in the 15-minute rate limit window.
+
+Keys:
+  ↑/↓, j/k      select instance
+  ←/→, tab, 1-5 switch view
+  r             refresh now
+  c             create a snapshot (asks for confirmation)
+  R             restart the VPS (asks for confirmation)
+  q             quit`,
+	Flags: []cli.Flag{
+		&cli.DurationFlag{
+			Name:  "refresh",
+			Usage: "auto-refresh interval (minimum 15s)",
+			Value: time.Minute,
+		},
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		if !term.IsTerminal(int(os.Stdin.Fd())) {
+			return fmt.Errorf("bwh ui needs an interactive terminal")
+		}
+
+		manager, err := createConfigManager(cmd)
+		if err != nil {
+			return err
+		}
+		names := slices.Sorted(maps.Keys(manager.ListInstances()))
+		_, current, err := manager.FindInstance(cmd.String("instance"))
+		if current == config.EnvInstanceName && err == nil && !slices.Contains(names, current) {
+			names = append(names, current)
+		}
+		if len(names) == 0 {
+			return fmt.Errorf("no instances configured. Run 'bwh node add <name>' to add one")
+		}
+
+		// Keys are resolved before the terminal switches to raw mode, since
+		// the vault may ask for its passphrase.
+		d := newDashboard(names, cmd.Duration("refresh"))
+		for i, name := range names {
+			if name == current {
+				d.selected = i
+			}
+			instance, err := manager.OpenInstance(name)
+			if err != nil {
+				d.openErrs[name] = err
+				continue
+			}
+			d.clients[name] = newInstanceClient(instance)
+		}
+
+		ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		return d.run(ctx, &xTerminal{}, newUIInput(stdinReader))
+	},
+}
+
+type uiView int
+
+const (
+	uiViewStatus uiView = iota
+	uiViewUsage
+	uiViewSnapshots
+	uiViewBackups
+	uiViewAudit
+	uiViewCount
+)
+
+var uiViewNames = [uiViewCount]string{"Status", "Usage", "Snapshots", "Backups", "Audit"}
+
+// dashboardAPI is what the dashboard reads; the actions use the full client.
+type dashboardAPI interface {
+	GetLiveServiceInfo(context.Context) (*client.LiveServiceInfo, error)
+	GetRateLimitStatus(context.Context) (*client.RateLimitStatus, error)
+	GetRawUsageStats(context.Context) (*client.UsageStatsResponse, error)
+	ListSnapshots(context.Context) (*client.SnapshotListResponse, error)
+	ListBackups(context.Context) (*client.BackupListResponse, error)
+	GetAuditLog(context.Context) (*client.AuditLogResponse, error)
+}
+
+// uiPanel is the data shown for one instance.
+type uiPanel struct {
+	live      *client.LiveServiceInfo
+	rate      *client.RateLimitStatus
+	usage     []client.UsageDataPoint
+	snapshots []client.SnapshotInfo
+	backups   map[string]client.BackupInfo
+	audit     []client.AuditLogEntry
+
+	fetched [uiViewCount]time.Time
+	loading [uiViewCount]bool
+	errs    [uiViewCount]error
+}
+
+// rateLimited reports whether auto-refresh should pause for the instance.
+func (p *uiPanel) rateLimited() bool {
+	return p.rate != nil && p.rate.RemainingPoints15Min < uiLowRatePoints
+}
+
+// uiResult is the outcome of fetching one view of one instance.
+type uiResult struct {
+	name  string
+	view  uiView
+	panel uiPanel
+	err   error
+}
+
+type dashboard struct {
+	names    []string
+	clients  map[string]*client.Client
+	openErrs map[string]error
+	panels   map[string]*uiPanel
+	selected int
+	view     uiView
+	interval time.Duration
+	message  string
+	width    int
+	height   int
+	now      func() time.Time
+}
+
+func newDashboard(names []string, interval time.Duration) *dashboard {
+	if interval < minUIRefresh {
+		interval = minUIRefresh
+	}
+	d := &dashboard{
+		names:    names,
+		clients:  make(map[string]*client.Client),
+		openErrs: make(map[string]error),
+		panels:   make(map[string]*uiPanel),
+		interval: interval,
+		width:    80,
+		height:   24,
+		now:      time.Now,
+	}
+	for _, name := range names {
+		d.panels[name] = &uiPanel{}
+	}
+	return d
+}
+
+func (d *dashboard) current() string {
+	return d.names[d.selected]
+}
+
+// terminal switches the terminal between the dashboard's raw mode and normal
+// line input.
+type terminal interface {
+	raw() error
+	restore() error
+	size() (width, height int)
+}
+
+// xTerminal drives the terminal of standard input with x/term.
+type xTerminal struct {
+	saved *term.State
+}
+
+func (t *xTerminal) raw() error {
+	saved, err := term.MakeRaw(int(os.Stdin.Fd()))
+	if err != nil {
+		return fmt.Errorf("failed to set up the terminal: %w", err)
+	}
+	t.saved = saved
+	return nil
+}
+
+func (t *xTerminal) restore() error {
+	if t.saved == nil {
+		return nil
+	}
+	if err := term.Restore(int(os.Stdin.Fd()), t.saved); err != nil {
+		return fmt.Errorf("failed to restore the terminal: %w", err)
+	}
+	return nil
+}
+
+func (t *xTerminal) size() (int, int) {
+	width, height, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || width == 0 || height == 0 {
+		return 80, 24
+	}
+	return width, height
+}
+
+// uiInput is the dashboard's only reader of standard input. A goroutine
+// reads stdinReader into a channel, so the dashboard can wait for keys and
+// fetch results at once, and the prompts of write actions read their lines
+// from the same channel through lines instead of racing it for the input.
+type uiInput struct {
+	chunks  chan []byte
+	pending []byte
+	lines   *bufio.Reader
+}
+
+func newUIInput(r io.Reader) *uiInput {
+	in := &uiInput{chunks: make(chan []byte)}
+	in.lines = bufio.NewReader(in)
+	go func() {
+		defer close(in.chunks)
+		for {
+			buf := make([]byte, 64)
+			n, err := r.Read(buf)
+			if n > 0 {
+				in.chunks <- buf[:n]
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return in
+}
+
+// Read implements io.Reader for lines, blocking until input arrives.
+func (in *uiInput) Read(p []byte) (int, error) {
+	if len(in.pending) == 0 {
+		chunk, ok := <-in.chunks
+		if !ok {
+			return 0, io.EOF
+		}
+		in.pending = chunk
+	}
+	n := copy(p, in.pending)
+	in.pending = in.pending[n:]
+	return n, nil
+}
+
+// keys returns the input typed so far, waiting at most timeout for some. It
+// returns io.EOF once standard input is closed.
+func (in *uiInput) keys(timeout time.Duration) ([]byte, error) {
+	if n := in.lines.Buffered(); n > 0 {
+		buf := make([]byte, n)
+		n, _ = in.lines.Read(buf)
+		return buf[:n], nil
+	}
+	if len(in.pending) > 0 {
+		buf := in.pending
+		in.pending = nil
+		return buf, nil
+	}
+	select {
+	case chunk, ok := <-in.chunks:
+		if !ok {
+			return nil, io.EOF
+		}
+		return chunk, nil
+	case <-time.After(timeout):
+		return nil, nil
+	}
+}
+
+// confirm is the confirmationFunc of write actions started from the
+// dashboard.
+func (in *uiInput) confirm(prompt string) (bool, error) {
+	return confirmFrom(in.lines, prompt)
+}
+
+const (
+	ansiClear      = "\x1b[H\x1b[2J"
+	ansiHideCursor = "\x1b[?25l"
+	ansiShowCursor = "\x1b[?25h"
+	ansiAltScreen  = "\x1b[?1049h"
+	ansiMainScreen = "\x1b[?1049l"
+	ansiReverse    = "\x1b[7m"
+	ansiReset      = "\x1b[0m"
+)
+
+// Names of the non-character keys returned by parseUIKeys.
+const (
+	uiKeyUp         = "up"
+	uiKeyDown       = "down"
+	uiKeyLeft       = "left"
+	uiKeyRight      = "right"
+	uiKeyInterrupt  = "ctrl-c"
+	uiKeyEndOfInput = "ctrl-d"
+)
+
+func (d *dashboard) run(ctx context.Context, tty terminal, input *uiInput) error {
+	if err := tty.raw(); err != nil {
+		return err
+	}
+	fmt.Print(ansiAltScreen + ansiHideCursor)
+	defer func() {
+		fmt.Print(ansiShowCursor + ansiMainScreen)
+		tty.restore() //nolint:errcheck
+	}()
+
+	results := make(chan uiResult, 8)
+	dirty := true
+	var lastSize time.Time
+	for ctx.Err() == nil {
+		for drained := false; !drained; {
+			select {
+			case r := <-results:
+				d.apply(r)
+				dirty = true
+			default:
+				drained = true
+			}
+		}
+		if d.startFetch(ctx, results, false) {
+			dirty = true
+		}
+		if time.Since(lastSize) > time.Second {
+			if w, h := tty.size(); w != d.width || h != d.height {
+				d.width, d.height = w, h
+				dirty = true
+			}
+			lastSize = time.Now()
+		}
+		if dirty {
+			fmt.Print(ansiClear + d.render())
+			dirty = false
+		}
+
+		typed, err := input.keys(100 * time.Millisecond)
+		if err != nil {
+			return nil
+		}
+		for _, key := range parseUIKeys(typed) {
+			switch key {
+			case "q", uiKeyInterrupt, uiKeyEndOfInput:
+				return nil
+			case "c", "R":
+				d.runAction(ctx, tty, input, key)
+				lastSize = time.Time{}
+			default:
+				d.handleKey(ctx, results, key)
+			}
+			dirty = true
+		}
+	}
+	return nil
+}
+
+// parseUIKeys splits terminal input into key names: arrow keys, control
+// keys and single characters.
+func parseUIKeys(input []byte) []string {
+	var keys []string
+	for len(input) > 0 {
+		switch {
+		case len(input) >= 3 && input[0] == 0x1b && (input[1] == '[' || input[1] == 'O'):
+			switch input[2] {
+			case 'A':
+				keys = append(keys, uiKeyUp)
+			case 'B':
+				keys = append(keys, uiKeyDown)
+			case 'C':
+				keys = append(keys, uiKeyRight)
+			case 'D':
+				keys = append(keys, uiKeyLeft)
+			}
+			input = input[3:]
+		case input[0] == 3:
+			keys = append(keys, uiKeyInterrupt)
+			input = input[1:]
+		case input[0] == 4:
+			keys = append(keys, uiKeyEndOfInput)
+			input = input[1:]
+		default:
+			r, size := utf8.DecodeRune(input)
+			keys = append(keys, string(r))
+			input = input[size:]
+		}
+	}
+	return keys
+}
+
+func (d *dashboard) handleKey(ctx context.Context, results chan<- uiResult, key string) {
+	d.message = ""
+	switch key {
+	case uiKeyUp, "k":
+		d.selected = (d.selected + len(d.names) - 1) % len(d.names)
+	case uiKeyDown, "j":
+		d.selected = (d.selected + 1) % len(d.names)
+	case uiKeyLeft:
+		d.view = (d.view + uiViewCount - 1) % uiViewCount
+	case uiKeyRight, "\t":
+		d.view = (d.view + 1) % uiViewCount
+	case "1", "2", "3", "4", "5":
+		d.view = uiView(key[0] - '1')
+	case "r":
+		d.startFetch(ctx, results, true)
+	}
+}
+
+// startFetch fetches the shown view of the selected instance in the
+// background if it is stale, or unconditionally with force. It reports
+// whether a fetch started.
+func (d *dashboard) startFetch(ctx context.Context, results chan<- uiResult, force bool) bool {
+	name := d.current()
+	panel := d.panels[name]
+	api := d.clients[name]
+	if api == nil || panel.loading[d.view] {
+		return false
+	}
+	if !force {
+		fetched := panel.fetched[d.view]
+		if !fetched.IsZero() && (d.now().Sub(fetched) < d.interval || panel.rateLimited()) {
+			return false
+		}
+	}
+
+	view := d.view
+	panel.loading[view] = true
+	go func() {
+		result := fetchUIView(ctx, api, view)
+		result.name = name
+		select {
+		case results <- result:
+		case <-ctx.Done():
+		}
+	}()
+	return true
+}
+
+// fetchUIView reads the data of one view.
+func fetchUIView(ctx context.Context, api dashboardAPI, view uiView) uiResult {
+	result := uiResult{view: view}
+	p := &result.panel
+	switch view {
+	case uiViewStatus:
+		p.live, result.err = api.GetLiveServiceInfo(ctx)
+		if result.err == nil {
+			// The rate limit only steers auto-refresh; failing to read it is
+			// not worth an error.
+			p.rate, _ = api.GetRateLimitStatus(ctx)
+		}
+	case uiViewUsage:
+		var stats *client.UsageStatsResponse
+		if stats, result.err = api.GetRawUsageStats(ctx); result.err == nil {
+			p.usage = filterDataByPeriod(stats.Data, "1d")
+		}
+	case uiViewSnapshots:
+		var resp *client.SnapshotListResponse
+		if resp, result.err = api.ListSnapshots(ctx); result.err == nil {
+			p.snapshots = resp.Snapshots
+		}
+	case uiViewBackups:
+		var resp *client.BackupListResponse
+		if resp, result.err = api.ListBackups(ctx); result.err == nil {
+			p.backups = resp.Backups
+		}
+	case uiViewAudit:
+		var resp *client.AuditLogResponse
+		if resp, result.err = api.GetAuditLog(ctx); result.err == nil {
+			p.audit = resp.LogEntries
+		}
+	}
+	return result
+}
+
+func (d *dashboard) apply(r uiResult) {
+	panel := d.panels[r.name]
+	panel.loading[r.view] = false
+	panel.fetched[r.view] = d.now()
+	panel.errs[r.view] = r.err
+	if r.err != nil {
+		return
+	}
+	switch r.view {
+	case uiViewStatus:
+		panel.live = r.panel.live
+		if r.panel.rate != nil {
+			panel.rate = r.panel.rate
+		}
+	case uiViewUsage:
+		panel.usage = r.panel.usage
+	case uiViewSnapshots:
+		panel.snapshots = r.panel.snapshots
+	case uiViewBackups:
+		panel.backups = r.panel.backups
+	case uiViewAudit:
+		panel.audit = r.panel.audit
+	}
+}
+
+// runAction leaves the dashboard for a write action, which runs with its
+// usual confirmation prompt, and returns when the user presses Enter.
+func (d *dashboard) runAction(ctx context.Context, tty terminal, input *uiInput, key string) {
+	name := d.current()
+	api := d.clients[name]
+	if api == nil {
+		d.message = fmt.Sprintf("%s: %v", name, d.openErrs[name])
+		return
+	}
+
+	fmt.Print(ansiShowCursor + ansiMainScreen)
+	if err := tty.restore(); err != nil {
+		d.message = err.Error()
+		return
+	}
+	fmt.Println()
+
+	var err error
+	switch key {
+	case "c":
+		err = runSnapshotCreate(ctx, api, name, "", false, false, input.confirm, nil)
+		d.panels[name].fetched[uiViewSnapshots] = time.Time{}
+	case "R":
+		err = runVPSAction(ctx, api, name, "restart", false, false, input.confirm, nil)
+	}
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+	}
+	d.panels[name].fetched[uiViewStatus] = time.Time{}
+
+	fmt.Print("\nPress Enter to return to the dashboard")
+	input.lines.ReadString('\n') //nolint:errcheck
+
+	if err := tty.raw(); err != nil {
+		d.message = err.Error()
+	}
+	fmt.Print(ansiAltScreen + ansiHideCursor)
+}
+
+// render draws the whole screen: a header with the views, the instance list
+// on the left, the selected view on the right and a footer with the keys.
+func (d *dashboard) render() string {
+	width, height := d.width, d.height
+	name := d.current()
+	panel := d.panels[name]
+
+	var tabs []string
+	for i, view := range uiViewNames {
+		label := fmt.Sprintf("%d %s", i+1, view)
+		if uiView(i) == d.view {
+			label = "[" + label + "]"
+		}
+		tabs = append(tabs, label)
+	}
+	header := " bwh ui  " + strings.Join(tabs, "  ")
+	if fetched := panel.fetched[d.view]; !fetched.IsZero() {
+		header += fmt.Sprintf("   updated %s", fetched.Format("15:04:05"))
+	}
+
+	// Errors and warnings replace the key help, which returns with the next
+	// key press, so they fit an 80 column terminal.
+	footer := " ↑↓ instance  ←→ view  r refresh  c snapshot  R restart  q quit"
+	switch {
+	case d.message != "":
+		footer = " " + d.message
+	case panel.rateLimited():
+		footer = fmt.Sprintf(" Rate limit low (%d calls left): auto-refresh paused  r refresh  q quit", panel.rate.RemainingPoints15Min)
+	}
+
+	leftWidth := 8
+	for _, n := range d.names {
+		leftWidth = maxInt(leftWidth, utf8.RuneCountInString(n)+4)
+	}
+	leftWidth = minInt(leftWidth, 24)
+	rightWidth := maxInt(width-leftWidth-3, 20)
+	bodyHeight := maxInt(height-4, 1)
+	content := d.renderView(name, panel, rightWidth, bodyHeight)
+
+	var b strings.Builder
+	b.WriteString(fitLine(header, width) + "\r\n")
+	b.WriteString(strings.Repeat("─", width) + "\r\n")
+	for row := 0; row < bodyHeight; row++ {
+		left := ""
+		if row < len(d.names) {
+			left = fitLine("  "+d.names[row], leftWidth)
+			if row == d.selected {
+				left = ansiReverse + fitLine("> "+d.names[row], leftWidth) + ansiReset
+			}
+		} else {
+			left = strings.Repeat(" ", leftWidth)
+		}
+		right := ""
+		if row < len(content) {
+			right = truncateLine(content[row], rightWidth)
+		}
+		b.WriteString(left + " │ " + right + "\r\n")
+	}
+	b.WriteString(strings.Repeat("─", width) + "\r\n")
+	b.WriteString(truncateLine(footer, width))
+	return b.String()
+}
+
+func (d *dashboard) renderView(name string, panel *uiPanel, width, height int) []string {
+	if err := d.openErrs[name]; err != nil {
+		return []string{"Cannot use this instance: " + err.Error()}
+	}
+	if err := panel.errs[d.view]; err != nil {
+		return []string{"Error: " + err.Error(), "", "Press r to retry."}
+	}
+	if panel.fetched[d.view].IsZero() {
+		return []string{"Loading..."}
+	}
+
+	switch d.view {
+	case uiViewStatus:
+		return renderUIStatus(panel.live, panel.rate)
+	case uiViewUsage:
+		return renderUIUsage(panel.usage, width, height)
+	case uiViewSnapshots:
+		return renderUISnapshots(panel.snapshots)
+	case uiViewBackups:
+		return renderUIBackups(panel.backups)
+	default:
+		return renderUIAudit(panel.audit)
+	}
+}
+
+func renderUIStatus(info *client.LiveServiceInfo, rate *client.RateLimitStatus) []string {
+	lines := []string{
+		fmt.Sprintf("Hostname   %s (%s)", info.Hostname, info.VMType),
+		fmt.Sprintf("Status     %s", client.PowerState(info)),
+		fmt.Sprintf("Plan       %s", info.Plan),
+		fmt.Sprintf("OS         %s", info.OS),
+		fmt.Sprintf("Location   %s (%s)", info.NodeLocation, info.NodeLocationID),
+	}
+	if len(info.IPAddresses) > 0 {
+		lines = append(lines, "IPs        "+strings.Join(info.IPAddresses, ", "))
+	}
+	if info.LoadAverage != "" {
+		lines = append(lines, "Load       "+info.LoadAverage)
+	}
+	lines = append(lines, "")
+
+	if info.MemAvailableKB.Value > 0 && info.PlanRAM > 0 {
+		used := info.PlanRAM - info.MemAvailableKB.Value*1024
+		lines = append(lines, fmt.Sprintf("RAM        %s / %s (%.1f%%)", formatBytes(used), formatBytes(info.PlanRAM), float64(used)/float64(info.PlanRAM)*100))
+	} else {
+		lines = append(lines, "RAM        "+formatBytes(info.PlanRAM))
+	}
+	if info.VeUsedDiskSpaceB.Value > 0 && info.PlanDisk > 0 {
+		used := info.VeUsedDiskSpaceB.Value
+		lines = append(lines, fmt.Sprintf("Disk       %s / %s (%.1f%%)", formatBytes(used), formatBytes(info.PlanDisk), float64(used)/float64(info.PlanDisk)*100))
+	} else {
+		lines = append(lines, "Disk       "+formatBytes(info.PlanDisk))
+	}
+	multiplier := int64(info.MonthlyDataMultiplier)
+	if multiplier == 0 {
+		multiplier = 1
+	}
+	if total := info.PlanMonthlyData * multiplier; total > 0 {
+		used := info.DataCounter * multiplier
+		lines = append(lines, fmt.Sprintf("Bandwidth  %s / %s (%.1f%%), resets %s", formatBytes(used), formatBytes(total),
+			float64(used)/float64(total)*100, time.Unix(info.DataNextReset, 0).Format("2006-01-02")))
+	}
+	if info.IsCPUThrottled.Value != 0 {
+		lines = append(lines, "CPU        throttled")
+	}
+	if info.IsDiskThrottled.Value != 0 {
+		lines = append(lines, "Disk I/O   throttled")
+	}
+	if info.Suspended {
+		lines = append(lines, "Suspended  yes")
+	}
+	if rate != nil {
+		lines = append(lines, "", fmt.Sprintf("API calls  %d left in 15 min, %d in 24 h", rate.RemainingPoints15Min, rate.RemainingPoints24H))
+	}
+	return lines
+}
+
+func renderUIUsage(data []client.UsageDataPoint, width, height int) []string {
+	if len(data) < 2 {
+		return []string{"Not enough usage data in the last 24 hours."}
+	}
+	cpu := make([]float64, len(data))
+	network := make([]float64, len(data))
+	for i, point := range data {
+		cpu[i] = float64(point.CPUUsage)
+		network[i] = float64(point.NetworkInBytes+point.NetworkOutBytes) / 1024 / 1024
+	}
+
+	// Each chart takes its height plus a caption line and a blank line.
+	chartHeight := maxInt((height-4)/2, 2)
+	chartWidth := maxInt(width-12, 10)
+	plot := func(series []float64, caption string) []string {
+		graph := asciigraph.Plot(series,
+			asciigraph.Height(chartHeight),
+			asciigraph.Width(chartWidth),
+			asciigraph.Caption(caption))
+		return strings.Split(graph, "\n")
+	}
+	span := getTimeRange(data)
+	lines := plot(cpu, "CPU % "+span)
+	lines = append(lines, "")
+	return append(lines, plot(network, "Network in+out (MB per 5 min) "+span)...)
+}
+
+func renderUISnapshots(snapshots []client.SnapshotInfo) []string {
+	if len(snapshots) == 0 {
+		return []string{"No snapshots."}
+	}
+	lines := []string{fmt.Sprintf("%-3s %-10s %-6s %-40s %s", "#", "SIZE", "PINNED", "FILE", "DESCRIPTION")}
+	for i, snapshot := range snapshots {
+		pinned := ""
+		if snapshot.Sticky {
+			pinned = "yes"
+		}
+		lines = append(lines, fmt.Sprintf("%-3d %-10s %-6s %-40s %s", i+1, progress.FormatBytes(snapshot.Size.Value), pinned,
+			snapshot.FileName, decodeDescription(snapshot.Description)))
+	}
+	return lines
+}
+
+func renderUIBackups(backups map[string]client.BackupInfo) []string {
+	if len(backups) == 0 {
+		return []string{"No backups."}
+	}
+	tokens := slices.Collect(maps.Keys(backups))
+	sort.Slice(tokens, func(i, j int) bool {
+		return backups[tokens[i]].Timestamp > backups[tokens[j]].Timestamp
+	})
+	lines := []string{fmt.Sprintf("%-16s %-10s %-20s %s", "TIME", "SIZE", "OS", "TOKEN")}
+	for _, token := range tokens {
+		backup := backups[token]
+		lines = append(lines, fmt.Sprintf("%-16s %-10s %-20s %s", time.Unix(backup.Timestamp, 0).Format("2006-01-02 15:04"),
+			formatBytes(backup.Size), backup.OS, token))
+	}
+	return lines
+}
+
+func renderUIAudit(entries []client.AuditLogEntry) []string {
+	if len(entries) == 0 {
+		return []string{"No audit log entries."}
+	}
+	lines := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		lines = append(lines, fmt.Sprintf("[%s] %-15s %s", time.Unix(entry.Timestamp, 0).Local().Format("01-02 15:04"),
+			intToIP(entry.RequestorIPv4), entry.Summary))
+	}
+	return lines
+}
+
+// truncateLine cuts s to width runes.
+func truncateLine(s string, width int) string {
+	if utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	runes := []rune(s)
+	if width < 1 {
+		return ""
+	}
+	return string(runes[:width-1]) + "…"
+}
+
+// fitLine truncates or pads s to exactly width runes.
+func fitLine(s string, width int) string {
+	s = truncateLine(s, width)
+	return s + strings.Repeat(" ", width-utf8.RuneCountInString(s))
+}
+
+// minInt and maxInt exist because min and max are taken by the usage
+// statistics helpers of this package.
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/strahe/bwh/pkg/client"
+)
+
+type fakeDashboardAPI struct {
+	live      *client.LiveServiceInfo
+	rate      *client.RateLimitStatus
+	usage     *client.UsageStatsResponse
+	snapshots *client.SnapshotListResponse
+	err       error
+}
+
+func (f *fakeDashboardAPI) GetLiveServiceInfo(context.Context) (*client.LiveServiceInfo, error) {
+	return f.live, f.err
+}
+
+func (f *fakeDashboardAPI) GetRateLimitStatus(context.Context) (*client.RateLimitStatus, error) {
+	return f.rate, nil
+}
+
+func (f *fakeDashboardAPI) GetRawUsageStats(context.Context) (*client.UsageStatsResponse, error) {
+	return f.usage, f.err
+}
+
+func (f *fakeDashboardAPI) ListSnapshots(context.Context) (*client.SnapshotListResponse, error) {
+	return f.snapshots, f.err
+}
+
+func (f *fakeDashboardAPI) ListBackups(context.Context) (*client.BackupListResponse, error) {
+	return &client.BackupListResponse{}, f.err
+}
+
+func (f *fakeDashboardAPI) GetAuditLog(context.Context) (*client.AuditLogResponse, error) {
+	return &client.AuditLogResponse{}, f.err
+}
+
+func TestParseUIKeys(t *testing.T) {
+	got := parseUIKeys([]byte("j\x1b[A\x1bOCq\x03é"))
+	want := []string{"j", uiKeyUp, uiKeyRight, "q", uiKeyInterrupt, "é"}
+	if !slices.Equal(got, want) {
+		t.Errorf("parseUIKeys() = %q, want %q", got, want)
+	}
+}
+
+func TestUIInput(t *testing.T) {
+	r, w := io.Pipe()
+	input := newUIInput(r)
+
+	go w.Write([]byte("j")) //nolint:errcheck
+	if keys, err := input.keys(time.Second); err != nil || string(keys) != "j" {
+		t.Fatalf("keys() = %q, %v", keys, err)
+	}
+	if keys, err := input.keys(10 * time.Millisecond); err != nil || len(keys) != 0 {
+		t.Fatalf("keys() without input = %q, %v", keys, err)
+	}
+
+	// The prompt and the dashboard read the same input: what the prompt
+	// buffered past its line is still there for the dashboard.
+	go w.Write([]byte("y\nq")) //nolint:errcheck
+	var confirmed bool
+	captureStdout(t, func() {
+		var err error
+		if confirmed, err = input.confirm("Restart?"); err != nil {
+			t.Fatalf("confirm() error = %v", err)
+		}
+	})
+	if !confirmed {
+		t.Fatal("confirm() = false, want true")
+	}
+	if keys, err := input.keys(time.Second); err != nil || string(keys) != "q" {
+		t.Fatalf("keys() after prompt = %q, %v", keys, err)
+	}
+
+	w.Close() //nolint:errcheck
+	if _, err := input.keys(time.Second); !errors.Is(err, io.EOF) {
+		t.Fatalf("keys() after close error = %v, want EOF", err)
+	}
+}
+
+func TestFetchUIView(t *testing.T) {
+	now := time.Now().Unix()
+	api := &fakeDashboardAPI{
+		live: &client.LiveServiceInfo{},
+		rate: &client.RateLimitStatus{RemainingPoints15Min: 5},
+		usage: &client.UsageStatsResponse{Data: []client.UsageDataPoint{
+			{Timestamp: now - 3*86400, CPUUsage: 90},
+			{Timestamp: now - 3600, CPUUsage: 10},
+		}},
+	}
+
+	status := fetchUIView(context.Background(), api, uiViewStatus)
+	if status.err != nil || status.panel.live == nil || status.panel.rate.RemainingPoints15Min != 5 {
+		t.Errorf("status view = %+v", status)
+	}
+	usage := fetchUIView(context.Background(), api, uiViewUsage)
+	if usage.err != nil || len(usage.panel.usage) != 1 || usage.panel.usage[0].CPUUsage != 10 {
+		t.Errorf("usage view = %+v, want only the last day", usage.panel.usage)
+	}
+
+	api.err = errors.New("boom")
+	if snapshots := fetchUIView(context.Background(), api, uiViewSnapshots); snapshots.err == nil {
+		t.Error("snapshots view error = nil, want boom")
+	}
+}
+
+func TestDashboard(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"error":0,"snapshots":[{"fileName":"a.tar.gz","description":"weekly","size":1024}]}`)) //nolint:errcheck
+	}))
+	defer server.Close()
+
+	now := time.Unix(1_700_000_000, 0)
+	d := newDashboard([]string{"db", "web"}, time.Second)
+	if d.interval != minUIRefresh {
+		t.Errorf("interval = %v, want the minimum %v", d.interval, minUIRefresh)
+	}
+	d.now = func() time.Time { return now }
+	api := client.NewClient("test-api-key-123456789", "1")
+	api.SetBaseURL(server.URL)
+	d.clients["web"] = api
+	d.openErrs["db"] = errors.New("vault is locked")
+
+	if out := d.render(); !strings.Contains(out, "> db") || !strings.Contains(out, "Cannot use this instance: vault is locked") {
+		t.Errorf("render() of a broken instance =\n%s", out)
+	}
+
+	ctx := context.Background()
+	results := make(chan uiResult, 1)
+	d.handleKey(ctx, results, "j")
+	d.handleKey(ctx, results, "3")
+	if d.current() != "web" || d.view != uiViewSnapshots {
+		t.Fatalf("selection = %s/%d, want web/snapshots", d.current(), d.view)
+	}
+	if !d.startFetch(ctx, results, false) {
+		t.Fatal("startFetch() of an unfetched view = false")
+	}
+	if d.startFetch(ctx, results, false) {
+		t.Error("startFetch() while loading = true")
+	}
+	d.apply(<-results)
+	out := d.render()
+	for _, want := range []string{"[3 Snapshots]", "> web", "a.tar.gz", "weekly", "updated "} {
+		if !strings.Contains(out, want) {
+			t.Errorf("render() is missing %q:\n%s", want, out)
+		}
+	}
+
+	if d.startFetch(ctx, results, false) {
+		t.Error("startFetch() of a fresh view = true")
+	}
+	now = now.Add(minUIRefresh)
+	d.panels["web"].rate = &client.RateLimitStatus{RemainingPoints15Min: uiLowRatePoints - 1}
+	if d.startFetch(ctx, results, false) {
+		t.Error("startFetch() with a low rate limit = true")
+	}
+	if out := d.render(); !strings.Contains(out, "auto-refresh paused") {
+		t.Errorf("render() is missing the rate limit warning:\n%s", out)
+	}
+	if !d.startFetch(ctx, results, true) {
+		t.Error("forced startFetch() with a low rate limit = false")
+	}
+	d.apply(<-results)
+	if n := calls.Load(); n != 2 {
+		t.Errorf("API called %d times, want 2", n)
+	}
+}
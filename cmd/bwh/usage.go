@@ -5,10 +5,12 @@ import (
 	"fmt"
 	"math"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/guptarohit/asciigraph"
+	"github.com/strahe/bwh/internal/history"
 	"github.com/strahe/bwh/pkg/client"
 	"github.com/urfave/cli/v3"
 )
@@ -16,6 +18,12 @@ import (
 var usageCmd = &cli.Command{
 	Name:  "usage",
 	Usage: "display detailed VPS usage statistics",
+	Commands: []*cli.Command{
+		usageForecastCmd,
+		usageCollectCmd,
+		usageExportCmd,
+		usageAnomaliesCmd,
+	},
 	Flags: []cli.Flag{
 		&cli.BoolFlag{
 			Name:  "compact",
@@ -23,9 +31,13 @@ var usageCmd = &cli.Command{
 		},
 		&cli.StringFlag{
 			Name:  "period",
-			Usage: "time period to display: 1d, 7d, 1m, all",
+			Usage: "time period to display: 1d, 7d, 1m, all, or any number of days such as 90d",
 			Value: "1d",
 		},
+		&cli.BoolFlag{
+			Name:  "no-history",
+			Usage: "ignore data points stored by 'bwh usage collect'",
+		},
 		&cli.BoolFlag{
 			Name:  "summary",
 			Usage: "show summary statistics only",
@@ -49,6 +61,16 @@ var usageCmd = &cli.Command{
 			return fmt.Errorf("failed to get usage statistics: %w", err)
 		}
 
+		if !cmd.Bool("no-history") {
+			// The history only adds to the live data, so a broken history
+			// must not keep the live statistics from being shown.
+			if dir, err := history.DefaultDir(); err != nil {
+				warnUsageHistory(err)
+			} else {
+				usageStats.Data = usageWithHistory(history.NewUsageStore(dir, resolvedName), usageStats.Data)
+			}
+		}
+
 		if len(usageStats.Data) == 0 {
 			fmt.Printf("No usage data available for instance: %s\n", resolvedName)
 			return nil
@@ -457,6 +479,10 @@ func filterDataByPeriod(data []client.UsageDataPoint, period string) []client.Us
 	case "all":
 		return data
 	default:
+		if days, ok := parseDayPeriod(period); ok {
+			cutoffTime = now.Add(-time.Duration(days) * 24 * time.Hour)
+			break
+		}
 		// Try to parse as duration (check for negative sign to ensure we subtract)
 		if duration, err := time.ParseDuration(period); err == nil {
 			if duration > 0 {
@@ -483,6 +509,19 @@ func filterDataByPeriod(data []client.UsageDataPoint, period string) []client.Us
 	return filtered
 }
 
+// parseDayPeriod parses periods given in days, such as "90d".
+func parseDayPeriod(period string) (int, bool) {
+	digits, ok := strings.CutSuffix(period, "d")
+	if !ok || digits == "" {
+		return 0, false
+	}
+	days, err := strconv.Atoi(digits)
+	if err != nil || days <= 0 {
+		return 0, false
+	}
+	return days, true
+}
+
 // getTimeRange formats the time range for chart titles
 func getTimeRange(data []client.UsageDataPoint) string {
 	if len(data) < 2 {
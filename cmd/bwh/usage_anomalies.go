@@ -0,0 +1,283 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/strahe/bwh/internal/history"
+	"github.com/strahe/bwh/pkg/client"
+	"github.com/urfave/cli/v3"
+)
+
+var usageAnomaliesCmd = &cli.Command{
+	Name:  "anomalies",
+	Usage: "find CPU, network and disk spikes and the audit entries around them",
+	Description: `Compares every usage data point with the median of the data points in the
+preceding --baseline and flags it when it is more than --threshold median
+absolute deviations above it. Consecutive flagged points are reported as one
+window, together with audit log entries within --audit-window of it.`,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "period",
+			Usage: "time period to analyze: 1d, 7d, 1m, all, or any number of days such as 90d",
+			Value: "7d",
+		},
+		&cli.DurationFlag{
+			Name:  "baseline",
+			Usage: "rolling window each data point is compared with",
+			Value: 3 * time.Hour,
+		},
+		&cli.FloatFlag{
+			Name:  "threshold",
+			Usage: "number of median absolute deviations that counts as an anomaly",
+			Value: 5,
+		},
+		&cli.DurationFlag{
+			Name:  "audit-window",
+			Usage: "show audit entries this long before or after an anomaly",
+			Value: 30 * time.Minute,
+		},
+		&cli.BoolFlag{
+			Name:  "no-history",
+			Usage: "ignore data points stored by 'bwh usage collect'",
+		},
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		opts := anomalyOptions{
+			baseline:    int(cmd.Duration("baseline") / (usageSampleSeconds * time.Second)),
+			threshold:   cmd.Float("threshold"),
+			auditWindow: cmd.Duration("audit-window"),
+		}
+		if opts.baseline < minAnomalyBaseline {
+			return fmt.Errorf("--baseline must cover at least %d data points (%s)", minAnomalyBaseline, time.Duration(minAnomalyBaseline*usageSampleSeconds)*time.Second)
+		}
+		if opts.threshold <= 0 {
+			return fmt.Errorf("--threshold must be greater than 0")
+		}
+
+		bwhClient, resolvedName, err := createBWHClient(cmd)
+		if err != nil {
+			return err
+		}
+		var store *history.UsageStore
+		if !cmd.Bool("no-history") {
+			dir, err := history.DefaultDir()
+			if err != nil {
+				return err
+			}
+			store = history.NewUsageStore(dir, resolvedName)
+		}
+		return runUsageAnomalies(ctx, bwhClient, store, resolvedName, cmd.String("period"), opts)
+	},
+}
+
+// minAnomalyBaseline is the smallest number of preceding data points a
+// baseline is computed from.
+const minAnomalyBaseline = 6
+
+// madScale converts a median absolute deviation to the standard deviation of
+// normally distributed data.
+const madScale = 1.4826
+
+type anomalyOptions struct {
+	baseline    int
+	threshold   float64
+	auditWindow time.Duration
+}
+
+// anomalyMetric is one series analyzed for anomalies.
+type anomalyMetric struct {
+	name  string
+	value func(client.UsageDataPoint) float64
+	// floor is the smallest deviation that counts, so that near-constant
+	// series do not flag tiny changes.
+	floor  float64
+	format func(float64) string
+}
+
+var anomalyMetrics = []anomalyMetric{
+	{
+		name:   "CPU",
+		value:  func(p client.UsageDataPoint) float64 { return float64(p.CPUUsage) },
+		floor:  5,
+		format: func(v float64) string { return fmt.Sprintf("%.0f%%", v) },
+	},
+	{
+		name:   "Network",
+		value:  func(p client.UsageDataPoint) float64 { return float64(p.NetworkInBytes + p.NetworkOutBytes) },
+		floor:  1 << 20,
+		format: func(v float64) string { return formatBytes(int64(v)) + " per 5 min" },
+	},
+	{
+		name:   "Disk IO",
+		value:  func(p client.UsageDataPoint) float64 { return float64(p.DiskReadBytes + p.DiskWriteBytes) },
+		floor:  1 << 20,
+		format: func(v float64) string { return formatBytes(int64(v)) + " per 5 min" },
+	},
+}
+
+// usageAnomaly is a run of consecutive anomalous data points of one metric.
+type usageAnomaly struct {
+	metric   *anomalyMetric
+	start    int64
+	end      int64
+	points   int
+	peak     float64
+	baseline float64
+	score    float64
+	audit    []client.AuditLogEntry
+}
+
+type usageAnomaliesAPI interface {
+	GetRawUsageStats(context.Context) (*client.UsageStatsResponse, error)
+	GetAuditLog(context.Context) (*client.AuditLogResponse, error)
+}
+
+func runUsageAnomalies(ctx context.Context, api usageAnomaliesAPI, store *history.UsageStore, resolvedName, period string, opts anomalyOptions) error {
+	stats, err := api.GetRawUsageStats(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get usage statistics: %w", err)
+	}
+	data := history.MergeUsage(stats.Data)
+	if store != nil {
+		if data, _, err = withUsageHistory(store, data); err != nil {
+			return err
+		}
+	}
+	data = filterDataByPeriod(data, period)
+	if len(data) <= opts.baseline {
+		return fmt.Errorf("not enough usage data: %d data points in period %s, need more than %d", len(data), period, opts.baseline)
+	}
+
+	anomalies := detectUsageAnomalies(data, opts.baseline, opts.threshold)
+	if len(anomalies) > 0 {
+		auditLog, err := api.GetAuditLog(ctx)
+		if err != nil {
+			fmt.Printf("⚠️  Could not get audit log for correlation: %v\n", err)
+		} else {
+			correlateAudit(anomalies, auditLog.LogEntries, opts.auditWindow)
+		}
+	}
+
+	printUsageAnomalies(resolvedName, data, anomalies, opts)
+	return nil
+}
+
+// detectUsageAnomalies flags data points that lie more than threshold scaled
+// median absolute deviations above the median of the preceding baseline
+// points. Only upward deviations are reported. data must be sorted by
+// timestamp.
+func detectUsageAnomalies(data []client.UsageDataPoint, baseline int, threshold float64) []*usageAnomaly {
+	var anomalies []*usageAnomaly
+	for m := range anomalyMetrics {
+		metric := &anomalyMetrics[m]
+		values := make([]float64, len(data))
+		for i, p := range data {
+			values[i] = metric.value(p)
+		}
+
+		var current *usageAnomaly
+		for i := baseline; i < len(values); i++ {
+			window := values[i-baseline : i]
+			median := medianOf(window)
+			deviations := make([]float64, len(window))
+			for j, v := range window {
+				deviations[j] = math.Abs(v - median)
+			}
+			scale := math.Max(madScale*medianOf(deviations), metric.floor)
+			score := (values[i] - median) / scale
+
+			if score < threshold {
+				current = nil
+				continue
+			}
+			if current == nil || data[i].Timestamp-current.end > usageSampleSeconds {
+				current = &usageAnomaly{metric: metric, start: data[i].Timestamp, baseline: median}
+				anomalies = append(anomalies, current)
+			}
+			current.end = data[i].Timestamp
+			current.points++
+			if score > current.score {
+				current.score = score
+				current.peak = values[i]
+			}
+		}
+	}
+
+	sort.SliceStable(anomalies, func(i, j int) bool {
+		return anomalies[i].start < anomalies[j].start
+	})
+	return anomalies
+}
+
+func medianOf(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+// correlateAudit attaches audit entries that fall within window of each
+// anomaly. An anomaly covers the 5 minutes before its first data point.
+func correlateAudit(anomalies []*usageAnomaly, entries []client.AuditLogEntry, window time.Duration) {
+	sorted := slices.Clone(entries)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Timestamp < sorted[j].Timestamp
+	})
+	margin := int64(window.Seconds())
+	for _, a := range anomalies {
+		from := a.start - usageSampleSeconds - margin
+		to := a.end + margin
+		for _, e := range sorted {
+			if e.Timestamp >= from && e.Timestamp <= to {
+				a.audit = append(a.audit, e)
+			}
+		}
+	}
+}
+
+func printUsageAnomalies(resolvedName string, data []client.UsageDataPoint, anomalies []*usageAnomaly, opts anomalyOptions) {
+	start := time.Unix(data[0].Timestamp-usageSampleSeconds, 0)
+	end := time.Unix(data[len(data)-1].Timestamp, 0)
+
+	fmt.Printf("\n🔍 Usage Anomalies: %s\n", resolvedName)
+	fmt.Printf("   Analyzed         : %d data points (%s - %s)\n", len(data), start.Local().Format("2006-01-02 15:04"), end.Local().Format("2006-01-02 15:04"))
+	fmt.Printf("   Baseline         : %s rolling median, threshold %.1f MAD\n", formatDuration(time.Duration(opts.baseline*usageSampleSeconds)*time.Second), opts.threshold)
+
+	if len(anomalies) == 0 {
+		fmt.Printf("\n✅ No anomalies found\n")
+		return
+	}
+
+	fmt.Printf("\n⚠️  %d anomal%s found\n", len(anomalies), pluralSuffix(len(anomalies), "y", "ies"))
+	for i, a := range anomalies {
+		from := time.Unix(a.start-usageSampleSeconds, 0).Local()
+		to := time.Unix(a.end, 0).Local()
+		fmt.Printf("\n[%d] %-8s %s - %s (%s)\n", i+1, a.metric.name, from.Format("2006-01-02 15:04"), to.Format("15:04"), formatDuration(to.Sub(from)))
+		fmt.Printf("    Peak: %s, baseline %s (%.1f MAD)\n", a.metric.format(a.peak), a.metric.format(a.baseline), a.score)
+		if len(a.audit) == 0 {
+			fmt.Printf("    Audit: no entries within %s\n", formatDuration(opts.auditWindow))
+			continue
+		}
+		for _, e := range a.audit {
+			fmt.Printf("    Audit: [%s] %-15s | %s\n", time.Unix(e.Timestamp, 0).Local().Format("01-02 15:04"), intToIP(e.RequestorIPv4), e.Summary)
+		}
+	}
+}
+
+func pluralSuffix(n int, singular, plural string) string {
+	if n == 1 {
+		return singular
+	}
+	return plural
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/strahe/bwh/pkg/client"
+	"github.com/urfave/cli/v3"
+)
+
+// usageSampleSeconds is the period covered by one raw usage data point.
+const usageSampleSeconds = 300
+
+var usageForecastCmd = &cli.Command{
+	Name:  "forecast",
+	Usage: "project monthly data transfer at the next counter reset",
+	Description: `Projects the data transfer counter at the next reset from the traffic rate
+over the recent --window and reports when the plan allowance would run out.
+
+Exits with an error when the projected usage reaches --threshold percent of
+the allowance, so the command can be used in cron jobs and alerting.`,
+	Flags: []cli.Flag{
+		&cli.DurationFlag{
+			Name:  "window",
+			Usage: "recent period used to measure the traffic rate",
+			Value: 24 * time.Hour,
+		},
+		&cli.FloatFlag{
+			Name:  "threshold",
+			Usage: "fail when projected usage reaches this percentage of the allowance",
+			Value: 100,
+		},
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		window := cmd.Duration("window")
+		if window < time.Hour {
+			return fmt.Errorf("--window must be at least 1h")
+		}
+		threshold := cmd.Float("threshold")
+		if threshold <= 0 {
+			return fmt.Errorf("--threshold must be greater than 0")
+		}
+
+		bwhClient, resolvedName, err := createBWHClient(cmd)
+		if err != nil {
+			return err
+		}
+		return runUsageForecast(ctx, bwhClient, resolvedName, window, threshold, time.Now())
+	},
+}
+
+type usageForecastAPI interface {
+	GetServiceInfo(context.Context) (*client.ServiceInfo, error)
+	GetRawUsageStats(context.Context) (*client.UsageStatsResponse, error)
+}
+
+// usageForecast is a projection of the monthly data transfer counter. All
+// byte values have the location multiplier applied.
+type usageForecast struct {
+	used       int64
+	limit      int64
+	rate       float64 // bytes per second over the sample window
+	samples    int
+	reset      time.Time
+	projected  int64
+	exhaustion time.Time // zero if the allowance lasts until the reset
+}
+
+func (f *usageForecast) projectedPercent() float64 {
+	if f.limit <= 0 {
+		return 0
+	}
+	return float64(f.projected) / float64(f.limit) * 100
+}
+
+// forecastUsage projects the data counter at the next reset from the network
+// traffic in data points newer than now-window.
+func forecastUsage(info *client.ServiceInfo, data []client.UsageDataPoint, window time.Duration, now time.Time) (*usageForecast, error) {
+	multiplier := int64(info.MonthlyDataMultiplier)
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	f := &usageForecast{
+		used:  info.DataCounter * multiplier,
+		limit: info.PlanMonthlyData * multiplier,
+		reset: time.Unix(info.DataNextReset, 0),
+	}
+	if info.DataNextReset <= 0 {
+		return nil, fmt.Errorf("next data counter reset is unknown")
+	}
+
+	cutoff := now.Add(-window).Unix()
+	var total float64
+	first, last := int64(0), int64(0)
+	for _, point := range data {
+		if point.Timestamp <= cutoff || point.Timestamp > now.Unix() {
+			continue
+		}
+		total += float64(point.NetworkInBytes + point.NetworkOutBytes)
+		if first == 0 || point.Timestamp < first {
+			first = point.Timestamp
+		}
+		if point.Timestamp > last {
+			last = point.Timestamp
+		}
+		f.samples++
+	}
+	if f.samples == 0 {
+		return nil, fmt.Errorf("no usage data in the last %s", formatDuration(window))
+	}
+	span := float64(last - first + usageSampleSeconds)
+	f.rate = total * float64(multiplier) / span
+
+	remaining := f.reset.Sub(now)
+	if remaining < 0 {
+		remaining = 0
+	}
+	f.projected = f.used + int64(f.rate*remaining.Seconds())
+
+	if f.limit > 0 && f.rate > 0 {
+		if f.used >= f.limit {
+			f.exhaustion = now
+		} else if left := float64(f.limit - f.used); left/f.rate < remaining.Seconds() {
+			f.exhaustion = now.Add(time.Duration(left / f.rate * float64(time.Second)))
+		}
+	}
+	return f, nil
+}
+
+func runUsageForecast(ctx context.Context, api usageForecastAPI, resolvedName string, window time.Duration, threshold float64, now time.Time) error {
+	info, err := api.GetServiceInfo(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get service info: %w", err)
+	}
+	stats, err := api.GetRawUsageStats(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get usage statistics: %w", err)
+	}
+
+	f, err := forecastUsage(info, stats.Data, window, now)
+	if err != nil {
+		return err
+	}
+	printUsageForecast(resolvedName, f, window, threshold, now)
+
+	if f.limit > 0 && f.projectedPercent() >= threshold {
+		return fmt.Errorf("projected usage %.1f%% reaches threshold %.1f%%", f.projectedPercent(), threshold)
+	}
+	return nil
+}
+
+func printUsageForecast(resolvedName string, f *usageForecast, window time.Duration, threshold float64, now time.Time) {
+	fmt.Printf("\n📈 Bandwidth Forecast: %s\n", resolvedName)
+	fmt.Printf("   Used             : %s / %s", formatBytes(f.used), formatBytes(f.limit))
+	if f.limit > 0 {
+		fmt.Printf(" (%.1f%%)", float64(f.used)/float64(f.limit)*100)
+	}
+	fmt.Printf("\n")
+	fmt.Printf("   Recent Rate      : %s/h (last %s, %d data points)\n", formatBytes(int64(f.rate*3600)), formatDuration(window), f.samples)
+	fmt.Printf("   Next Reset       : %s (in %s)\n", f.reset.Local().Format("2006-01-02 15:04"), formatDuration(f.reset.Sub(now)))
+	fmt.Printf("   Projected        : %s", formatBytes(f.projected))
+	if f.limit > 0 {
+		fmt.Printf(" (%.1f%%)", f.projectedPercent())
+	}
+	fmt.Printf("\n")
+
+	switch {
+	case f.limit <= 0:
+		fmt.Printf("   Exhaustion       : no monthly allowance reported\n")
+	case !f.exhaustion.IsZero():
+		fmt.Printf("   Exhaustion       : ⚠️  %s (in %s)\n", f.exhaustion.Local().Format("2006-01-02 15:04"), formatDuration(f.exhaustion.Sub(now)))
+	default:
+		fmt.Printf("   Exhaustion       : ✅ not before the next reset\n")
+	}
+
+	if f.limit > 0 && f.projectedPercent() >= threshold {
+		fmt.Printf("\n❌ Projected usage reaches the %.1f%% threshold\n", threshold)
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+
+	"github.com/strahe/bwh/internal/history"
+	"github.com/strahe/bwh/pkg/client"
+	"github.com/urfave/cli/v3"
+)
+
+var usageCollectCmd = &cli.Command{
+	Name:  "collect",
+	Usage: "append new usage data points to the local history",
+	Description: `Fetches raw usage statistics and appends data points not yet stored to
+~/.bwh/history/<instance>/usage.jsonl (or $BWH_HISTORY_DIR). KiwiVM only keeps a
+limited window, so run this from cron at least daily to build a long-term
+history. 'bwh usage --period 90d' merges the history with live data.`,
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "all",
+			Usage: "collect for every configured instance",
+		},
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		dir, err := history.DefaultDir()
+		if err != nil {
+			return err
+		}
+
+		if !cmd.Bool("all") {
+			bwhClient, resolvedName, err := createBWHClient(cmd)
+			if err != nil {
+				return err
+			}
+			return runUsageCollect(ctx, bwhClient, history.NewUsageStore(dir, resolvedName), resolvedName)
+		}
+
+		manager, err := createConfigManager(cmd)
+		if err != nil {
+			return err
+		}
+		instances := manager.ListInstances()
+		if len(instances) == 0 {
+			return fmt.Errorf("no instances configured. Run 'bwh node add <name>' to add one")
+		}
+
+		var failed []string
+		for _, name := range slices.Sorted(maps.Keys(instances)) {
+			instance, err := manager.OpenInstance(name)
+			if err == nil {
+				err = runUsageCollect(ctx, newInstanceClient(instance), history.NewUsageStore(dir, name), name)
+			}
+			if err != nil {
+				fmt.Printf("❌ %s: %v\n", name, err)
+				failed = append(failed, name)
+			}
+		}
+		if len(failed) > 0 {
+			return fmt.Errorf("failed to collect usage for %d instance(s): %v", len(failed), failed)
+		}
+		return nil
+	},
+}
+
+type usageStatsAPI interface {
+	GetRawUsageStats(context.Context) (*client.UsageStatsResponse, error)
+}
+
+func runUsageCollect(ctx context.Context, api usageStatsAPI, store *history.UsageStore, resolvedName string) error {
+	stats, err := api.GetRawUsageStats(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get usage statistics: %w", err)
+	}
+	added, err := store.Append(stats.Data)
+	if err != nil {
+		return fmt.Errorf("failed to update usage history: %w", err)
+	}
+	if added == 0 {
+		fmt.Printf("✅ %s: %d data points fetched, history is up to date (no change needed)\n", resolvedName, len(stats.Data))
+		return nil
+	}
+	fmt.Printf("✅ %s: %d new data point(s) of %d fetched, saved to %s\n", resolvedName, added, len(stats.Data), store.Path())
+	return nil
+}
+
+// withUsageHistory merges live data points with the stored history of the
+// instance. Without a history the live points are returned unchanged.
+func withUsageHistory(store *history.UsageStore, live []client.UsageDataPoint) ([]client.UsageDataPoint, int, error) {
+	stored, err := store.Load()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read usage history: %w", err)
+	}
+	if len(stored) == 0 {
+		return live, 0, nil
+	}
+	return history.MergeUsage(stored, live), len(stored), nil
+}
+
+// usageWithHistory is withUsageHistory for displaying usage: if the history
+// cannot be read, it warns on stderr and returns the live data points.
+func usageWithHistory(store *history.UsageStore, live []client.UsageDataPoint) []client.UsageDataPoint {
+	merged, stored, err := withUsageHistory(store, live)
+	if err != nil {
+		warnUsageHistory(err)
+		return live
+	}
+	if stored > 0 {
+		fmt.Printf("Including %d data points from local history\n", stored)
+	}
+	return merged
+}
+
+func warnUsageHistory(err error) {
+	fmt.Fprintf(os.Stderr, "⚠️  Ignoring local usage history: %v\n", err) //nolint:errcheck
+}
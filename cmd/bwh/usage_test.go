@@ -0,0 +1,274 @@
+package main
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/strahe/bwh/internal/history"
+	"github.com/strahe/bwh/pkg/client"
+)
+
+type fakeUsageAPI struct {
+	info  *client.ServiceInfo
+	stats *client.UsageStatsResponse
+}
+
+func (f *fakeUsageAPI) GetServiceInfo(context.Context) (*client.ServiceInfo, error) {
+	return f.info, nil
+}
+
+func (f *fakeUsageAPI) GetRawUsageStats(context.Context) (*client.UsageStatsResponse, error) {
+	return f.stats, nil
+}
+
+// steadyUsage returns one data point every 5 minutes for the given period,
+// each with in and out bytes.
+func steadyUsage(now time.Time, period time.Duration, in, out int64) []client.UsageDataPoint {
+	var data []client.UsageDataPoint
+	for ts := now.Add(-period).Unix() + usageSampleSeconds; ts <= now.Unix(); ts += usageSampleSeconds {
+		data = append(data, client.UsageDataPoint{Timestamp: ts, NetworkInBytes: in, NetworkOutBytes: out})
+	}
+	return data
+}
+
+func TestForecastUsage(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	const gb = int64(1) << 30
+
+	t.Run("projection applies multiplier and finds exhaustion", func(t *testing.T) {
+		info := &client.ServiceInfo{
+			PlanMonthlyData:       100 * gb,
+			DataCounter:           40 * gb,
+			MonthlyDataMultiplier: 2,
+			DataNextReset:         now.Add(10 * 24 * time.Hour).Unix(),
+		}
+		// 12 samples per hour of 1 GB split across in and out: 1 GB/h raw, 2 GB/h billed.
+		data := steadyUsage(now, 24*time.Hour, gb/24, gb/24)
+
+		f, err := forecastUsage(info, data, 24*time.Hour, now)
+		if err != nil {
+			t.Fatalf("forecastUsage() error = %v", err)
+		}
+		if f.used != 80*gb || f.limit != 200*gb {
+			t.Fatalf("used/limit = %d/%d, want multiplier applied", f.used, f.limit)
+		}
+		if f.samples != 288 {
+			t.Fatalf("samples = %d, want 288", f.samples)
+		}
+		if got, want := f.rate*3600, float64(2*gb); got < want*0.99 || got > want*1.01 {
+			t.Fatalf("rate = %.0f B/h, want about %.0f", got, want)
+		}
+		// 80 GB used + 2 GB/h for 240h = 560 GB, well over the 200 GB allowance.
+		if f.projectedPercent() < 270 {
+			t.Fatalf("projected percent = %.1f, want > 270", f.projectedPercent())
+		}
+		// 120 GB left at 2 GB/h runs out after 60 hours.
+		if got := f.exhaustion.Sub(now); got < 59*time.Hour || got > 61*time.Hour {
+			t.Fatalf("exhaustion in %v, want about 60h", got)
+		}
+	})
+
+	t.Run("window ignores older samples", func(t *testing.T) {
+		info := &client.ServiceInfo{PlanMonthlyData: 100 * gb, DataNextReset: now.Add(time.Hour).Unix()}
+		data := append(steadyUsage(now.Add(-2*time.Hour), time.Hour, gb, gb), steadyUsage(now, time.Hour, 0, 0)...)
+
+		f, err := forecastUsage(info, data, time.Hour, now)
+		if err != nil {
+			t.Fatalf("forecastUsage() error = %v", err)
+		}
+		if f.rate != 0 || !f.exhaustion.IsZero() {
+			t.Fatalf("rate = %v, exhaustion = %v, want idle", f.rate, f.exhaustion)
+		}
+	})
+
+	t.Run("no recent data is an error", func(t *testing.T) {
+		info := &client.ServiceInfo{PlanMonthlyData: gb, DataNextReset: now.Add(time.Hour).Unix()}
+		if _, err := forecastUsage(info, nil, time.Hour, now); err == nil {
+			t.Fatal("forecastUsage() error = nil, want error")
+		}
+	})
+}
+
+func TestRunUsageForecastThreshold(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	const gb = int64(1) << 30
+	api := &fakeUsageAPI{
+		info: &client.ServiceInfo{
+			PlanMonthlyData:       100 * gb,
+			DataCounter:           50 * gb,
+			MonthlyDataMultiplier: 1,
+			DataNextReset:         now.Add(10 * time.Hour).Unix(),
+		},
+		// 1 GB/h for 10 hours projects 60 GB.
+		stats: &client.UsageStatsResponse{Data: steadyUsage(now, 24*time.Hour, gb/12, 0)},
+	}
+
+	out := captureStdout(t, func() {
+		if err := runUsageForecast(context.Background(), api, "web", 24*time.Hour, 80, now); err != nil {
+			t.Fatalf("runUsageForecast() error = %v", err)
+		}
+	})
+	if !strings.Contains(out, "not before the next reset") {
+		t.Fatalf("output = %s", out)
+	}
+
+	captureStdout(t, func() {
+		err := runUsageForecast(context.Background(), api, "web", 24*time.Hour, 55, now)
+		if err == nil || !strings.Contains(err.Error(), "reaches threshold 55.0%") {
+			t.Fatalf("runUsageForecast() error = %v, want threshold error", err)
+		}
+	})
+}
+
+func TestFilterDataByPeriodDays(t *testing.T) {
+	now := time.Now()
+	data := []client.UsageDataPoint{
+		{Timestamp: now.Add(-100 * 24 * time.Hour).Unix()},
+		{Timestamp: now.Add(-80 * 24 * time.Hour).Unix()},
+		{Timestamp: now.Add(-time.Hour).Unix()},
+	}
+	if got := filterDataByPeriod(data, "90d"); len(got) != 2 {
+		t.Fatalf("filterDataByPeriod(90d) returned %d points, want 2", len(got))
+	}
+	for _, period := range []string{"d", "0d", "-3d", "xd"} {
+		if _, ok := parseDayPeriod(period); ok {
+			t.Fatalf("parseDayPeriod(%q) ok = true, want false", period)
+		}
+	}
+}
+
+func TestRunUsageCollect(t *testing.T) {
+	store := history.NewUsageStore(t.TempDir(), "web")
+	api := &fakeUsageAPI{stats: &client.UsageStatsResponse{Data: []client.UsageDataPoint{
+		{Timestamp: 100}, {Timestamp: 200},
+	}}}
+
+	out := captureStdout(t, func() {
+		if err := runUsageCollect(context.Background(), api, store, "web"); err != nil {
+			t.Fatalf("runUsageCollect() error = %v", err)
+		}
+		if err := runUsageCollect(context.Background(), api, store, "web"); err != nil {
+			t.Fatalf("second runUsageCollect() error = %v", err)
+		}
+	})
+	if !strings.Contains(out, "2 new data point(s)") || !strings.Contains(out, "no change needed") {
+		t.Fatalf("output = %s", out)
+	}
+
+	merged, stored, err := withUsageHistory(store, []client.UsageDataPoint{{Timestamp: 200}, {Timestamp: 300}})
+	if err != nil {
+		t.Fatalf("withUsageHistory() error = %v", err)
+	}
+	if stored != 2 || len(merged) != 3 || merged[2].Timestamp != 300 {
+		t.Fatalf("withUsageHistory() = %v (stored %d)", merged, stored)
+	}
+}
+
+func TestUsageWithCorruptHistory(t *testing.T) {
+	store := history.NewUsageStore(t.TempDir(), "web")
+	if _, err := store.Append([]client.UsageDataPoint{{Timestamp: 100}}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(store.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(store.Path(), append(data, "{not json\n"...), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	live := []client.UsageDataPoint{{Timestamp: 200}, {Timestamp: 300}}
+	if _, _, err := withUsageHistory(store, live); err == nil {
+		t.Fatal("withUsageHistory() error = nil, want invalid history entry")
+	}
+
+	var merged []client.UsageDataPoint
+	stderr := captureStderr(t, func() {
+		out := captureStdout(t, func() {
+			merged = usageWithHistory(store, live)
+		})
+		if out != "" {
+			t.Errorf("stdout = %q, want nothing", out)
+		}
+	})
+	if len(merged) != 2 || merged[0].Timestamp != 200 || merged[1].Timestamp != 300 {
+		t.Fatalf("usageWithHistory() = %v, want the live data points", merged)
+	}
+	if !strings.Contains(stderr, "Ignoring local usage history") || !strings.Contains(stderr, "usage.jsonl:2") {
+		t.Fatalf("stderr = %q", stderr)
+	}
+}
+
+type fakeAnomaliesAPI struct {
+	fakeUsageAPI
+	audit []client.AuditLogEntry
+}
+
+func (f *fakeAnomaliesAPI) GetAuditLog(context.Context) (*client.AuditLogResponse, error) {
+	return &client.AuditLogResponse{LogEntries: f.audit}, nil
+}
+
+func TestDetectUsageAnomalies(t *testing.T) {
+	now := time.Now().Truncate(time.Hour)
+	data := steadyUsage(now, 12*time.Hour, 100<<10, 100<<10)
+	for i := range data {
+		data[i].CPUUsage = 10 + i%3
+	}
+	// A 15 minute CPU spike and a single network burst later on.
+	for i := 60; i < 63; i++ {
+		data[i].CPUUsage = 95
+	}
+	data[100].NetworkOutBytes = 500 << 20
+
+	anomalies := detectUsageAnomalies(data, 36, 5)
+	if len(anomalies) != 2 {
+		t.Fatalf("anomalies = %d, want 2", len(anomalies))
+	}
+	cpu, network := anomalies[0], anomalies[1]
+	if cpu.metric.name != "CPU" || cpu.points != 3 || cpu.start != data[60].Timestamp || cpu.end != data[62].Timestamp || cpu.peak != 95 {
+		t.Fatalf("cpu anomaly = %+v", cpu)
+	}
+	if got := cpu.metric.format(cpu.peak); got != "95%" {
+		t.Fatalf("cpu peak = %q, want 95%%", got)
+	}
+	if network.metric.name != "Network" || network.points != 1 || network.start != data[100].Timestamp {
+		t.Fatalf("network anomaly = %+v", network)
+	}
+
+	correlateAudit(anomalies, []client.AuditLogEntry{
+		{Timestamp: data[60].Timestamp - 600, Summary: "Login from new IP"},
+		{Timestamp: data[80].Timestamp, Summary: "unrelated"},
+	}, 10*time.Minute)
+	if len(cpu.audit) != 1 || cpu.audit[0].Summary != "Login from new IP" || len(network.audit) != 0 {
+		t.Fatalf("audit correlation = %v / %v", cpu.audit, network.audit)
+	}
+}
+
+func TestRunUsageAnomalies(t *testing.T) {
+	now := time.Now().Truncate(time.Minute)
+	data := steadyUsage(now, 6*time.Hour, 1<<20, 1<<20)
+	data[len(data)-2].DiskWriteBytes = 2 << 30
+	api := &fakeAnomaliesAPI{
+		fakeUsageAPI: fakeUsageAPI{stats: &client.UsageStatsResponse{Data: data}},
+		audit:        []client.AuditLogEntry{{Timestamp: data[len(data)-2].Timestamp, RequestorIPv4: 0x01020304, Summary: "OS reinstalled"}},
+	}
+
+	out := captureStdout(t, func() {
+		if err := runUsageAnomalies(context.Background(), api, nil, "web", "1d", anomalyOptions{baseline: 36, threshold: 5, auditWindow: 30 * time.Minute}); err != nil {
+			t.Fatalf("runUsageAnomalies() error = %v", err)
+		}
+	})
+	for _, want := range []string{"1 anomaly found", "Disk IO", "Peak: 2.0 GB per 5 min, baseline 0 B per 5 min", "1.2.3.4", "OS reinstalled"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("output missing %q:\n%s", want, out)
+		}
+	}
+
+	err := runUsageAnomalies(context.Background(), api, nil, "web", "1d", anomalyOptions{baseline: 500, threshold: 5})
+	if err == nil || !strings.Contains(err.Error(), "not enough usage data") {
+		t.Fatalf("runUsageAnomalies() error = %v, want not enough data", err)
+	}
+}
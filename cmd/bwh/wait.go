@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/strahe/bwh/internal/progress"
+	"github.com/strahe/bwh/pkg/client"
+	"github.com/urfave/cli/v3"
+)
+
+const defaultWaitTimeout = 30 * time.Minute
+
+// unlockWaitFunc blocks until the VE is unlocked after a write call.
+// A nil unlockWaitFunc means the command returns as soon as the API accepts the request.
+type unlockWaitFunc func(context.Context) error
+
+type unlockWaiter interface {
+	WaitUntilUnlocked(context.Context, client.WaitOptions) error
+}
+
+func waitFlag() cli.Flag {
+	return &cli.BoolFlag{
+		Name:  "wait",
+		Usage: "wait until the VE unlocks and show live progress",
+	}
+}
+
+func waitTimeoutFlag() cli.Flag {
+	return &cli.DurationFlag{
+		Name:  "wait-timeout",
+		Usage: "maximum time to wait when --wait is set",
+		Value: defaultWaitTimeout,
+	}
+}
+
+// waitWriteFlags returns writeFlags plus --wait and --wait-timeout for
+// commands that lock the VE.
+func waitWriteFlags(extra ...cli.Flag) []cli.Flag {
+	return writeFlags(append(extra, waitFlag(), waitTimeoutFlag())...)
+}
+
+// newUnlockWait returns an unlockWaitFunc when --wait is set, or nil otherwise.
+func newUnlockWait(cmd *cli.Command, api unlockWaiter, resolvedName string) unlockWaitFunc {
+	if !cmd.Bool("wait") {
+		return nil
+	}
+	return unlockWaitWithTimeout(api, resolvedName, cmd.Duration("wait-timeout"))
+}
+
+func unlockWaitWithTimeout(api unlockWaiter, resolvedName string, timeout time.Duration) unlockWaitFunc {
+	return func(ctx context.Context) error {
+		return waitForUnlock(ctx, api, resolvedName, timeout)
+	}
+}
+
+func waitForUnlock(ctx context.Context, api unlockWaiter, resolvedName string, timeout time.Duration) error {
+	fmt.Printf("⏳ Waiting for instance %s to unlock (timeout: %s)...\n", resolvedName, timeout)
+
+	printer := &lockProgressPrinter{}
+	err := api.WaitUntilUnlocked(ctx, client.WaitOptions{
+		Timeout:    timeout,
+		OnProgress: printer.print,
+	})
+	if err != nil {
+		return fmt.Errorf("failed waiting for VE to unlock: %w", err)
+	}
+
+	fmt.Printf("✅ VE unlocked\n")
+	return nil
+}
+
+// lockProgressPrinter prints lock progress, skipping repeated operation headers.
+type lockProgressPrinter struct {
+	lastOperation string
+}
+
+func (p *lockProgressPrinter) print(event client.ProgressEvent) {
+	if event.Operation != "" && event.Operation != p.lastOperation {
+		fmt.Printf("%s\n", event.Operation)
+		p.lastOperation = event.Operation
+	}
+	if event.Message == "" && event.CompletedPercent == 0 {
+		return
+	}
+	if event.LastUpdateSecondsAgo > 0 {
+		fmt.Printf("Progress: %d%% complete - %s (updated %ds ago)\n", event.CompletedPercent, event.Message, event.LastUpdateSecondsAgo)
+	} else {
+		fmt.Printf("Progress: %d%% complete - %s\n", event.CompletedPercent, event.Message)
+	}
+}
+
+// run calls the wait function, if any.
+func (w unlockWaitFunc) run(ctx context.Context) error {
+	if w == nil {
+		return nil
+	}
+	return w(ctx)
+}
+
+const defaultPowerWaitTimeout = 5 * time.Minute
+
+// powerTargetRestarted is the wait target after a restart: the VPS must go
+// down and report running again.
+const powerTargetRestarted = "restarted"
+
+// powerWaitFunc blocks until the VPS reports the target power state.
+// A nil powerWaitFunc means the command does not wait.
+type powerWaitFunc func(ctx context.Context, target string) error
+
+type powerStateWaiter interface {
+	WaitForPowerState(context.Context, string, client.PowerWaitOptions) (*client.LiveServiceInfo, error)
+}
+
+func powerWaitFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "wait",
+			Usage: "wait until the VPS reaches the target power state",
+		},
+		&cli.DurationFlag{
+			Name:  "wait-timeout",
+			Usage: "maximum time to wait when --wait is set",
+			Value: defaultPowerWaitTimeout,
+		},
+	}
+}
+
+// newPowerWait returns a powerWaitFunc when --wait is set, or nil otherwise.
+func newPowerWait(cmd *cli.Command, api powerStateWaiter, resolvedName string) powerWaitFunc {
+	if !cmd.Bool("wait") {
+		return nil
+	}
+	return powerWaitWithTimeout(api, resolvedName, cmd.Duration("wait-timeout"))
+}
+
+func powerWaitWithTimeout(api powerStateWaiter, resolvedName string, timeout time.Duration) powerWaitFunc {
+	return func(ctx context.Context, target string) error {
+		return waitForPowerState(ctx, api, resolvedName, target, timeout)
+	}
+}
+
+func waitForPowerState(ctx context.Context, api powerStateWaiter, resolvedName, target string, timeout time.Duration) error {
+	message := fmt.Sprintf("Waiting for VPS %s to be %s", resolvedName, target)
+	opts := client.PowerWaitOptions{Timeout: timeout}
+	if target == powerTargetRestarted {
+		message = fmt.Sprintf("Waiting for VPS %s to restart", resolvedName)
+		target, opts.Transition = client.PowerStateRunning, true
+	}
+	spinner := progress.NewSpinner(message)
+	spinner.Start()
+
+	opts.OnStatus = func(state string) {
+		spinner.Update(fmt.Sprintf("%s (current: %s)", message, state))
+	}
+	_, err := api.WaitForPowerState(ctx, target, opts)
+	if err != nil {
+		spinner.Stop("")
+		return fmt.Errorf("failed waiting for VPS to be %s: %w", target, err)
+	}
+
+	spinner.Stop(fmt.Sprintf("✅ VPS is %s", target))
+	return nil
+}
+
+// run calls the wait function, if any.
+func (w powerWaitFunc) run(ctx context.Context, target string) error {
+	if w == nil {
+		return nil
+	}
+	return w(ctx, target)
+}
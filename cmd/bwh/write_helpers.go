@@ -1,10 +1,8 @@
 package main
 
 import (
-	"bufio"
 	"fmt"
 	"io"
-	"os"
 	"slices"
 	"strings"
 
@@ -42,6 +40,17 @@ func forceFlag() cli.Flag {
 	}
 }
 
+// isWriteCommand reports whether cmd changes the VPS, which every such
+// command marks with --dry-run.
+func isWriteCommand(cmd *cli.Command) bool {
+	for _, flag := range cmd.Flags {
+		if slices.Contains(flag.Names(), "dry-run") {
+			return true
+		}
+	}
+	return false
+}
+
 func skipConfirm(cmd *cli.Command) bool {
 	return cmd.Bool("yes")
 }
@@ -68,8 +77,7 @@ func confirmWrite(prompt string, skip bool, confirm confirmationFunc) (bool, err
 func promptExactConfirmation(prompt, expected string) (bool, error) {
 	fmt.Print(prompt)
 
-	reader := bufio.NewReader(os.Stdin)
-	response, err := reader.ReadString('\n')
+	response, err := stdinReader.ReadString('\n')
 	if err != nil {
 		if err == io.EOF {
 			fmt.Printf("\n")
@@ -6,11 +6,14 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/strahe/bwh/internal/config"
 	"github.com/strahe/bwh/pkg/client"
+	"github.com/urfave/cli/v3"
 )
 
 type fakePowerAPI struct {
@@ -46,7 +49,7 @@ func TestRunVPSActionSafety(t *testing.T) {
 	t.Run("dry run does not write", func(t *testing.T) {
 		api := &fakePowerAPI{status: "Stopped"}
 		out := captureStdout(t, func() {
-			if err := runVPSAction(context.Background(), api, "test", "start", true, false, confirmNo); err != nil {
+			if err := runVPSAction(context.Background(), api, "test", "start", true, false, confirmNo, nil); err != nil {
 				t.Fatalf("runVPSAction() error = %v", err)
 			}
 		})
@@ -61,7 +64,7 @@ func TestRunVPSActionSafety(t *testing.T) {
 	t.Run("clear stopped noop prevents write", func(t *testing.T) {
 		api := &fakePowerAPI{status: "Stopped"}
 		out := captureStdout(t, func() {
-			if err := runVPSAction(context.Background(), api, "test", "stop", false, true, confirmYes); err != nil {
+			if err := runVPSAction(context.Background(), api, "test", "stop", false, true, confirmYes, nil); err != nil {
 				t.Fatalf("runVPSAction() error = %v", err)
 			}
 		})
@@ -75,13 +78,121 @@ func TestRunVPSActionSafety(t *testing.T) {
 
 	t.Run("skip confirm writes", func(t *testing.T) {
 		api := &fakePowerAPI{status: "Running"}
-		if err := runVPSAction(context.Background(), api, "test", "restart", false, true, confirmNo); err != nil {
+		if err := runVPSAction(context.Background(), api, "test", "restart", false, true, confirmNo, nil); err != nil {
 			t.Fatalf("runVPSAction() error = %v", err)
 		}
 		if len(api.calls) != 1 || api.calls[0] != "restart" {
 			t.Fatalf("calls = %v, want [restart]", api.calls)
 		}
 	})
+
+	t.Run("wait uses target power state", func(t *testing.T) {
+		for action, want := range map[string]string{"start": "running", "stop": "stopped", "restart": "restarted"} {
+			api := &fakePowerAPI{status: "Starting"}
+			targets := []string{}
+			wait := func(_ context.Context, target string) error {
+				targets = append(targets, target)
+				return nil
+			}
+			captureStdout(t, func() {
+				if err := runVPSAction(context.Background(), api, "test", action, false, true, confirmNo, wait); err != nil {
+					t.Fatalf("runVPSAction(%s) error = %v", action, err)
+				}
+			})
+			if len(targets) != 1 || targets[0] != want {
+				t.Fatalf("%s wait targets = %v, want [%s]", action, targets, want)
+			}
+		}
+	})
+}
+
+type fakePowerWaiter struct {
+	target string
+	opts   client.PowerWaitOptions
+}
+
+func (f *fakePowerWaiter) WaitForPowerState(_ context.Context, target string, opts client.PowerWaitOptions) (*client.LiveServiceInfo, error) {
+	f.target, f.opts = target, opts
+	return &client.LiveServiceInfo{VeStatus: target}, nil
+}
+
+func TestWriteCommandRefusesFuzzyInstance(t *testing.T) {
+	t.Setenv(config.NoProjectConfigEnv, "1")
+	t.Setenv("BWH_INSTANCE", "")
+	t.Setenv("BWH_API_KEY", "")
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configPath, []byte(`version: 1
+instances:
+  prod-web:
+    api_key: web-api-key-123456789
+    veid: "100"
+  staging:
+    api_key: stg-api-key-123456789
+    veid: "300"
+`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	run := func(sub *cli.Command, instance string, args ...string) error {
+		t.Helper()
+		root := &cli.Command{
+			Name: "bwh",
+			Flags: []cli.Flag{
+				&cli.StringFlag{Name: "config"},
+				&cli.StringFlag{Name: "instance"},
+			},
+			Commands: []*cli.Command{sub},
+		}
+		var err error
+		captureStdout(t, func() {
+			err = root.Run(context.Background(), append([]string{"bwh", "--config", configPath, "--instance", instance, sub.Name}, args...))
+		})
+		return err
+	}
+	resolved := func(write bool, instance string) (string, error) {
+		t.Helper()
+		var name string
+		sub := &cli.Command{Name: "probe", Action: func(_ context.Context, cmd *cli.Command) error {
+			var err error
+			_, name, err = createBWHClient(cmd)
+			return err
+		}}
+		if write {
+			sub.Flags = writeFlags()
+		}
+		err := run(sub, instance)
+		return name, err
+	}
+
+	// Read commands accept the fuzzy match; writes take a name, VEID or prefix.
+	if name, err := resolved(false, "pweb"); err != nil || name != "prod-web" {
+		t.Errorf("read command with a fuzzy instance = %q, %v; want prod-web", name, err)
+	}
+	for _, instance := range []string{"prod-web", "100", "stag"} {
+		if _, err := resolved(true, instance); err != nil {
+			t.Errorf("write command with instance %q error = %v", instance, err)
+		}
+	}
+	if _, err := resolved(true, "pweb"); !errors.Is(err, config.ErrFuzzyInstance) {
+		t.Errorf("write command with a fuzzy instance error = %v, want %v", err, config.ErrFuzzyInstance)
+	}
+	if err := run(killCmd, "pweb", "--dry-run"); !errors.Is(err, config.ErrFuzzyInstance) {
+		t.Errorf("kill with a fuzzy instance error = %v, want %v", err, config.ErrFuzzyInstance)
+	}
+}
+
+func TestPowerWaitRestart(t *testing.T) {
+	for action, transition := range map[string]bool{"start": false, "restart": true} {
+		api := &fakePowerWaiter{}
+		captureStdout(t, func() {
+			if err := powerWaitWithTimeout(api, "test", time.Minute).run(context.Background(), powerActionTarget(action)); err != nil {
+				t.Fatalf("%s wait error = %v", action, err)
+			}
+		})
+		if api.target != client.PowerStateRunning || api.opts.Transition != transition {
+			t.Errorf("%s waits for %q with Transition = %v, want running and %v", action, api.target, api.opts.Transition, transition)
+		}
+	}
 }
 
 type fakeSettingsAPI struct {
@@ -191,7 +302,7 @@ func TestRunBackupCopyToSnapshotMasksTokenInDryRun(t *testing.T) {
 	}
 
 	out := captureStdout(t, func() {
-		if err := runBackupCopyToSnapshot(context.Background(), api, "test", token, true, false, confirmNo); err != nil {
+		if err := runBackupCopyToSnapshot(context.Background(), api, "test", token, true, false, confirmNo, nil); err != nil {
 			t.Fatalf("runBackupCopyToSnapshot() error = %v", err)
 		}
 	})
@@ -210,7 +321,7 @@ func TestRunBackupCopyToSnapshotMasksMissingTokenError(t *testing.T) {
 	token := "0123456789abcdef0123456789abcdef01234567"
 	api := &fakeBackupCopyAPI{backups: map[string]client.BackupInfo{}}
 
-	err := runBackupCopyToSnapshot(context.Background(), api, "test", token, true, false, confirmNo)
+	err := runBackupCopyToSnapshot(context.Background(), api, "test", token, true, false, confirmNo, nil)
 	if err == nil {
 		t.Fatal("runBackupCopyToSnapshot() error = nil, want error")
 	}
@@ -224,21 +335,39 @@ func TestRunBackupCopyToSnapshotMasksMissingTokenError(t *testing.T) {
 
 type fakeISOAPI struct {
 	service   *client.ServiceInfo
+	status    string
 	mounted   []string
 	unmounted int
+	calls     []string
 }
 
 func (f *fakeISOAPI) GetServiceInfo(context.Context) (*client.ServiceInfo, error) {
 	return f.service, nil
 }
 
+func (f *fakeISOAPI) GetLiveServiceInfo(context.Context) (*client.LiveServiceInfo, error) {
+	return &client.LiveServiceInfo{VeStatus: f.status}, nil
+}
+
 func (f *fakeISOAPI) MountISO(_ context.Context, iso string) error {
 	f.mounted = append(f.mounted, iso)
+	f.calls = append(f.calls, "mount")
 	return nil
 }
 
 func (f *fakeISOAPI) UnmountISO(context.Context) error {
 	f.unmounted++
+	f.calls = append(f.calls, "unmount")
+	return nil
+}
+
+func (f *fakeISOAPI) Start(context.Context) error {
+	f.calls = append(f.calls, "start")
+	return nil
+}
+
+func (f *fakeISOAPI) Stop(context.Context) error {
+	f.calls = append(f.calls, "stop")
 	return nil
 }
 
@@ -249,7 +378,7 @@ func TestRunISOSafety(t *testing.T) {
 	}}
 
 	out := captureStdout(t, func() {
-		if err := runMountISO(context.Background(), api, "test", "ubuntu.iso", true, false, confirmNo); err != nil {
+		if err := runMountISO(context.Background(), api, "test", "ubuntu.iso", true, false, confirmNo, nil, nil); err != nil {
 			t.Fatalf("runMountISO() error = %v", err)
 		}
 	})
@@ -260,14 +389,14 @@ func TestRunISOSafety(t *testing.T) {
 		t.Fatalf("output missing DRY RUN:\n%s", out)
 	}
 
-	if err := runMountISO(context.Background(), api, "test", "ubuntu.iso", false, false, confirmNo); err != nil {
+	if err := runMountISO(context.Background(), api, "test", "ubuntu.iso", false, false, confirmNo, nil, nil); err != nil {
 		t.Fatalf("runMountISO() error = %v", err)
 	}
 	if len(api.mounted) != 0 {
 		t.Fatalf("mounted = %v, want none after cancel", api.mounted)
 	}
 
-	if err := runMountISO(context.Background(), api, "test", "ubuntu.iso", false, true, confirmNo); err != nil {
+	if err := runMountISO(context.Background(), api, "test", "ubuntu.iso", false, true, confirmNo, nil, nil); err != nil {
 		t.Fatalf("runMountISO() error = %v", err)
 	}
 	if len(api.mounted) != 1 || api.mounted[0] != "ubuntu.iso" {
@@ -275,7 +404,7 @@ func TestRunISOSafety(t *testing.T) {
 	}
 
 	out = captureStdout(t, func() {
-		if err := runUnmountISO(context.Background(), api, "test", true, false, confirmNo); err != nil {
+		if err := runUnmountISO(context.Background(), api, "test", true, false, confirmNo, nil, nil); err != nil {
 			t.Fatalf("runUnmountISO() error = %v", err)
 		}
 	})
@@ -286,18 +415,18 @@ func TestRunISOSafety(t *testing.T) {
 		t.Fatalf("output missing DRY RUN:\n%s", out)
 	}
 
-	if err := runUnmountISO(context.Background(), api, "test", false, true, confirmNo); err != nil {
+	if err := runUnmountISO(context.Background(), api, "test", false, true, confirmNo, nil, nil); err != nil {
 		t.Fatalf("runUnmountISO() error = %v", err)
 	}
 	if api.unmounted != 1 {
 		t.Fatalf("unmounted = %d, want 1", api.unmounted)
 	}
 
-	if err := runMountISO(context.Background(), api, "test", "missing.iso", true, true, confirmNo); err == nil {
+	if err := runMountISO(context.Background(), api, "test", "missing.iso", true, true, confirmNo, nil, nil); err == nil {
 		t.Fatal("runMountISO() error = nil, want unavailable ISO error")
 	}
 	noopAPI := &fakeISOAPI{service: &client.ServiceInfo{AvailableISOs: []string{"debian.iso"}, ISO1: "debian.iso"}}
-	if err := runMountISO(context.Background(), noopAPI, "test", "debian.iso", false, true, confirmNo); err != nil {
+	if err := runMountISO(context.Background(), noopAPI, "test", "debian.iso", false, true, confirmNo, nil, nil); err != nil {
 		t.Fatalf("runMountISO() noop error = %v", err)
 	}
 	if len(noopAPI.mounted) != 0 {
@@ -305,6 +434,66 @@ func TestRunISOSafety(t *testing.T) {
 	}
 }
 
+func TestRunISOCycle(t *testing.T) {
+	api := &fakeISOAPI{service: &client.ServiceInfo{AvailableISOs: []string{"ubuntu.iso"}}}
+	targets := []string{}
+	cycle := func(_ context.Context, target string) error {
+		targets = append(targets, target)
+		return nil
+	}
+
+	out := captureStdout(t, func() {
+		if err := runMountISO(context.Background(), api, "test", "ubuntu.iso", true, false, confirmNo, nil, cycle); err != nil {
+			t.Fatalf("runMountISO() dry-run error = %v", err)
+		}
+	})
+	if len(api.calls) != 0 || len(targets) != 0 {
+		t.Fatalf("calls = %v, targets = %v, want none during dry-run", api.calls, targets)
+	}
+	if !strings.Contains(out, "power cycle") {
+		t.Fatalf("dry-run output missing power cycle:\n%s", out)
+	}
+
+	captureStdout(t, func() {
+		if err := runMountISO(context.Background(), api, "test", "ubuntu.iso", false, true, confirmNo, nil, cycle); err != nil {
+			t.Fatalf("runMountISO() error = %v", err)
+		}
+	})
+	if want := []string{"stop", "mount", "start"}; !slices.Equal(api.calls, want) {
+		t.Fatalf("calls = %v, want %v", api.calls, want)
+	}
+	if want := []string{"stopped", "running"}; !slices.Equal(targets, want) {
+		t.Fatalf("targets = %v, want %v", targets, want)
+	}
+
+	api = &fakeISOAPI{service: &client.ServiceInfo{ISO1: "ubuntu.iso"}}
+	failed := func(_ context.Context, target string) error {
+		return fmt.Errorf("still not %s", target)
+	}
+	captureStdout(t, func() {
+		if err := runUnmountISO(context.Background(), api, "test", false, true, confirmNo, nil, failed); err == nil {
+			t.Fatal("runUnmountISO() error = nil, want stop wait error")
+		}
+	})
+	if want := []string{"stop"}; !slices.Equal(api.calls, want) {
+		t.Fatalf("calls = %v, want %v after failed stop wait", api.calls, want)
+	}
+
+	api = &fakeISOAPI{service: &client.ServiceInfo{AvailableISOs: []string{"ubuntu.iso"}}, status: "Stopped"}
+	targets = nil
+	captureStdout(t, func() {
+		if err := runMountISO(context.Background(), api, "test", "ubuntu.iso", false, true, confirmNo, nil, cycle); err != nil {
+			t.Fatalf("runMountISO() of a stopped VPS error = %v", err)
+		}
+	})
+	if want := []string{"mount", "start"}; !slices.Equal(api.calls, want) {
+		t.Fatalf("calls = %v, want %v for a stopped VPS", api.calls, want)
+	}
+	if want := []string{"running"}; !slices.Equal(targets, want) {
+		t.Fatalf("targets = %v, want %v for a stopped VPS", targets, want)
+	}
+}
+
 type fakeIPv6API struct {
 	service *client.ServiceInfo
 	added   int
@@ -722,7 +911,7 @@ func TestRunReinstallSafety(t *testing.T) {
 		if err := runReinstall(context.Background(), api, "test", "ubuntu-24.04-x86_64", false, true, false, func(string, string, string) (bool, error) {
 			t.Fatal("confirm called during dry-run")
 			return false, nil
-		}); err != nil {
+		}, nil); err != nil {
 			t.Fatalf("runReinstall() error = %v", err)
 		}
 	})
@@ -735,7 +924,7 @@ func TestRunReinstallSafety(t *testing.T) {
 
 	if err := runReinstall(context.Background(), api, "test", "ubuntu-24.04-x86_64", false, false, true, func(string, string, string) (bool, error) {
 		return false, nil
-	}); err != nil {
+	}, nil); err != nil {
 		t.Fatalf("runReinstall() error = %v", err)
 	}
 	if len(api.reinstalled) != 1 || api.reinstalled[0] != "ubuntu-24.04-x86_64" {
@@ -791,7 +980,7 @@ func TestRunSnapshotCreateAndStickySafety(t *testing.T) {
 	api := &fakeSnapshotAPI{snapshots: []client.SnapshotInfo{{FileName: "snap.tar.gz", OS: "debian", Sticky: false}}}
 
 	out := captureStdout(t, func() {
-		if err := runSnapshotCreate(context.Background(), api, "test", "desc", true, false, confirmNo); err != nil {
+		if err := runSnapshotCreate(context.Background(), api, "test", "desc", true, false, confirmNo, nil); err != nil {
 			t.Fatalf("runSnapshotCreate() error = %v", err)
 		}
 	})
@@ -802,7 +991,7 @@ func TestRunSnapshotCreateAndStickySafety(t *testing.T) {
 		t.Fatalf("output missing DRY RUN:\n%s", out)
 	}
 
-	if err := runSnapshotCreate(context.Background(), api, "test", "desc", false, false, confirmNo); err != nil {
+	if err := runSnapshotCreate(context.Background(), api, "test", "desc", false, false, confirmNo, nil); err != nil {
 		t.Fatalf("runSnapshotCreate() error = %v", err)
 	}
 	if len(api.created) != 0 {
@@ -843,7 +1032,7 @@ func TestRunSnapshotDeleteAndRestoreSafety(t *testing.T) {
 		t.Fatalf("output missing DRY RUN:\n%s", out)
 	}
 
-	if err := runSnapshotRestore(context.Background(), api, "test", "snap.tar.gz", false, false, confirmNo); err != nil {
+	if err := runSnapshotRestore(context.Background(), api, "test", "snap.tar.gz", false, false, confirmNo, nil); err != nil {
 		t.Fatalf("runSnapshotRestore() error = %v", err)
 	}
 	if len(api.restored) != 0 {
@@ -882,7 +1071,7 @@ func TestRunSnapshotExportImportSafety(t *testing.T) {
 	}
 
 	out = captureStdout(t, func() {
-		if err := runSnapshotImport(context.Background(), api, "test", "12345", token, true, false, confirmNo); err != nil {
+		if err := runSnapshotImport(context.Background(), api, "test", "12345", token, true, false, confirmNo, nil); err != nil {
 			t.Fatalf("runSnapshotImport() error = %v", err)
 		}
 	})
@@ -896,14 +1085,14 @@ func TestRunSnapshotExportImportSafety(t *testing.T) {
 		t.Fatalf("dry-run output missing masked source token:\n%s", out)
 	}
 
-	if err := runSnapshotImport(context.Background(), api, "test", "12345", token, false, false, confirmNo); err != nil {
+	if err := runSnapshotImport(context.Background(), api, "test", "12345", token, false, false, confirmNo, nil); err != nil {
 		t.Fatalf("runSnapshotImport() error = %v", err)
 	}
 	if len(api.imported) != 0 {
 		t.Fatalf("imported = %v, want none after cancel", api.imported)
 	}
 
-	if err := runSnapshotImport(context.Background(), api, "test", "12345", token, false, true, confirmNo); err != nil {
+	if err := runSnapshotImport(context.Background(), api, "test", "12345", token, false, true, confirmNo, nil); err != nil {
 		t.Fatalf("runSnapshotImport() error = %v", err)
 	}
 	if len(api.imported) != 1 || api.imported[0] != "12345="+token {
@@ -973,3 +1162,237 @@ func TestRunMigrateStartSafety(t *testing.T) {
 		t.Fatal("runMigrateStart() error = nil, want unavailable location error")
 	}
 }
+
+type fakeUnlockWaiter struct {
+	events []client.ProgressEvent
+	opts   client.WaitOptions
+	err    error
+}
+
+func (f *fakeUnlockWaiter) WaitUntilUnlocked(_ context.Context, opts client.WaitOptions) error {
+	f.opts = opts
+	for _, event := range f.events {
+		opts.OnProgress(event)
+	}
+	return f.err
+}
+
+func TestRunWriteWaitsForUnlock(t *testing.T) {
+	waits := 0
+	wait := func(context.Context) error {
+		waits++
+		return nil
+	}
+	api := &fakeSnapshotAPI{}
+
+	captureStdout(t, func() {
+		if err := runSnapshotCreate(context.Background(), api, "test", "desc", true, false, confirmNo, wait); err != nil {
+			t.Fatalf("runSnapshotCreate() dry-run error = %v", err)
+		}
+		if err := runSnapshotCreate(context.Background(), api, "test", "desc", false, false, confirmNo, wait); err != nil {
+			t.Fatalf("runSnapshotCreate() cancel error = %v", err)
+		}
+	})
+	if waits != 0 {
+		t.Fatalf("waits = %d, want 0 for dry-run and cancel", waits)
+	}
+
+	captureStdout(t, func() {
+		if err := runSnapshotCreate(context.Background(), api, "test", "desc", false, true, confirmNo, wait); err != nil {
+			t.Fatalf("runSnapshotCreate() error = %v", err)
+		}
+	})
+	if len(api.created) != 1 || waits != 1 {
+		t.Fatalf("created = %v, waits = %d, want one create followed by one wait", api.created, waits)
+	}
+}
+
+func TestWaitForUnlock(t *testing.T) {
+	waiter := &fakeUnlockWaiter{events: []client.ProgressEvent{
+		{Operation: "Snapshot: create", CompletedPercent: 10, Message: "Stopping VM"},
+		{Operation: "Snapshot: create", CompletedPercent: 60, Message: "Copying disk", LastUpdateSecondsAgo: 4},
+	}}
+
+	out := captureStdout(t, func() {
+		if err := waitForUnlock(context.Background(), waiter, "test", time.Minute); err != nil {
+			t.Fatalf("waitForUnlock() error = %v", err)
+		}
+	})
+	if waiter.opts.Timeout != time.Minute {
+		t.Fatalf("timeout = %s, want 1m", waiter.opts.Timeout)
+	}
+	if strings.Count(out, "Snapshot: create") != 1 {
+		t.Fatalf("operation header should be printed once:\n%s", out)
+	}
+	for _, want := range []string{"Progress: 10% complete - Stopping VM", "Progress: 60% complete - Copying disk (updated 4s ago)", "VE unlocked"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("output missing %q:\n%s", want, out)
+		}
+	}
+
+	waiter = &fakeUnlockWaiter{err: client.ErrWaitTimeout}
+	captureStdout(t, func() {
+		if err := waitForUnlock(context.Background(), waiter, "test", time.Minute); !errors.Is(err, client.ErrWaitTimeout) {
+			t.Fatalf("waitForUnlock() error = %v, want ErrWaitTimeout", err)
+		}
+	})
+}
+
+type fakeSafeReinstallAPI struct {
+	service     *client.ServiceInfo
+	keys        string
+	snapshots   []client.SnapshotInfo
+	calls       []string
+	hostnames   []string
+	ptrs        []string
+	updatedKeys []string
+}
+
+func (f *fakeSafeReinstallAPI) GetAvailableOS(context.Context) (*client.AvailableOSResponse, error) {
+	return &client.AvailableOSResponse{Installed: "debian-12-x86_64", Templates: []string{"debian-12-x86_64", "ubuntu-24.04-x86_64"}}, nil
+}
+
+func (f *fakeSafeReinstallAPI) GetServiceInfo(context.Context) (*client.ServiceInfo, error) {
+	info := *f.service
+	return &info, nil
+}
+
+func (f *fakeSafeReinstallAPI) GetSshKeys(context.Context) (*client.SshKeysResponse, error) {
+	return &client.SshKeysResponse{SshKeysVeid: f.keys}, nil
+}
+
+func (f *fakeSafeReinstallAPI) CreateSnapshot(_ context.Context, description string) (*client.CreateSnapshotResponse, error) {
+	f.calls = append(f.calls, "snapshot")
+	f.snapshots = append(f.snapshots, client.SnapshotInfo{FileName: "new.tar.gz", Description: description})
+	return &client.CreateSnapshotResponse{}, nil
+}
+
+func (f *fakeSafeReinstallAPI) ListSnapshots(context.Context) (*client.SnapshotListResponse, error) {
+	return &client.SnapshotListResponse{Snapshots: slices.Clone(f.snapshots)}, nil
+}
+
+func (f *fakeSafeReinstallAPI) ToggleSnapshotSticky(_ context.Context, fileName string, sticky bool) error {
+	f.calls = append(f.calls, fmt.Sprintf("pin %s %v", fileName, sticky))
+	return nil
+}
+
+func (f *fakeSafeReinstallAPI) ReinstallOSWithResult(_ context.Context, osTemplate string) (*client.ReinstallOSResponse, error) {
+	f.calls = append(f.calls, "reinstall "+osTemplate)
+	// A fresh install resets the hostname, PTR records and VM keys.
+	f.service.Hostname = "localhost"
+	f.service.PTR = map[string]string{}
+	f.keys = ""
+	return &client.ReinstallOSResponse{RootPassword: "N3wP4ss", SSHPort: client.FlexibleInt{Value: 22}}, nil
+}
+
+func (f *fakeSafeReinstallAPI) SetHostname(_ context.Context, hostname string) error {
+	f.hostnames = append(f.hostnames, hostname)
+	return nil
+}
+
+func (f *fakeSafeReinstallAPI) SetPTR(_ context.Context, ip, ptr string) error {
+	f.ptrs = append(f.ptrs, ip+"="+ptr)
+	return nil
+}
+
+func (f *fakeSafeReinstallAPI) UpdateSshKeys(_ context.Context, keys []string) error {
+	f.updatedKeys = keys
+	return nil
+}
+
+func newFakeSafeReinstallAPI() *fakeSafeReinstallAPI {
+	return &fakeSafeReinstallAPI{
+		service: &client.ServiceInfo{
+			Hostname:         "web.example.com",
+			IPAddresses:      []string{"1.2.3.4"},
+			RDNSAPIAvailable: true,
+			PTR:              map[string]string{"1.2.3.4": "web.example.com"},
+		},
+		keys:      "ssh-ed25519 AAAA one\nssh-ed25519 BBBB two",
+		snapshots: []client.SnapshotInfo{{FileName: "old.tar.gz"}},
+	}
+}
+
+func TestRunReinstallSafe(t *testing.T) {
+	t.Run("dry run does not write", func(t *testing.T) {
+		api := newFakeSafeReinstallAPI()
+		waits := 0
+		out := captureStdout(t, func() {
+			err := runReinstallSafe(context.Background(), api, "test", "ubuntu-24.04-x86_64", true, false, func(string, string, string) (bool, error) {
+				t.Fatal("confirm called during dry-run")
+				return false, nil
+			}, func(context.Context) error {
+				waits++
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("runReinstallSafe() error = %v", err)
+			}
+		})
+		if len(api.calls) != 0 || waits != 0 {
+			t.Fatalf("calls = %v, waits = %d, want none", api.calls, waits)
+		}
+		if !strings.Contains(out, "DRY RUN") || !strings.Contains(out, "web.example.com") {
+			t.Fatalf("dry-run output missing plan:\n%s", out)
+		}
+	})
+
+	t.Run("snapshot reinstall and restore", func(t *testing.T) {
+		api := newFakeSafeReinstallAPI()
+		waits := 0
+		out := captureStdout(t, func() {
+			err := runReinstallSafe(context.Background(), api, "test", "ubuntu-24.04-x86_64", false, true, nil, func(context.Context) error {
+				waits++
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("runReinstallSafe() error = %v", err)
+			}
+		})
+
+		wantCalls := []string{"snapshot", "pin new.tar.gz true", "reinstall ubuntu-24.04-x86_64"}
+		if !slices.Equal(api.calls, wantCalls) {
+			t.Fatalf("calls = %v, want %v", api.calls, wantCalls)
+		}
+		if waits != 2 {
+			t.Fatalf("waits = %d, want 2 (snapshot and reinstall)", waits)
+		}
+		if !slices.Equal(api.hostnames, []string{"web.example.com"}) {
+			t.Fatalf("hostnames = %v", api.hostnames)
+		}
+		if !slices.Equal(api.ptrs, []string{"1.2.3.4=web.example.com"}) {
+			t.Fatalf("ptrs = %v", api.ptrs)
+		}
+		if len(api.updatedKeys) != 2 {
+			t.Fatalf("updated keys = %v, want 2 keys", api.updatedKeys)
+		}
+		for _, want := range []string{"SAFE REINSTALL REPORT", "new.tar.gz (pinned)", "Root password: N3wP4ss"} {
+			if !strings.Contains(out, want) {
+				t.Fatalf("output missing %q:\n%s", want, out)
+			}
+		}
+	})
+
+	t.Run("snapshot wait failure aborts before reinstall", func(t *testing.T) {
+		api := newFakeSafeReinstallAPI()
+		captureStdout(t, func() {
+			err := runReinstallSafe(context.Background(), api, "test", "ubuntu-24.04-x86_64", false, true, nil, func(context.Context) error {
+				return client.ErrWaitTimeout
+			})
+			if !errors.Is(err, client.ErrWaitTimeout) {
+				t.Fatalf("runReinstallSafe() error = %v, want ErrWaitTimeout", err)
+			}
+		})
+		if slices.Contains(api.calls, "reinstall ubuntu-24.04-x86_64") {
+			t.Fatalf("calls = %v, reinstall should not run", api.calls)
+		}
+	})
+
+	t.Run("requires os", func(t *testing.T) {
+		api := newFakeSafeReinstallAPI()
+		err := runReinstallSafe(context.Background(), api, "test", "", false, true, nil, func(context.Context) error { return nil })
+		if err == nil {
+			t.Fatal("runReinstallSafe() error = nil, want missing --os error")
+		}
+	})
+}
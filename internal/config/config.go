@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
 	"strings"
@@ -19,27 +20,91 @@ var (
 	ErrNoDefaultInstance = errors.New("no default instance set")
 	ErrInvalidAPIKey     = errors.New("invalid API key format")
 	ErrInvalidVeID       = errors.New("invalid VeID format")
+	ErrInvalidCIDR       = errors.New("invalid trusted CIDR")
 )
 
 // Config represents the BWH CLI configuration
 type Config struct {
+	// Version is the schema version; older files are migrated on load.
+	Version         int                  `yaml:"version"`
 	DefaultInstance string               `yaml:"default_instance,omitempty"`
 	Instances       map[string]*Instance `yaml:"instances"`
+	Notifiers       map[string]*Notifier `yaml:"notifiers,omitempty"`
+	// Tags optionally declares the instance tags in use, with a description.
+	// 'bwh config validate' reports declared tags no instance uses and
+	// undeclared tags on instances.
+	Tags map[string]string `yaml:"tags,omitempty"`
+	// Include lists further config files merged below this one. Relative
+	// paths are relative to this file.
+	Include []string `yaml:"include,omitempty"`
+	// Vault is set when API keys are stored encrypted.
+	Vault *Vault `yaml:"vault,omitempty"`
+}
+
+// Notifier configures a notification sink. Type selects which of the other
+// fields apply: url and headers for webhook, slack and discord, smtp for
+// smtp, and command for command.
+type Notifier struct {
+	Type    string            `yaml:"type"`
+	URL     string            `yaml:"url,omitempty"`
+	Headers map[string]string `yaml:"headers,omitempty"`
+	SMTP    *SMTP             `yaml:"smtp,omitempty"`
+	Command []string          `yaml:"command,omitempty"`
+	// MinSeverity drops events below this severity: info (default),
+	// warning or critical.
+	MinSeverity string `yaml:"min_severity,omitempty"`
+}
+
+// SMTP configures an email notification sink.
+type SMTP struct {
+	Host     string   `yaml:"host"`
+	Port     int      `yaml:"port,omitempty"`
+	Username string   `yaml:"username,omitempty"`
+	Password string   `yaml:"password,omitempty"`
+	From     string   `yaml:"from"`
+	To       []string `yaml:"to"`
 }
 
 // Instance represents a BWH VPS instance configuration
 type Instance struct {
-	APIKey      string   `yaml:"api_key"`
-	VeID        string   `yaml:"veid"`
-	Description string   `yaml:"description,omitempty"`
-	Endpoint    string   `yaml:"endpoint,omitempty"`
-	Tags        []string `yaml:"tags,omitempty"`
+	APIKey string `yaml:"api_key,omitempty"`
+	// APIKeyEncrypted replaces APIKey when the vault is enabled.
+	APIKeyEncrypted string `yaml:"api_key_encrypted,omitempty"`
+	// APIKeyEnv, APIKeyFile and APIKeyCommand read the API key from an
+	// environment variable, a file or the output of a shell command instead
+	// of storing it in the config file. They are resolved when the instance
+	// is used.
+	APIKeyEnv     string   `yaml:"api_key_env,omitempty"`
+	APIKeyFile    string   `yaml:"api_key_file,omitempty"`
+	APIKeyCommand string   `yaml:"api_key_command,omitempty"`
+	VeID          string   `yaml:"veid"`
+	Description   string   `yaml:"description,omitempty"`
+	Endpoint      string   `yaml:"endpoint,omitempty"`
+	Tags          []string `yaml:"tags,omitempty"`
+	// TrustedCIDRs lists the IPv4 ranges API requests are expected to come
+	// from. Audit log entries from other addresses are reported by
+	// 'bwh audit check'.
+	TrustedCIDRs []string `yaml:"trusted_cidrs,omitempty"`
 }
 
 // Manager handles configuration operations
 type Manager struct {
 	configPath string
 	config     *Config
+
+	// below and above are the read-only layers merged with config into
+	// merged, which all lookups use. Changes are only made to config.
+	below   []configLayer
+	above   []configLayer
+	merged  *Config
+	origins map[string][]string
+
+	// migratedFrom and backupPath are set when Load upgraded the config file.
+	migratedFrom int
+	backupPath   string
+
+	vaultKeyFunc  VaultKeyFunc
+	vaultKeyCache []byte
 }
 
 // NewManager creates a new configuration manager
@@ -58,8 +123,13 @@ func NewManager(configPath string) (*Manager, error) {
 	}
 
 	// Try to load existing config
-	if err := m.Load(); err != nil && !os.IsNotExist(err) {
-		return nil, fmt.Errorf("failed to load config: %w", err)
+	if err := m.Load(); err != nil {
+		if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to load config: %w", err)
+		}
+		if err := m.loadLayers(); err != nil {
+			return nil, fmt.Errorf("failed to load config: %w", err)
+		}
 	}
 
 	return m, nil
@@ -93,6 +163,22 @@ func (m *Manager) Load() error {
 		return err
 	}
 
+	upgraded, from, err := upgradeConfig(data)
+	if err != nil {
+		return err
+	}
+	if upgraded != nil {
+		backupPath := fmt.Sprintf("%s.v%d.bak", m.configPath, from)
+		if err := os.WriteFile(backupPath, data, 0o600); err != nil {
+			return fmt.Errorf("failed to back up config file before migration: %w", err)
+		}
+		if err := os.WriteFile(m.configPath, upgraded, 0o600); err != nil {
+			return fmt.Errorf("failed to write migrated config file: %w", err)
+		}
+		m.migratedFrom, m.backupPath = from, backupPath
+		data = upgraded
+	}
+
 	if err := yaml.Unmarshal(data, m.config); err != nil {
 		return fmt.Errorf("failed to parse config file: %w", err)
 	}
@@ -101,7 +187,7 @@ func (m *Manager) Load() error {
 		m.config.Instances = make(map[string]*Instance)
 	}
 
-	return nil
+	return m.loadLayers()
 }
 
 // Save saves the configuration to file with secure permissions
@@ -117,6 +203,7 @@ func (m *Manager) Save() error {
 		return fmt.Errorf("failed to set directory permissions: %w", err)
 	}
 
+	m.config.Version = CurrentVersion
 	data, err := yaml.Marshal(m.config)
 	if err != nil {
 		return fmt.Errorf("failed to marshal config: %w", err)
@@ -127,6 +214,7 @@ func (m *Manager) Save() error {
 		return fmt.Errorf("failed to write config file: %w", err)
 	}
 
+	m.merge()
 	return nil
 }
 
@@ -144,6 +232,14 @@ func (m *Manager) AddInstance(name string, instance *Instance, setDefault bool)
 		return fmt.Errorf("%w: %s", ErrInstanceExists, name)
 	}
 
+	if m.VaultEnabled() && instance.APIKey != "" {
+		sealed, err := m.sealInstance(name, instance)
+		if err != nil {
+			return err
+		}
+		instance = sealed
+	}
+
 	m.config.Instances[name] = instance
 
 	// Set as default if requested or if it's the first instance
@@ -157,6 +253,9 @@ func (m *Manager) AddInstance(name string, instance *Instance, setDefault bool)
 // RemoveInstance removes an instance from the configuration
 func (m *Manager) RemoveInstance(name string) error {
 	if _, exists := m.config.Instances[name]; !exists {
+		if origins := m.origins[name]; len(origins) > 0 {
+			return fmt.Errorf("instance %s is defined in %s; edit that file to remove it", name, strings.Join(origins, ", "))
+		}
 		return fmt.Errorf("%w: %s", ErrInstanceNotFound, name)
 	}
 
@@ -179,7 +278,7 @@ func (m *Manager) RemoveInstance(name string) error {
 
 // SetDefault sets the default instance
 func (m *Manager) SetDefault(name string) error {
-	if _, exists := m.config.Instances[name]; !exists {
+	if _, exists := m.merged.Instances[name]; !exists {
 		return fmt.Errorf("%w: %s", ErrInstanceNotFound, name)
 	}
 
@@ -189,54 +288,122 @@ func (m *Manager) SetDefault(name string) error {
 
 // GetInstance returns the configuration for a specific instance
 func (m *Manager) GetInstance(name string) (*Instance, error) {
-	instance, exists := m.config.Instances[name]
+	instance, exists := m.merged.Instances[name]
 	if !exists {
 		return nil, fmt.Errorf("%w: %s", ErrInstanceNotFound, name)
 	}
 	return instance, nil
 }
 
-// ListInstances returns all configured instances
+// ListInstances returns all configured instances, merged from all config
+// files
 func (m *Manager) ListInstances() map[string]*Instance {
-	return m.config.Instances
+	return m.merged.Instances
+}
+
+// ListNotifiers returns all configured notification sinks
+func (m *Manager) ListNotifiers() map[string]*Notifier {
+	return m.merged.Notifiers
+}
+
+// Path returns the path of the config file.
+func (m *Manager) Path() string {
+	return m.configPath
+}
+
+// Migration reports whether loading upgraded the config file to
+// CurrentVersion, the version it had before, and where the original was
+// backed up.
+func (m *Manager) Migration() (from int, backupPath string, migrated bool) {
+	return m.migratedFrom, m.backupPath, m.backupPath != ""
 }
 
 // GetDefaultInstance returns the default instance name
 func (m *Manager) GetDefaultInstance() string {
-	return m.config.DefaultInstance
+	return m.merged.DefaultInstance
+}
+
+// OpenInstance returns the configuration for a specific instance with its API
+// key decrypted, unlocking the vault if needed.
+func (m *Manager) OpenInstance(name string) (*Instance, error) {
+	instance, err := m.lookupInstance(name)
+	if err != nil {
+		return nil, err
+	}
+	return m.openInstance(name, instance)
 }
 
 // ResolveInstance resolves the instance to use based on priority:
 // 1. Explicit instance name parameter
 // 2. BWH_INSTANCE environment variable
-// 3. Default instance from config
-// 4. If only one instance exists, use it
+// 3. Instance defined by BWH_API_KEY and BWH_VEID, named "env"
+// 4. Default instance from config
+// 5. If only one instance exists, use it
+//
+// The names in 1 and 2 may also be a VEID, a hostname or IP address cached
+// by 'bwh node refresh', or a unique prefix or fuzzy match of a name.
+//
+// The returned instance has its API key decrypted.
 func (m *Manager) ResolveInstance(instanceName string) (*Instance, string, error) {
-	if len(m.config.Instances) == 0 {
+	instance, name, err := m.FindInstance(instanceName)
+	if err != nil {
+		return nil, name, err
+	}
+	instance, err = m.openInstance(name, instance)
+	return instance, name, err
+}
+
+// ResolveInstanceForWrite resolves the instance like ResolveInstance, but
+// refuses names that only match fuzzily, so that a typo never sends a write
+// to another server.
+func (m *Manager) ResolveInstanceForWrite(instanceName string) (*Instance, string, error) {
+	instance, name, err := m.findInstance(instanceName, false)
+	if err != nil {
+		return nil, name, err
+	}
+	instance, err = m.openInstance(name, instance)
+	return instance, name, err
+}
+
+// FindInstance resolves the instance like ResolveInstance, but leaves its API
+// key unresolved.
+func (m *Manager) FindInstance(instanceName string) (*Instance, string, error) {
+	return m.findInstance(instanceName, true)
+}
+
+func (m *Manager) findInstance(instanceName string, fuzzy bool) (*Instance, string, error) {
+	env, err := envInstance()
+	if err != nil {
+		return nil, "", err
+	}
+	if len(m.merged.Instances) == 0 && env == nil {
 		return nil, "", ErrNoInstances
 	}
 
 	// Priority 1: Explicit instance name
 	if instanceName != "" {
-		instance, err := m.GetInstance(instanceName)
-		return instance, instanceName, err
+		return m.findNamed(instanceName, fuzzy)
 	}
 
 	// Priority 2: Environment variable
 	if envInstance := os.Getenv("BWH_INSTANCE"); envInstance != "" {
-		instance, err := m.GetInstance(envInstance)
-		return instance, envInstance, err
+		return m.findNamed(envInstance, fuzzy)
 	}
 
-	// Priority 3: Default instance
-	if m.config.DefaultInstance != "" {
-		instance, err := m.GetInstance(m.config.DefaultInstance)
-		return instance, m.config.DefaultInstance, err
+	// Priority 3: Environment credentials
+	if env != nil {
+		return env, EnvInstanceName, nil
 	}
 
-	// Priority 4: Single instance
-	if len(m.config.Instances) == 1 {
-		for name, instance := range m.config.Instances {
+	// Priority 4: Default instance
+	if m.merged.DefaultInstance != "" {
+		instance, err := m.GetInstance(m.merged.DefaultInstance)
+		return instance, m.merged.DefaultInstance, err
+	}
+
+	// Priority 5: Single instance
+	if len(m.merged.Instances) == 1 {
+		for name, instance := range m.merged.Instances {
 			return instance, name, nil
 		}
 	}
@@ -246,7 +413,7 @@ func (m *Manager) ResolveInstance(instanceName string) (*Instance, string, error
 
 // ValidateInstance validates an instance by testing the API connection
 func (m *Manager) ValidateInstance(instanceName string) error {
-	instance, err := m.GetInstance(instanceName)
+	instance, err := m.OpenInstance(instanceName)
 	if err != nil {
 		return err
 	}
@@ -267,8 +434,8 @@ func (m *Manager) ValidateInstance(instanceName string) error {
 
 // GetAvailableInstances returns a list of available instance names
 func (m *Manager) GetAvailableInstances() []string {
-	names := make([]string, 0, len(m.config.Instances))
-	for name := range m.config.Instances {
+	names := make([]string, 0, len(m.merged.Instances))
+	for name := range m.merged.Instances {
 		names = append(names, name)
 	}
 	return names
@@ -306,29 +473,87 @@ func validateInstanceName(name string) error {
 }
 
 func validateInstance(instance *Instance) error {
-	if instance.APIKey == "" {
+	switch instance.apiKeySources() {
+	case 0:
 		return ErrInvalidAPIKey
+	case 1:
+	default:
+		return fmt.Errorf("%w: set only one of api_key, api_key_env, api_key_file and api_key_command", ErrInvalidAPIKey)
 	}
 	if instance.VeID == "" {
 		return ErrInvalidVeID
 	}
 
+	if instance.APIKey != "" {
+		if err := validateAPIKey(instance.APIKey); err != nil {
+			return err
+		}
+	}
+
+	// Basic VeID validation (should be numeric or alphanumeric)
+	if len(instance.VeID) == 0 || len(instance.VeID) > 32 {
+		return ErrInvalidVeID
+	}
+
+	if _, err := instance.TrustedNets(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidCIDR, err)
+	}
+
+	return nil
+}
+
+func validateAPIKey(apiKey string) error {
 	// Enhanced API key validation
-	if len(instance.APIKey) < 10 || len(instance.APIKey) > 256 {
+	if len(apiKey) < 10 || len(apiKey) > 256 {
 		return ErrInvalidAPIKey
 	}
 
 	// Check for common patterns that might indicate invalid keys
-	if strings.Contains(instance.APIKey, " ") ||
-		strings.Contains(instance.APIKey, "\t") ||
-		strings.Contains(instance.APIKey, "\n") {
+	if strings.Contains(apiKey, " ") ||
+		strings.Contains(apiKey, "\t") ||
+		strings.Contains(apiKey, "\n") {
 		return ErrInvalidAPIKey
 	}
+	return nil
+}
 
-	// Basic VeID validation (should be numeric or alphanumeric)
-	if len(instance.VeID) == 0 || len(instance.VeID) > 32 {
-		return ErrInvalidVeID
+// TrustedNets parses the instance's trusted CIDRs.
+func (i *Instance) TrustedNets() ([]*net.IPNet, error) {
+	return ParseIPv4Nets(i.TrustedCIDRs)
+}
+
+// ParseIPv4Nets parses IPv4 addresses and CIDR ranges. A plain address is
+// treated as a /32.
+func ParseIPv4Nets(values []string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if !strings.Contains(value, "/") {
+			value += "/32"
+		}
+		ip, ipNet, err := net.ParseCIDR(value)
+		if err != nil || ip.To4() == nil {
+			return nil, fmt.Errorf("%q is not an IPv4 address or CIDR", strings.TrimSuffix(value, "/32"))
+		}
+		nets = append(nets, ipNet)
 	}
+	return nets, nil
+}
 
-	return nil
+// IPv4 converts an IPv4 address the API encodes as an integer, such as the
+// requestor of an audit log entry.
+func IPv4(n uint32) net.IP {
+	return net.IPv4(byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+}
+
+// IPv4InNets reports whether the integer-encoded IPv4 address n is in any of
+// nets.
+func IPv4InNets(n uint32, nets []*net.IPNet) bool {
+	ip := IPv4(n)
+	for _, ipNet := range nets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
 }
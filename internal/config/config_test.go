@@ -1,6 +1,7 @@
 package config
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"strings"
@@ -74,6 +75,28 @@ func TestValidateInstance(t *testing.T) {
 	}
 }
 
+func TestValidateInstanceTrustedCIDRs(t *testing.T) {
+	instance := &Instance{
+		APIKey:       "valid-api-key-123456789",
+		VeID:         "123456",
+		TrustedCIDRs: []string{"203.0.113.0/24", "198.51.100.7"},
+	}
+	if err := validateInstance(instance); err != nil {
+		t.Fatalf("validateInstance() error = %v", err)
+	}
+	nets, err := instance.TrustedNets()
+	if err != nil || len(nets) != 2 || nets[1].String() != "198.51.100.7/32" {
+		t.Fatalf("TrustedNets() = %v, %v", nets, err)
+	}
+
+	for _, bad := range []string{"2001:db8::/32", "1.2.3", "10.0.0.0/33"} {
+		instance.TrustedCIDRs = []string{bad}
+		if err := validateInstance(instance); !errors.Is(err, ErrInvalidCIDR) {
+			t.Errorf("validateInstance(%q) error = %v, want %v", bad, err, ErrInvalidCIDR)
+		}
+	}
+}
+
 func TestConfigFileSecurity(t *testing.T) {
 	tempDir := t.TempDir()
 	configPath := filepath.Join(tempDir, "test-config.yaml")
@@ -157,3 +180,31 @@ func TestInstanceResolution(t *testing.T) {
 		t.Errorf("ResolveInstance() name = %v, want instance1 (default)", name)
 	}
 }
+
+func TestLoadNotifiers(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	data := `instances: {}
+notifiers:
+  chat:
+    type: slack
+    url: https://hooks.example.com/x
+    min_severity: warning
+  mail:
+    type: smtp
+    smtp:
+      host: smtp.example.com
+      from: bwh@example.com
+      to: [ops@example.com]
+`
+	if err := os.WriteFile(configPath, []byte(data), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	manager, err := NewManager(configPath)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	notifiers := manager.ListNotifiers()
+	if len(notifiers) != 2 || notifiers["chat"].MinSeverity != "warning" || notifiers["mail"].SMTP.To[0] != "ops@example.com" {
+		t.Fatalf("ListNotifiers() = %+v", notifiers)
+	}
+}
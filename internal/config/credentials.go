@@ -0,0 +1,121 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// ErrAPIKeyUnavailable is returned when an external API key source cannot be
+// read.
+var ErrAPIKeyUnavailable = errors.New("API key unavailable")
+
+// apiKeyCommandTimeout bounds how long api_key_command may run.
+const apiKeyCommandTimeout = 30 * time.Second
+
+// apiKeySources counts the API key sources set on the instance.
+func (i *Instance) apiKeySources() int {
+	count := 0
+	for _, source := range []string{i.APIKey, i.APIKeyEncrypted, i.APIKeyEnv, i.APIKeyFile, i.APIKeyCommand} {
+		if source != "" {
+			count++
+		}
+	}
+	return count
+}
+
+// openInstance returns instance with its API key resolved. Instances with a
+// plain API key are returned as is; all others as a copy, so that the
+// resolved key is never saved.
+func (m *Manager) openInstance(name string, instance *Instance) (*Instance, error) {
+	var (
+		apiKey string
+		source string
+		err    error
+	)
+	switch {
+	case instance.APIKeyEncrypted != "":
+		return m.decryptInstance(name, instance)
+	case instance.APIKeyEnv != "":
+		source = "api_key_env " + instance.APIKeyEnv
+		apiKey, err = apiKeyFromEnv(instance.APIKeyEnv)
+	case instance.APIKeyFile != "":
+		source = "api_key_file " + instance.APIKeyFile
+		apiKey, err = apiKeyFromFile(expandPath(instance.APIKeyFile, filepath.Dir(m.configPath)))
+	case instance.APIKeyCommand != "":
+		source = "api_key_command"
+		apiKey, err = apiKeyFromCommand(instance.APIKeyCommand)
+	case instance.APIKey == "":
+		return nil, fmt.Errorf("%w: instance %s has no API key; add one with 'bwh node add %s --api-key <key> --veid %s'", ErrAPIKeyUnavailable, name, name, instance.VeID)
+	default:
+		return instance, nil
+	}
+	if err == nil {
+		err = validateAPIKey(apiKey)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("instance %s: %s: %w", name, source, err)
+	}
+
+	opened := *instance
+	opened.APIKey = apiKey
+	return &opened, nil
+}
+
+func apiKeyFromEnv(name string) (string, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok || strings.TrimSpace(value) == "" {
+		return "", fmt.Errorf("%w: environment variable %s is not set", ErrAPIKeyUnavailable, name)
+	}
+	return strings.TrimSpace(value), nil
+}
+
+func apiKeyFromFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrAPIKeyUnavailable, err)
+	}
+	apiKey := strings.TrimSpace(string(data))
+	if apiKey == "" {
+		return "", fmt.Errorf("%w: %s is empty", ErrAPIKeyUnavailable, path)
+	}
+	return apiKey, nil
+}
+
+// apiKeyFromCommand runs command with the system shell, like git credential
+// helpers, and returns the first line of its output.
+func apiKeyFromCommand(command string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), apiKeyCommandTimeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%w: %q failed: %v: %s", ErrAPIKeyUnavailable, command, err, msg)
+		}
+		return "", fmt.Errorf("%w: %q failed: %v", ErrAPIKeyUnavailable, command, err)
+	}
+
+	apiKey, _, _ := strings.Cut(strings.TrimSpace(stdout.String()), "\n")
+	apiKey = strings.TrimSpace(apiKey)
+	if apiKey == "" {
+		return "", fmt.Errorf("%w: %q printed nothing", ErrAPIKeyUnavailable, command)
+	}
+	return apiKey, nil
+}
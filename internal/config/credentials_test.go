@@ -0,0 +1,100 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestExternalAPIKeySources(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(filepath.Join(dir, "prod.key"), []byte("file-api-key-123456789\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("BWH_TEST_KEY", "env-api-key-123456789")
+
+	manager, err := NewManager(configPath)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	instances := map[string]*Instance{
+		"env":  {APIKeyEnv: "BWH_TEST_KEY", VeID: "1"},
+		"file": {APIKeyFile: "prod.key", VeID: "2"},
+	}
+	if runtime.GOOS != "windows" {
+		instances["command"] = &Instance{APIKeyCommand: "echo command-api-key-123456789; echo second line", VeID: "3"}
+	}
+	for name, instance := range instances {
+		if err := manager.AddInstance(name, instance, false); err != nil {
+			t.Fatalf("AddInstance(%s) error = %v", name, err)
+		}
+	}
+
+	for name := range instances {
+		instance, resolved, err := manager.ResolveInstance(name)
+		if err != nil {
+			t.Fatalf("ResolveInstance(%s) error = %v", name, err)
+		}
+		if want := name + "-api-key-123456789"; instance.APIKey != want || resolved != name {
+			t.Errorf("ResolveInstance(%s) = %q, %s; want %q", name, instance.APIKey, resolved, want)
+		}
+	}
+
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "api_key:") {
+		t.Errorf("config file contains a resolved API key:\n%s", data)
+	}
+}
+
+func TestExternalAPIKeyErrors(t *testing.T) {
+	manager, err := NewManager(filepath.Join(t.TempDir(), "config.yaml"))
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+
+	tests := map[string]*Instance{
+		"unset-env":    {APIKeyEnv: "BWH_TEST_UNSET_KEY", VeID: "1"},
+		"missing-file": {APIKeyFile: "/nonexistent/bwh.key", VeID: "1"},
+	}
+	if runtime.GOOS != "windows" {
+		tests["failing-command"] = &Instance{APIKeyCommand: "echo locked >&2; exit 3", VeID: "1"}
+		tests["silent-command"] = &Instance{APIKeyCommand: "true", VeID: "1"}
+	}
+	for name, instance := range tests {
+		if err := manager.AddInstance(name, instance, false); err != nil {
+			t.Fatalf("AddInstance(%s) error = %v", name, err)
+		}
+		_, _, err := manager.ResolveInstance(name)
+		if !errors.Is(err, ErrAPIKeyUnavailable) {
+			t.Errorf("ResolveInstance(%s) error = %v, want %v", name, err, ErrAPIKeyUnavailable)
+		}
+		if err != nil && !strings.Contains(err.Error(), "instance "+name) {
+			t.Errorf("ResolveInstance(%s) error %q does not name the instance", name, err)
+		}
+	}
+
+	t.Setenv("BWH_TEST_SHORT_KEY", "short")
+	if err := manager.AddInstance("short", &Instance{APIKeyEnv: "BWH_TEST_SHORT_KEY", VeID: "1"}, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := manager.ResolveInstance("short"); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Errorf("ResolveInstance(short) error = %v, want %v", err, ErrInvalidAPIKey)
+	}
+}
+
+func TestValidateInstanceAPIKeySources(t *testing.T) {
+	both := &Instance{APIKey: "valid-api-key-123456789", APIKeyEnv: "BWH_KEY", VeID: "1"}
+	if err := validateInstance(both); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Errorf("validateInstance() with two sources error = %v, want %v", err, ErrInvalidAPIKey)
+	}
+	if err := validateInstance(&Instance{APIKeyCommand: "pass show bwh/prod", VeID: "1"}); err != nil {
+		t.Errorf("validateInstance() with api_key_command error = %v", err)
+	}
+}
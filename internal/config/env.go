@@ -0,0 +1,119 @@
+package config
+
+import (
+	"fmt"
+	"os"
+)
+
+// Environment variables that define an instance without a config file.
+const (
+	EnvAPIKey   = "BWH_API_KEY"
+	EnvVeID     = "BWH_VEID"
+	EnvEndpoint = "BWH_ENDPOINT"
+)
+
+// EnvInstanceName is the name of the instance built from BWH_API_KEY and
+// BWH_VEID.
+const EnvInstanceName = "env"
+
+// envInstance returns the instance defined by BWH_API_KEY, BWH_VEID and
+// BWH_ENDPOINT, or nil if neither BWH_API_KEY nor BWH_VEID is set.
+func envInstance() (*Instance, error) {
+	apiKey, veid := os.Getenv(EnvAPIKey), os.Getenv(EnvVeID)
+	switch {
+	case apiKey == "" && veid == "":
+		return nil, nil
+	case apiKey == "":
+		return nil, fmt.Errorf("%s is set but %s is not", EnvVeID, EnvAPIKey)
+	case veid == "":
+		return nil, fmt.Errorf("%s is set but %s is not", EnvAPIKey, EnvVeID)
+	}
+
+	instance := &Instance{
+		APIKey:      apiKey,
+		VeID:        veid,
+		Endpoint:    os.Getenv(EnvEndpoint),
+		Description: "from environment variables",
+	}
+	if err := validateInstance(instance); err != nil {
+		return nil, fmt.Errorf("invalid %s/%s: %w", EnvAPIKey, EnvVeID, err)
+	}
+	return instance, nil
+}
+
+// lookupInstance returns the named instance. A configured instance takes
+// precedence over the environment instance of the same name.
+func (m *Manager) lookupInstance(name string) (*Instance, error) {
+	if name == EnvInstanceName && !m.isConfigured(name) {
+		env, err := envInstance()
+		if err != nil {
+			return nil, err
+		}
+		if env != nil {
+			return env, nil
+		}
+	}
+	return m.GetInstance(name)
+}
+
+func (m *Manager) isConfigured(name string) bool {
+	_, ok := m.merged.Instances[name]
+	return ok
+}
+
+// ValueSources describes where the values of an instance come from. APIKey is
+// empty if the instance has no API key.
+type ValueSources struct {
+	APIKey   string
+	VeID     string
+	Endpoint string
+}
+
+// Sources reports where the values of the named instance come from.
+func (m *Manager) Sources(name string, instance *Instance) ValueSources {
+	if name == EnvInstanceName && !m.isConfigured(name) {
+		sources := ValueSources{
+			APIKey:   "environment variable " + EnvAPIKey,
+			VeID:     "environment variable " + EnvVeID,
+			Endpoint: "default",
+		}
+		if instance.Endpoint != "" {
+			sources.Endpoint = "environment variable " + EnvEndpoint
+		}
+		return sources
+	}
+
+	sources := ValueSources{
+		APIKey:   "config file " + m.layerDefining(name, func(i *Instance) bool { return i.apiKeySources() > 0 }),
+		VeID:     "config file " + m.layerDefining(name, func(i *Instance) bool { return i.VeID != "" }),
+		Endpoint: "default",
+	}
+	switch {
+	case instance.APIKeyEncrypted != "":
+		sources.APIKey += " (encrypted)"
+	case instance.APIKeyEnv != "":
+		sources.APIKey = "environment variable " + instance.APIKeyEnv
+	case instance.APIKeyFile != "":
+		sources.APIKey = "file " + instance.APIKeyFile
+	case instance.APIKeyCommand != "":
+		sources.APIKey = "command " + instance.APIKeyCommand
+	case instance.APIKey == "":
+		sources.APIKey = ""
+	}
+	if instance.Endpoint != "" {
+		sources.Endpoint = "config file " + m.layerDefining(name, func(i *Instance) bool { return i.Endpoint != "" })
+	}
+	return sources
+}
+
+// layerDefining returns the config file with the highest precedence whose
+// entry for the named instance satisfies set.
+func (m *Manager) layerDefining(name string, set func(*Instance) bool) string {
+	layers := m.layers()
+	for i := len(layers) - 1; i >= 0; i-- {
+		if instance, ok := layers[i].config.Instances[name]; ok && set(instance) {
+			return layers[i].path
+		}
+	}
+	return m.configPath
+}
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEnvInstance(t *testing.T) {
+	t.Setenv("BWH_INSTANCE", "")
+	t.Setenv(EnvAPIKey, "env-api-key-123456789")
+	t.Setenv(EnvVeID, "777")
+	t.Setenv(EnvEndpoint, "https://kiwivm.example.com/v1")
+
+	// Without a config file.
+	manager, err := NewManager(filepath.Join(t.TempDir(), "missing.yaml"))
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	instance, name, err := manager.ResolveInstance("")
+	if err != nil {
+		t.Fatalf("ResolveInstance() error = %v", err)
+	}
+	if name != EnvInstanceName || instance.APIKey != "env-api-key-123456789" || instance.VeID != "777" || instance.Endpoint != "https://kiwivm.example.com/v1" {
+		t.Errorf("ResolveInstance() = %+v, %s", instance, name)
+	}
+	sources := manager.Sources(name, instance)
+	if sources.APIKey != "environment variable BWH_API_KEY" || sources.Endpoint != "environment variable BWH_ENDPOINT" {
+		t.Errorf("Sources() = %+v", sources)
+	}
+
+	// Environment credentials take precedence over the default instance,
+	// but not over an explicit or BWH_INSTANCE selection.
+	if err := manager.AddInstance("prod", &Instance{APIKey: "prod-api-key-123456789", VeID: "1"}, true); err != nil {
+		t.Fatalf("AddInstance() error = %v", err)
+	}
+	if _, name, _ := manager.ResolveInstance(""); name != EnvInstanceName {
+		t.Errorf("ResolveInstance() with default instance = %s, want %s", name, EnvInstanceName)
+	}
+	if _, name, _ := manager.ResolveInstance("prod"); name != "prod" {
+		t.Errorf("ResolveInstance(prod) = %s, want prod", name)
+	}
+	t.Setenv("BWH_INSTANCE", "prod")
+	if _, name, _ := manager.ResolveInstance(""); name != "prod" {
+		t.Errorf("ResolveInstance() with BWH_INSTANCE = %s, want prod", name)
+	}
+	t.Setenv("BWH_INSTANCE", EnvInstanceName)
+	if _, name, _ := manager.ResolveInstance(""); name != EnvInstanceName {
+		t.Errorf("ResolveInstance() with BWH_INSTANCE=env = %s, want %s", name, EnvInstanceName)
+	}
+
+	prod, _ := manager.GetInstance("prod")
+	sources = manager.Sources("prod", prod)
+	if !strings.HasPrefix(sources.APIKey, "config file ") || sources.Endpoint != "default" {
+		t.Errorf("Sources(prod) = %+v", sources)
+	}
+}
+
+func TestEnvInstanceIncomplete(t *testing.T) {
+	t.Setenv(EnvAPIKey, "env-api-key-123456789")
+	t.Setenv(EnvVeID, "")
+
+	manager, err := NewManager(filepath.Join(t.TempDir(), "missing.yaml"))
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	_, _, err = manager.ResolveInstance("")
+	if err == nil || !strings.Contains(err.Error(), EnvVeID) {
+		t.Errorf("ResolveInstance() error = %v, want missing %s", err, EnvVeID)
+	}
+
+	t.Setenv(EnvAPIKey, "")
+	if _, _, err := manager.ResolveInstance(""); !errors.Is(err, ErrNoInstances) {
+		t.Errorf("ResolveInstance() without credentials error = %v, want %v", err, ErrNoInstances)
+	}
+}
@@ -0,0 +1,277 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProjectConfigName is the name of the project config file looked up in the
+// working directory and its parents.
+const ProjectConfigName = "bwh.yaml"
+
+// NoProjectConfigEnv disables the project config lookup when set to a
+// non-empty value.
+const NoProjectConfigEnv = "BWH_NO_PROJECT_CONFIG"
+
+// configLayer is a read-only config file merged with the personal config.
+type configLayer struct {
+	path   string
+	config *Config
+}
+
+// loadLayers loads the project configs and the files included by them and by
+// the personal config. Layers below the personal config have lower
+// precedence, layers above it higher.
+func (m *Manager) loadLayers() error {
+	seen := map[string]bool{absPath(m.configPath): true}
+
+	below, err := loadIncludes(m.config.Include, filepath.Dir(m.configPath), seen)
+	if err != nil {
+		return err
+	}
+	trusted := map[string]*Instance{}
+	for _, layer := range append(below, configLayer{path: m.configPath, config: m.config}) {
+		for name, instance := range layer.config.Instances {
+			if base, ok := trusted[name]; ok {
+				instance = mergeInstance(base, instance)
+			}
+			trusted[name] = instance
+		}
+	}
+
+	var above []configLayer
+	if os.Getenv(NoProjectConfigEnv) == "" {
+		wd, err := os.Getwd()
+		if err == nil {
+			for _, path := range findProjectConfigs(wd) {
+				if seen[path] {
+					continue
+				}
+				layers, err := loadLayerFile(path, seen)
+				if err != nil {
+					return err
+				}
+				// Project configs and the files they include come with
+				// whatever repository is checked out.
+				for _, layer := range layers {
+					if err := checkUntrustedLayer(layer.config, trusted); err != nil {
+						return fmt.Errorf("%s: %w", layer.path, err)
+					}
+				}
+				above = append(above, layers...)
+			}
+		}
+	}
+
+	m.below, m.above = below, above
+	m.merge()
+	return nil
+}
+
+// findProjectConfigs returns the project config files in dir and its
+// parents, farthest first.
+func findProjectConfigs(dir string) []string {
+	var paths []string
+	for {
+		path := filepath.Join(dir, ProjectConfigName)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			paths = append([]string{path}, paths...)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return paths
+		}
+		dir = parent
+	}
+}
+
+// loadLayerFile loads path and, before it, the files it includes.
+func loadLayerFile(path string, seen map[string]bool) ([]configLayer, error) {
+	seen[path] = true
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config %s: %w", path, err)
+	}
+	// Shared files may be checked in, so they are only migrated in memory.
+	upgraded, _, err := upgradeConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if upgraded != nil {
+		data = upgraded
+	}
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+
+	layers, err := loadIncludes(cfg.Include, filepath.Dir(path), seen)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return append(layers, configLayer{path: path, config: &cfg}), nil
+}
+
+// checkUntrustedLayer rejects the settings a project layer may not carry,
+// given the instances of the personal config and the files it includes. An
+// API key source could run a command or read a file, an endpoint or a
+// different VEID would send the personal API key elsewhere, trusted CIDRs
+// would hide logins from 'bwh audit check', and a notifier could run a
+// command or post alerts anywhere.
+func checkUntrustedLayer(cfg *Config, trusted map[string]*Instance) error {
+	if len(cfg.Notifiers) > 0 {
+		return errors.New("notifiers are only read from the personal config and the files it includes")
+	}
+	for _, name := range slices.Sorted(maps.Keys(cfg.Instances)) {
+		instance := cfg.Instances[name]
+		var fields []string
+		for field, value := range map[string]string{
+			"api_key":           instance.APIKey,
+			"api_key_encrypted": instance.APIKeyEncrypted,
+			"api_key_env":       instance.APIKeyEnv,
+			"api_key_file":      instance.APIKeyFile,
+			"api_key_command":   instance.APIKeyCommand,
+			"endpoint":          instance.Endpoint,
+		} {
+			if value != "" {
+				fields = append(fields, field)
+			}
+		}
+		if len(instance.TrustedCIDRs) > 0 {
+			fields = append(fields, "trusted_cidrs")
+		}
+		if len(fields) > 0 {
+			slices.Sort(fields)
+			return fmt.Errorf("instance %s sets %s; API keys, endpoints and trusted CIDRs are only read from the personal config and the files it includes", name, strings.Join(fields, ", "))
+		}
+		// A project may list the VEID of an instance it introduces, but not
+		// point a personal instance at another VPS.
+		if base, ok := trusted[name]; ok && instance.VeID != "" && instance.VeID != base.VeID {
+			return fmt.Errorf("instance %s sets veid %s; the VEID of an instance in the personal config can only be changed there", name, instance.VeID)
+		}
+	}
+	return nil
+}
+
+func loadIncludes(includes []string, dir string, seen map[string]bool) ([]configLayer, error) {
+	var layers []configLayer
+	for _, include := range includes {
+		path := absPath(expandPath(include, dir))
+		if seen[path] {
+			continue
+		}
+		included, err := loadLayerFile(path, seen)
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, included...)
+	}
+	return layers, nil
+}
+
+// merge rebuilds the merged view of all layers.
+func (m *Manager) merge() {
+	merged := &Config{
+		Instances: map[string]*Instance{},
+		Notifiers: map[string]*Notifier{},
+		Tags:      map[string]string{},
+	}
+	origins := map[string][]string{}
+
+	for _, layer := range m.layers() {
+		if layer.config.DefaultInstance != "" {
+			merged.DefaultInstance = layer.config.DefaultInstance
+		}
+		for name, notifier := range layer.config.Notifiers {
+			merged.Notifiers[name] = notifier
+		}
+		for tag, description := range layer.config.Tags {
+			merged.Tags[tag] = description
+		}
+		dir := filepath.Dir(layer.path)
+		for name, instance := range layer.config.Instances {
+			over := *instance
+			if over.APIKeyFile != "" {
+				over.APIKeyFile = expandPath(over.APIKeyFile, dir)
+			}
+			if base, ok := merged.Instances[name]; ok {
+				merged.Instances[name] = mergeInstance(base, &over)
+			} else {
+				merged.Instances[name] = &over
+			}
+			origins[name] = append([]string{layer.path}, origins[name]...)
+		}
+	}
+
+	merged.Vault = m.config.Vault
+	m.merged, m.origins = merged, origins
+}
+
+// layers returns all config files, lowest precedence first.
+func (m *Manager) layers() []configLayer {
+	layers := append([]configLayer{}, m.below...)
+	layers = append(layers, configLayer{path: m.configPath, config: m.config})
+	return append(layers, m.above...)
+}
+
+// mergeInstance overlays the values set in over on base. The API key sources
+// are replaced together, so that an instance never ends up with two.
+func mergeInstance(base, over *Instance) *Instance {
+	merged := *base
+	if over.apiKeySources() > 0 {
+		merged.APIKey = over.APIKey
+		merged.APIKeyEncrypted = over.APIKeyEncrypted
+		merged.APIKeyEnv = over.APIKeyEnv
+		merged.APIKeyFile = over.APIKeyFile
+		merged.APIKeyCommand = over.APIKeyCommand
+	}
+	if over.VeID != "" {
+		merged.VeID = over.VeID
+	}
+	if over.Description != "" {
+		merged.Description = over.Description
+	}
+	if over.Endpoint != "" {
+		merged.Endpoint = over.Endpoint
+	}
+	if len(over.Tags) > 0 {
+		merged.Tags = over.Tags
+	}
+	if len(over.TrustedCIDRs) > 0 {
+		merged.TrustedCIDRs = over.TrustedCIDRs
+	}
+	return &merged
+}
+
+// InstanceOrigins returns the config files that define the named instance,
+// highest precedence first.
+func (m *Manager) InstanceOrigins(name string) []string {
+	return m.origins[name]
+}
+
+// expandPath expands a leading ~ and makes a relative path relative to dir.
+func expandPath(path, dir string) string {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if homeDir, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(homeDir, rest)
+		}
+	}
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
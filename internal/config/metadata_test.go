@@ -0,0 +1,37 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestMetadataCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), MetadataFileName)
+	cache, err := LoadMetadataCache(path)
+	if err != nil {
+		t.Fatalf("LoadMetadataCache() of a missing file error = %v", err)
+	}
+
+	prod := &Instance{VeID: "1"}
+	cache.Set("prod", &Metadata{VeID: "1", Hostname: "prod.example.com", IPAddresses: []string{"192.0.2.1"}})
+	cache.Set("old", &Metadata{VeID: "2"})
+	cache.Prune(map[string]*Instance{"prod": prod})
+	if err := cache.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	loaded, err := LoadMetadataCache(path)
+	if err != nil {
+		t.Fatalf("LoadMetadataCache() error = %v", err)
+	}
+	if metadata := loaded.Get("prod", prod); metadata == nil || metadata.Hostname != "prod.example.com" {
+		t.Errorf("Get(prod) = %+v", metadata)
+	}
+	if metadata := loaded.Get("old", &Instance{VeID: "2"}); metadata != nil {
+		t.Errorf("Get(old) after Prune = %+v, want nil", metadata)
+	}
+	// Metadata of a different VEID is stale.
+	if metadata := loaded.Get("prod", &Instance{VeID: "9"}); metadata != nil {
+		t.Errorf("Get(prod) with a changed VEID = %+v, want nil", metadata)
+	}
+}
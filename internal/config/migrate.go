@@ -0,0 +1,99 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CurrentVersion is the config schema version written by this build.
+const CurrentVersion = 1
+
+// ErrUnsupportedVersion is returned for config files written by a newer bwh.
+var ErrUnsupportedVersion = errors.New("unsupported config version")
+
+// migration upgrades the top-level mapping of a config file by one version.
+type migration struct {
+	description string
+	apply       func(root *yaml.Node) error
+}
+
+// migrations[i] upgrades version i to version i+1. Files without a version
+// key are version 0.
+var migrations = []migration{
+	{
+		// Version 1 is the first versioned schema and only adds the key.
+		description: "add the version key",
+		apply:       func(*yaml.Node) error { return nil },
+	},
+}
+
+// upgradeConfig migrates the config file data to CurrentVersion. It returns
+// the version data was written with and, if any migration ran, the upgraded
+// data. Comments and key order are kept.
+func upgradeConfig(data []byte) (upgraded []byte, from int, err error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, 0, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		// An empty file has nothing to migrate.
+		return nil, CurrentVersion, nil
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, 0, errors.New("failed to parse config file: top level must be a mapping")
+	}
+
+	if value := mappingValue(root, "version"); value != nil {
+		from, err = strconv.Atoi(value.Value)
+		if err != nil || from < 0 {
+			return nil, 0, fmt.Errorf("invalid config version %q", value.Value)
+		}
+	}
+	if from > CurrentVersion {
+		return nil, from, fmt.Errorf("%w: the file has version %d, this bwh supports up to %d; upgrade bwh with 'bwh update'", ErrUnsupportedVersion, from, CurrentVersion)
+	}
+	if from == CurrentVersion {
+		return nil, from, nil
+	}
+
+	for version := from; version < CurrentVersion; version++ {
+		if err := migrations[version].apply(root); err != nil {
+			return nil, from, fmt.Errorf("failed to migrate config from version %d (%s): %w", version, migrations[version].description, err)
+		}
+	}
+	setVersion(root, CurrentVersion)
+
+	upgraded, err = yaml.Marshal(&doc)
+	if err != nil {
+		return nil, from, fmt.Errorf("failed to marshal migrated config: %w", err)
+	}
+	return upgraded, from, nil
+}
+
+// mappingValue returns the value of key in a mapping node, or nil.
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// setVersion sets the version key, adding it at the top if it is missing.
+func setVersion(root *yaml.Node, version int) {
+	value := strconv.Itoa(version)
+	if node := mappingValue(root, "version"); node != nil {
+		node.Value = value
+		node.Tag = "!!int"
+		return
+	}
+	root.Content = append([]*yaml.Node{
+		{Kind: yaml.ScalarNode, Tag: "!!str", Value: "version"},
+		{Kind: yaml.ScalarNode, Tag: "!!int", Value: value},
+	}, root.Content...)
+}
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMigrateUnversionedConfig(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	original := `# personal servers
+default_instance: prod
+instances:
+  prod:
+    api_key: prod-api-key-123456789 # from KiwiVM
+    veid: "1"
+`
+	writeFile(t, configPath, original)
+
+	manager, err := NewManager(configPath)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	from, backupPath, migrated := manager.Migration()
+	if !migrated || from != 0 || backupPath != configPath+".v0.bak" {
+		t.Fatalf("Migration() = %d, %q, %v", from, backupPath, migrated)
+	}
+
+	backup, err := os.ReadFile(backupPath)
+	if err != nil || string(backup) != original {
+		t.Errorf("backup = %q, %v; want the original file", backup, err)
+	}
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"version: 1", "# personal servers", "# from KiwiVM"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("migrated config does not contain %q:\n%s", want, data)
+		}
+	}
+	if instance, _, err := manager.ResolveInstance(""); err != nil || instance.APIKey != "prod-api-key-123456789" {
+		t.Errorf("ResolveInstance() after migration = %+v, %v", instance, err)
+	}
+
+	// Loading the upgraded file again does not migrate.
+	again, err := NewManager(configPath)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	if _, _, migrated := again.Migration(); migrated {
+		t.Errorf("Migration() on a current file = true")
+	}
+}
+
+func TestNewerConfigVersion(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	writeFile(t, configPath, "version: 99\ninstances: {}\n")
+
+	if _, err := NewManager(configPath); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("NewManager() error = %v, want %v", err, ErrUnsupportedVersion)
+	}
+}
+
+func TestSaveWritesVersion(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	manager, err := NewManager(configPath)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	if err := manager.AddInstance("prod", &Instance{APIKey: "prod-api-key-123456789", VeID: "1"}, true); err != nil {
+		t.Fatalf("AddInstance() error = %v", err)
+	}
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), "version: 1\n") {
+		t.Errorf("saved config does not start with the version:\n%s", data)
+	}
+}
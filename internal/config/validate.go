@@ -0,0 +1,190 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"net/url"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Issue severities reported by Validate.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Issue is a problem found by Validate.
+type Issue struct {
+	Severity string
+	// File is the config file the issue was found in, if known.
+	File    string
+	Message string
+}
+
+// Validate checks all loaded config files for unknown keys and the merged
+// configuration for invalid instances and endpoints, VEIDs used by more than
+// one instance, and tags that are declared but unused or used but
+// undeclared.
+func (m *Manager) Validate() []Issue {
+	var issues []Issue
+	add := func(severity, file, format string, args ...any) {
+		issues = append(issues, Issue{Severity: severity, File: file, Message: fmt.Sprintf(format, args...)})
+	}
+
+	for _, layer := range m.layers() {
+		data, err := os.ReadFile(layer.path)
+		if err != nil {
+			if layer.path != m.configPath || !os.IsNotExist(err) {
+				add(SeverityError, layer.path, "%v", err)
+			}
+			continue
+		}
+		var doc yaml.Node
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			add(SeverityError, layer.path, "%v", err)
+			continue
+		}
+		for _, key := range unknownKeys(&doc, reflect.TypeFor[Config](), "") {
+			add(SeverityError, layer.path, "line %d: unknown key %s", key.line, key.path)
+		}
+	}
+
+	instances := m.merged.Instances
+	names := slices.Sorted(maps.Keys(instances))
+
+	if def := m.merged.DefaultInstance; def != "" && instances[def] == nil {
+		add(SeverityError, "", "default_instance %s is not defined", def)
+	}
+
+	veids := map[string][]string{}
+	used := map[string]bool{}
+	for _, name := range names {
+		instance := instances[name]
+		if instance.apiKeySources() == 0 {
+			add(SeverityWarning, m.layerDefining(name, func(*Instance) bool { return true }), "instance %s has no API key", name)
+		} else if err := validateInstance(instance); err != nil {
+			add(SeverityError, "", "instance %s: %v", name, err)
+		}
+
+		if instance.Endpoint != "" {
+			if err := validateEndpoint(instance.Endpoint); err != nil {
+				file := m.layerDefining(name, func(i *Instance) bool { return i.Endpoint != "" })
+				add(SeverityError, file, "instance %s: invalid endpoint %q: %v", name, instance.Endpoint, err)
+			}
+		}
+
+		if instance.VeID != "" {
+			veids[instance.VeID] = append(veids[instance.VeID], name)
+		}
+		for _, tag := range instance.Tags {
+			used[tag] = true
+			if len(m.merged.Tags) > 0 && !hasKey(m.merged.Tags, tag) {
+				add(SeverityWarning, "", "instance %s uses undeclared tag %q", name, tag)
+			}
+		}
+	}
+
+	for _, veid := range slices.Sorted(maps.Keys(veids)) {
+		if shared := veids[veid]; len(shared) > 1 {
+			add(SeverityWarning, "", "VEID %s is used by %d instances: %s", veid, len(shared), strings.Join(shared, ", "))
+		}
+	}
+	for _, tag := range slices.Sorted(maps.Keys(m.merged.Tags)) {
+		if !used[tag] {
+			add(SeverityWarning, "", "tag %q is declared but not used by any instance", tag)
+		}
+	}
+
+	return issues
+}
+
+func hasKey(m map[string]string, key string) bool {
+	_, ok := m[key]
+	return ok
+}
+
+func validateEndpoint(endpoint string) error {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("scheme must be http or https")
+	}
+	if u.Host == "" {
+		return fmt.Errorf("missing host")
+	}
+	return nil
+}
+
+type unknownKey struct {
+	path string
+	line int
+}
+
+// unknownKeys returns the mapping keys in node that do not match a yaml field
+// of t.
+func unknownKeys(node *yaml.Node, t reflect.Type, path string) []unknownKey {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	var keys []unknownKey
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			keys = append(keys, unknownKeys(child, t, path)...)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			keyPath := key.Value
+			if path != "" {
+				keyPath = path + "." + key.Value
+			}
+			switch t.Kind() {
+			case reflect.Struct:
+				field, ok := yamlFields(t)[key.Value]
+				if !ok {
+					keys = append(keys, unknownKey{path: keyPath, line: key.Line})
+					continue
+				}
+				keys = append(keys, unknownKeys(value, field, keyPath)...)
+			case reflect.Map:
+				keys = append(keys, unknownKeys(value, t.Elem(), keyPath)...)
+			}
+		}
+	case yaml.SequenceNode:
+		if t.Kind() == reflect.Slice {
+			for i, child := range node.Content {
+				keys = append(keys, unknownKeys(child, t.Elem(), fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+	}
+	return keys
+}
+
+// yamlFields maps the yaml keys of struct type t to the field types.
+func yamlFields(t reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		switch name {
+		case "-":
+			continue
+		case "":
+			name = strings.ToLower(field.Name)
+		}
+		fields[name] = field.Type
+	}
+	return fields
+}
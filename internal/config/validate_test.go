@@ -0,0 +1,91 @@
+package config
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	t.Setenv(NoProjectConfigEnv, "1")
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	writeFile(t, configPath, `version: 1
+default_instance: missing
+tags:
+  web: web servers
+  db: databases
+instances:
+  a:
+    api_key: a-api-key-123456789
+    veid: "100"
+    tags: [web, wbe]
+    endpoint: ftp://kiwivm.example.com
+  b:
+    api_key: b-api-key-123456789
+    veid: "100"
+    veidd: "200"
+  c:
+    veid: "300"
+notifiers:
+  ops:
+    type: webhook
+    urll: https://example.com/hook
+`)
+
+	manager, err := NewManager(configPath)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+
+	want := map[string]string{
+		"line 15: unknown key instances.b.veidd":            SeverityError,
+		"line 21: unknown key notifiers.ops.urll":           SeverityError,
+		"default_instance missing is not defined":           SeverityError,
+		`instance a: invalid endpoint "ftp://`:              SeverityError,
+		"instance c has no API key":                         SeverityWarning,
+		`instance a uses undeclared tag "wbe"`:              SeverityWarning,
+		"VEID 100 is used by 2 instances: a, b":             SeverityWarning,
+		`tag "db" is declared but not used by any instance`: SeverityWarning,
+	}
+	issues := manager.Validate()
+	for prefix, severity := range want {
+		found := false
+		for _, issue := range issues {
+			if strings.HasPrefix(issue.Message, prefix) {
+				found = true
+				if issue.Severity != severity {
+					t.Errorf("issue %q severity = %s, want %s", issue.Message, issue.Severity, severity)
+				}
+			}
+		}
+		if !found {
+			t.Errorf("missing issue %q in %+v", prefix, issues)
+		}
+	}
+	if len(issues) != len(want) {
+		t.Errorf("Validate() returned %d issues, want %d: %+v", len(issues), len(want), issues)
+	}
+}
+
+func TestValidateClean(t *testing.T) {
+	t.Setenv(NoProjectConfigEnv, "1")
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	writeFile(t, configPath, `version: 1
+tags:
+  web: web servers
+instances:
+  a:
+    api_key: a-api-key-123456789
+    veid: "100"
+    tags: [web]
+    endpoint: https://api.64clouds.com/v1
+`)
+
+	manager, err := NewManager(configPath)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	if issues := manager.Validate(); len(issues) != 0 {
+		t.Errorf("Validate() = %+v, want no issues", issues)
+	}
+}
@@ -0,0 +1,308 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+var (
+	ErrVaultLocked      = errors.New("config vault is locked")
+	ErrWrongPassphrase  = errors.New("wrong vault passphrase")
+	ErrVaultEnabled     = errors.New("config vault is already enabled")
+	ErrVaultNotEnabled  = errors.New("config vault is not enabled")
+	ErrInvalidSealedKey = errors.New("invalid encrypted API key")
+)
+
+const (
+	vaultKDF       = "scrypt"
+	vaultKeyLength = 32
+	vaultSaltSize  = 16
+	// sealedPrefix marks the format of encrypted values.
+	sealedPrefix = "v1:"
+	// vaultCheckText is sealed into Vault.Check to verify a key.
+	vaultCheckText = "bwh-vault"
+)
+
+// vaultScryptN is the scrypt cost parameter for new vaults; with r = 8 it
+// uses 32 MiB of memory. Tests lower it.
+var vaultScryptN = 1 << 15
+
+const (
+	vaultScryptR = 8
+	vaultScryptP = 1
+	// vaultMaxScryptN bounds the cost a config file can ask for, so an edited
+	// file cannot make unlocking exhaust memory.
+	vaultMaxScryptN = 1 << 20
+)
+
+// Vault holds the parameters of the passphrase-derived key that encrypts API
+// keys in the config file. The key is derived with scrypt and API keys are
+// sealed with AES-256-GCM.
+type Vault struct {
+	KDF  string `yaml:"kdf"`
+	N    int    `yaml:"n"`
+	R    int    `yaml:"r"`
+	P    int    `yaml:"p"`
+	Salt string `yaml:"salt"`
+	// Check is a known value sealed with the key, used to tell a wrong
+	// passphrase from a corrupt API key.
+	Check string `yaml:"check"`
+}
+
+// DeriveKey derives the vault key from passphrase and verifies it.
+func (v *Vault) DeriveKey(passphrase string) ([]byte, error) {
+	salt, err := v.validate()
+	if err != nil {
+		return nil, err
+	}
+	key, err := scrypt.Key([]byte(passphrase), salt, v.N, v.R, v.P, vaultKeyLength)
+	if err != nil {
+		return nil, err
+	}
+	if err := v.VerifyKey(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// validate checks the KDF parameters and returns the decoded salt.
+func (v *Vault) validate() ([]byte, error) {
+	if v.KDF != vaultKDF {
+		return nil, fmt.Errorf("unsupported vault kdf %q (want %s)", v.KDF, vaultKDF)
+	}
+	salt, err := base64.StdEncoding.DecodeString(v.Salt)
+	if err != nil || len(salt) == 0 {
+		return nil, errors.New("invalid vault parameters: bad salt")
+	}
+	if v.N < 2 || v.N > vaultMaxScryptN || v.N&(v.N-1) != 0 || v.R <= 0 || v.P <= 0 || v.R*v.P > 64 {
+		return nil, fmt.Errorf("invalid vault parameters: scrypt n=%d r=%d p=%d", v.N, v.R, v.P)
+	}
+	return salt, nil
+}
+
+// VerifyKey checks that key opens this vault.
+func (v *Vault) VerifyKey(key []byte) error {
+	check, err := openValue(key, "", v.Check)
+	if err != nil || subtle.ConstantTimeCompare([]byte(check), []byte(vaultCheckText)) != 1 {
+		return ErrWrongPassphrase
+	}
+	return nil
+}
+
+// newVault creates vault parameters with a fresh salt and returns them with
+// the derived key.
+func newVault(passphrase string) (*Vault, []byte, error) {
+	if passphrase == "" {
+		return nil, nil, errors.New("vault passphrase cannot be empty")
+	}
+	salt := make([]byte, vaultSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, nil, err
+	}
+	key, err := scrypt.Key([]byte(passphrase), salt, vaultScryptN, vaultScryptR, vaultScryptP, vaultKeyLength)
+	if err != nil {
+		return nil, nil, err
+	}
+	check, err := sealValue(key, "", vaultCheckText)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &Vault{
+		KDF:   vaultKDF,
+		N:     vaultScryptN,
+		R:     vaultScryptR,
+		P:     vaultScryptP,
+		Salt:  base64.StdEncoding.EncodeToString(salt),
+		Check: check,
+	}, key, nil
+}
+
+// sealValue encrypts plaintext as "v1:" followed by the base64 encoded nonce
+// and ciphertext. The value is bound to the instance name, so that it cannot
+// be moved to another instance in the config file; the vault check uses an
+// empty name.
+func sealValue(key []byte, name, plaintext string) (string, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), []byte(name))
+	return sealedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func openValue(key []byte, name, value string) (string, error) {
+	encoded, ok := strings.CutPrefix(value, sealedPrefix)
+	if !ok {
+		return "", ErrInvalidSealedKey
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", ErrInvalidSealedKey
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return "", ErrInvalidSealedKey
+	}
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(name))
+	if err != nil {
+		return "", ErrInvalidSealedKey
+	}
+	return string(plaintext), nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// VaultKeyFunc supplies the key of a locked vault, for example by prompting
+// for the passphrase and calling Vault.DeriveKey.
+type VaultKeyFunc func(v *Vault) ([]byte, error)
+
+// SetVaultKeyFunc sets how the manager unlocks the vault. The key is
+// requested at most once per manager, the first time an encrypted API key is
+// needed.
+func (m *Manager) SetVaultKeyFunc(fn VaultKeyFunc) {
+	m.vaultKeyFunc = fn
+}
+
+// VaultEnabled reports whether API keys in the config file are encrypted.
+func (m *Manager) VaultEnabled() bool {
+	return m.config.Vault != nil
+}
+
+// Vault returns the vault parameters, or nil if the vault is not enabled.
+func (m *Manager) Vault() *Vault {
+	return m.config.Vault
+}
+
+// vaultKey returns the key of the vault, unlocking it if needed.
+func (m *Manager) vaultKey() ([]byte, error) {
+	if m.config.Vault == nil {
+		return nil, ErrVaultNotEnabled
+	}
+	if m.vaultKeyCache != nil {
+		return m.vaultKeyCache, nil
+	}
+	if m.vaultKeyFunc == nil {
+		return nil, ErrVaultLocked
+	}
+	key, err := m.vaultKeyFunc(m.config.Vault)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.config.Vault.VerifyKey(key); err != nil {
+		return nil, err
+	}
+	m.vaultKeyCache = key
+	return key, nil
+}
+
+// decryptInstance returns a copy of instance with its API key decrypted.
+func (m *Manager) decryptInstance(name string, instance *Instance) (*Instance, error) {
+	key, err := m.vaultKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt API key of instance %s: %w", name, err)
+	}
+	apiKey, err := openValue(key, name, instance.APIKeyEncrypted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt API key of instance %s: %w", name, err)
+	}
+	opened := *instance
+	opened.APIKey = apiKey
+	opened.APIKeyEncrypted = ""
+	return &opened, nil
+}
+
+// sealInstance returns a copy of instance with its API key encrypted.
+func (m *Manager) sealInstance(name string, instance *Instance) (*Instance, error) {
+	key, err := m.vaultKey()
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := sealValue(key, name, instance.APIKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt API key of instance %s: %w", name, err)
+	}
+	copied := *instance
+	copied.APIKey = ""
+	copied.APIKeyEncrypted = sealed
+	return &copied, nil
+}
+
+// EncryptVault enables the vault with passphrase and encrypts every plain
+// API key in the config file.
+func (m *Manager) EncryptVault(passphrase string) (int, error) {
+	if m.config.Vault != nil {
+		return 0, ErrVaultEnabled
+	}
+	vault, key, err := newVault(passphrase)
+	if err != nil {
+		return 0, err
+	}
+
+	sealed := map[string]*Instance{}
+	for _, name := range slices.Sorted(maps.Keys(m.config.Instances)) {
+		instance := m.config.Instances[name]
+		if instance.APIKey == "" {
+			continue
+		}
+		value, err := sealValue(key, name, instance.APIKey)
+		if err != nil {
+			return 0, fmt.Errorf("failed to encrypt API key of instance %s: %w", name, err)
+		}
+		copied := *instance
+		copied.APIKey = ""
+		copied.APIKeyEncrypted = value
+		sealed[name] = &copied
+	}
+
+	m.config.Vault = vault
+	maps.Copy(m.config.Instances, sealed)
+	m.vaultKeyCache = key
+	return len(sealed), m.Save()
+}
+
+// DecryptVault decrypts every API key and disables the vault.
+func (m *Manager) DecryptVault() (int, error) {
+	if m.config.Vault == nil {
+		return 0, ErrVaultNotEnabled
+	}
+
+	opened := map[string]*Instance{}
+	for name, instance := range m.config.Instances {
+		if instance.APIKeyEncrypted == "" {
+			continue
+		}
+		plain, err := m.decryptInstance(name, instance)
+		if err != nil {
+			return 0, err
+		}
+		opened[name] = plain
+	}
+
+	maps.Copy(m.config.Instances, opened)
+	m.config.Vault = nil
+	m.vaultKeyCache = nil
+	return len(opened), m.Save()
+}
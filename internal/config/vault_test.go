@@ -0,0 +1,198 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVaultEncryptDecrypt(t *testing.T) {
+	defer func(n int) { vaultScryptN = n }(vaultScryptN)
+	vaultScryptN = 1 << 10
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+
+	manager, err := NewManager(configPath)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	if err := manager.AddInstance("plain", &Instance{APIKey: "plain-api-key-123456789", VeID: "1"}, true); err != nil {
+		t.Fatalf("AddInstance() error = %v", err)
+	}
+
+	count, err := manager.EncryptVault("correct horse")
+	if err != nil {
+		t.Fatalf("EncryptVault() error = %v", err)
+	}
+	if count != 1 {
+		t.Errorf("EncryptVault() count = %d, want 1", count)
+	}
+	if _, err := manager.EncryptVault("again"); !errors.Is(err, ErrVaultEnabled) {
+		t.Errorf("EncryptVault() twice error = %v, want %v", err, ErrVaultEnabled)
+	}
+
+	// New instances are sealed with the cached key.
+	if err := manager.AddInstance("added", &Instance{APIKey: "added-api-key-123456789", VeID: "2"}, false); err != nil {
+		t.Fatalf("AddInstance() error = %v", err)
+	}
+
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"plain-api-key", "added-api-key"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("config file contains plain API key %s:\n%s", secret, data)
+		}
+	}
+
+	// A fresh manager has to unlock the vault.
+	locked, err := NewManager(configPath)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	if _, _, err := locked.ResolveInstance("plain"); !errors.Is(err, ErrVaultLocked) {
+		t.Errorf("ResolveInstance() without key func error = %v, want %v", err, ErrVaultLocked)
+	}
+
+	locked.SetVaultKeyFunc(func(v *Vault) ([]byte, error) { return v.DeriveKey("wrong") })
+	if _, err := locked.OpenInstance("plain"); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("OpenInstance() with wrong passphrase error = %v, want %v", err, ErrWrongPassphrase)
+	}
+
+	calls := 0
+	unlocked, err := NewManager(configPath)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	unlocked.SetVaultKeyFunc(func(v *Vault) ([]byte, error) {
+		calls++
+		return v.DeriveKey("correct horse")
+	})
+	for name, want := range map[string]string{"plain": "plain-api-key-123456789", "added": "added-api-key-123456789"} {
+		instance, err := unlocked.OpenInstance(name)
+		if err != nil {
+			t.Fatalf("OpenInstance(%s) error = %v", name, err)
+		}
+		if instance.APIKey != want {
+			t.Errorf("OpenInstance(%s) API key = %q, want %q", name, instance.APIKey, want)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("vault key requested %d times, want 1", calls)
+	}
+	if raw, _ := unlocked.GetInstance("plain"); raw.APIKey != "" {
+		t.Errorf("OpenInstance() modified the stored instance")
+	}
+
+	count, err = unlocked.DecryptVault()
+	if err != nil {
+		t.Fatalf("DecryptVault() error = %v", err)
+	}
+	if count != 2 {
+		t.Errorf("DecryptVault() count = %d, want 2", count)
+	}
+
+	plain, err := NewManager(configPath)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	if plain.VaultEnabled() {
+		t.Errorf("VaultEnabled() after decrypt = true")
+	}
+	instance, _, err := plain.ResolveInstance("added")
+	if err != nil || instance.APIKey != "added-api-key-123456789" {
+		t.Errorf("ResolveInstance() after decrypt = %+v, %v", instance, err)
+	}
+}
+
+func TestVaultRejectsBadParameters(t *testing.T) {
+	defer func(n int) { vaultScryptN = n }(vaultScryptN)
+	vaultScryptN = 1 << 10
+	vault, _, err := newVault("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vault.KDF != "scrypt" || vault.N != 1<<10 || vault.R != 8 || vault.P != 1 {
+		t.Fatalf("newVault() = %+v, want scrypt parameters in the header", vault)
+	}
+	if _, err := vault.DeriveKey("correct horse"); err != nil {
+		t.Fatalf("DeriveKey() error = %v", err)
+	}
+
+	tests := map[string]func(v *Vault){
+		"unsupported vault kdf": func(v *Vault) { v.KDF = "pbkdf2-sha256" },
+		"bad salt":              func(v *Vault) { v.Salt = "" },
+		"n=1000":                func(v *Vault) { v.N = 1000 },
+		"n=2097152":             func(v *Vault) { v.N = 1 << 21 },
+		"r=0":                   func(v *Vault) { v.R = 0 },
+	}
+	for want, mutate := range tests {
+		bad := *vault
+		mutate(&bad)
+		if _, err := bad.DeriveKey("correct horse"); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("DeriveKey() error = %v, want %q", err, want)
+		}
+	}
+}
+
+func TestOpenValueRejectsTampering(t *testing.T) {
+	key := make([]byte, vaultKeyLength)
+	sealed, err := sealValue(key, "prod", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := openValue(key, "prod", sealed); err != nil || got != "secret" {
+		t.Fatalf("openValue() = %q, %v", got, err)
+	}
+
+	tampered := sealed[:len(sealed)-2] + "AA"
+	if tampered == sealed {
+		tampered = sealed[:len(sealed)-2] + "BB"
+	}
+	for _, value := range []string{tampered, "secret", "v1:not-base64!"} {
+		if _, err := openValue(key, "prod", value); !errors.Is(err, ErrInvalidSealedKey) {
+			t.Errorf("openValue(%q) error = %v, want %v", value, err, ErrInvalidSealedKey)
+		}
+	}
+	if _, err := openValue(key, "staging", sealed); !errors.Is(err, ErrInvalidSealedKey) {
+		t.Errorf("openValue() for another instance error = %v, want %v", err, ErrInvalidSealedKey)
+	}
+}
+
+func TestVaultRejectsSwappedKeys(t *testing.T) {
+	defer func(n int) { vaultScryptN = n }(vaultScryptN)
+	vaultScryptN = 1 << 10
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+
+	manager, err := NewManager(configPath)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	for name, veid := range map[string]string{"prod": "1", "staging": "2"} {
+		if err := manager.AddInstance(name, &Instance{APIKey: name + "-api-key-123456789", VeID: veid}, false); err != nil {
+			t.Fatalf("AddInstance(%s) error = %v", name, err)
+		}
+	}
+	if _, err := manager.EncryptVault("correct horse"); err != nil {
+		t.Fatalf("EncryptVault() error = %v", err)
+	}
+
+	prod, staging := manager.config.Instances["prod"], manager.config.Instances["staging"]
+	prod.APIKeyEncrypted, staging.APIKeyEncrypted = staging.APIKeyEncrypted, prod.APIKeyEncrypted
+	if err := manager.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	swapped, err := NewManager(configPath)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	swapped.SetVaultKeyFunc(func(v *Vault) ([]byte, error) { return v.DeriveKey("correct horse") })
+	for _, name := range []string{"prod", "staging"} {
+		if _, err := swapped.OpenInstance(name); !errors.Is(err, ErrInvalidSealedKey) {
+			t.Errorf("OpenInstance(%s) with a swapped key error = %v, want %v", name, err, ErrInvalidSealedKey)
+		}
+	}
+}
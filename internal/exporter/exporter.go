@@ -0,0 +1,506 @@
+// Package exporter serves Prometheus metrics for BWH instances.
+//
+// Every instance is polled in its own loop. The delay between polls grows as
+// the instance's remaining API points shrink, so the exporter never spends
+// more than a configured share of the KiwiVM rate limit.
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/strahe/bwh/pkg/client"
+)
+
+const (
+	// callsPerPoll is the number of API calls one poll spends.
+	callsPerPoll = 4
+
+	window15Min = 15 * time.Minute
+	window24H   = 24 * time.Hour
+)
+
+// API is the subset of the BWH client the exporter polls.
+type API interface {
+	GetRateLimitStatus(ctx context.Context) (*client.RateLimitStatus, error)
+	GetServiceInfo(ctx context.Context) (*client.ServiceInfo, error)
+	GetLiveServiceInfo(ctx context.Context) (*client.LiveServiceInfo, error)
+	GetRawUsageStats(ctx context.Context) (*client.UsageStatsResponse, error)
+}
+
+// Target is one instance to export metrics for.
+type Target struct {
+	Name string
+	API  API
+}
+
+// Options configures an Exporter.
+type Options struct {
+	// Interval is the shortest delay between two polls of one instance.
+	// Defaults to 5 minutes.
+	Interval time.Duration
+	// Budget is the share of the remaining API points the exporter may use,
+	// between 0 and 1. Defaults to 0.5.
+	Budget float64
+	// Logger receives poll errors. Defaults to a logger writing to io.Discard.
+	Logger *log.Logger
+}
+
+// Exporter polls instances and renders their metrics.
+type Exporter struct {
+	targets []Target
+	opts    Options
+
+	mu     sync.RWMutex
+	states map[string]*instanceState
+}
+
+// instanceState is the latest data collected for one instance.
+type instanceState struct {
+	up          bool
+	lastPoll    time.Time
+	lastSuccess time.Time
+	nextDelay   time.Duration
+	pollErrors  int
+	pollSkips   int
+
+	rate  *client.RateLimitStatus
+	info  *client.ServiceInfo
+	live  *client.LiveServiceInfo
+	usage *client.UsageDataPoint
+}
+
+// New creates an exporter for targets.
+func New(targets []Target, opts Options) *Exporter {
+	if opts.Interval <= 0 {
+		opts.Interval = 5 * time.Minute
+	}
+	if opts.Budget <= 0 || opts.Budget > 1 {
+		opts.Budget = 0.5
+	}
+	if opts.Logger == nil {
+		opts.Logger = log.New(io.Discard, "", 0)
+	}
+
+	states := make(map[string]*instanceState, len(targets))
+	for _, t := range targets {
+		states[t.Name] = &instanceState{}
+	}
+	return &Exporter{targets: targets, opts: opts, states: states}
+}
+
+// Run polls every target until ctx is cancelled.
+func (e *Exporter) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, t := range e.targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			e.runTarget(ctx, t)
+		}()
+	}
+	wg.Wait()
+}
+
+func (e *Exporter) runTarget(ctx context.Context, t Target) {
+	for {
+		delay := e.Poll(ctx, t)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// Poll collects metrics for one target and returns the delay before the next
+// poll. The rate limit status is read first; when the remaining points do not
+// cover the rest of the poll within the budget, the data calls are skipped and
+// the previous values stay in place.
+func (e *Exporter) Poll(ctx context.Context, t Target) time.Duration {
+	now := time.Now()
+	rate, err := t.API.GetRateLimitStatus(ctx)
+	if err != nil {
+		e.opts.Logger.Printf("%s: failed to get rate limit status: %v", t.Name, err)
+		return e.recordFailure(t.Name, now, nil)
+	}
+
+	if !e.hasBudget(rate, callsPerPoll-1) {
+		delay := e.nextDelay(rate)
+		e.update(t.Name, func(s *instanceState) {
+			s.rate = rate
+			s.lastPoll = now
+			s.nextDelay = delay
+			s.pollSkips++
+		})
+		e.opts.Logger.Printf("%s: skipping poll, API budget exhausted (15min: %d, 24h: %d)", t.Name, rate.RemainingPoints15Min, rate.RemainingPoints24H)
+		return delay
+	}
+
+	info, err := t.API.GetServiceInfo(ctx)
+	if err != nil {
+		e.opts.Logger.Printf("%s: failed to get service info: %v", t.Name, err)
+		return e.recordFailure(t.Name, now, rate)
+	}
+	live, err := t.API.GetLiveServiceInfo(ctx)
+	if err != nil {
+		e.opts.Logger.Printf("%s: failed to get live service info: %v", t.Name, err)
+		return e.recordFailure(t.Name, now, rate)
+	}
+	stats, err := t.API.GetRawUsageStats(ctx)
+	if err != nil {
+		e.opts.Logger.Printf("%s: failed to get usage stats: %v", t.Name, err)
+		return e.recordFailure(t.Name, now, rate)
+	}
+
+	delay := e.nextDelay(rate)
+	e.update(t.Name, func(s *instanceState) {
+		s.up = true
+		s.lastPoll = now
+		s.lastSuccess = now
+		s.nextDelay = delay
+		s.rate = rate
+		s.info = info
+		s.live = live
+		if latest := latestUsage(stats.Data); latest != nil {
+			s.usage = latest
+		}
+	})
+	return delay
+}
+
+func (e *Exporter) recordFailure(name string, now time.Time, rate *client.RateLimitStatus) time.Duration {
+	delay := e.nextDelay(rate)
+	e.update(name, func(s *instanceState) {
+		s.up = false
+		s.lastPoll = now
+		s.nextDelay = delay
+		s.pollErrors++
+		if rate != nil {
+			s.rate = rate
+		}
+	})
+	return delay
+}
+
+func (e *Exporter) update(name string, fn func(*instanceState)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	s, ok := e.states[name]
+	if !ok {
+		s = &instanceState{}
+		e.states[name] = s
+	}
+	fn(s)
+}
+
+// hasBudget reports whether calls more API calls fit in the budget of both
+// rate limit windows.
+func (e *Exporter) hasBudget(rate *client.RateLimitStatus, calls int) bool {
+	return float64(rate.RemainingPoints15Min)*e.opts.Budget >= float64(calls) &&
+		float64(rate.RemainingPoints24H)*e.opts.Budget >= float64(calls)
+}
+
+// nextDelay spreads polls so that they use at most the budgeted share of the
+// points left in each window. Without a rate limit status it falls back to
+// the configured interval.
+func (e *Exporter) nextDelay(rate *client.RateLimitStatus) time.Duration {
+	delay := e.opts.Interval
+	if rate == nil {
+		return delay
+	}
+	for _, w := range []struct {
+		remaining int
+		window    time.Duration
+	}{
+		{rate.RemainingPoints15Min, window15Min},
+		{rate.RemainingPoints24H, window24H},
+	} {
+		polls := float64(w.remaining) * e.opts.Budget / callsPerPoll
+		if polls < 1 {
+			// Not even one poll fits; wait for the short window to refill.
+			delay = max(delay, window15Min)
+			continue
+		}
+		delay = max(delay, time.Duration(float64(w.window)/polls))
+	}
+	return delay
+}
+
+func latestUsage(points []client.UsageDataPoint) *client.UsageDataPoint {
+	var latest *client.UsageDataPoint
+	for i := range points {
+		if latest == nil || points[i].Timestamp > latest.Timestamp {
+			latest = &points[i]
+		}
+	}
+	return latest
+}
+
+// Handler returns an HTTP handler serving /metrics and a short index page.
+func (e *Exporter) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		e.WriteMetrics(w) //nolint:errcheck
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, "<html><head><title>BWH Exporter</title></head><body><h1>BWH Exporter</h1><p><a href=\"/metrics\">Metrics</a></p></body></html>\n")
+	})
+	return mux
+}
+
+// sample is one metric value with extra labels beyond "instance".
+type sample struct {
+	labels [][2]string
+	value  float64
+}
+
+// family describes one metric and how to read its samples from an instance.
+type family struct {
+	name    string
+	help    string
+	kind    string
+	samples func(s *instanceState) []sample
+}
+
+func one(v float64) []sample { return []sample{{value: v}} }
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func withInfo(fn func(*client.ServiceInfo) []sample) func(*instanceState) []sample {
+	return func(s *instanceState) []sample {
+		if s.info == nil {
+			return nil
+		}
+		return fn(s.info)
+	}
+}
+
+func withLive(fn func(*client.LiveServiceInfo) []sample) func(*instanceState) []sample {
+	return func(s *instanceState) []sample {
+		if s.live == nil {
+			return nil
+		}
+		return fn(s.live)
+	}
+}
+
+// withKVM only reports values for KVM instances, which are the only ones that
+// return memory and disk throttle details in live service info.
+func withKVM(fn func(*client.LiveServiceInfo) []sample) func(*instanceState) []sample {
+	return withLive(func(l *client.LiveServiceInfo) []sample {
+		if l.VeStatus == "" {
+			return nil
+		}
+		return fn(l)
+	})
+}
+
+func withUsage(fn func(*client.UsageDataPoint) []sample) func(*instanceState) []sample {
+	return func(s *instanceState) []sample {
+		if s.usage == nil {
+			return nil
+		}
+		return fn(s.usage)
+	}
+}
+
+func multiplier(info *client.ServiceInfo) int64 {
+	if info.MonthlyDataMultiplier > 0 {
+		return int64(info.MonthlyDataMultiplier)
+	}
+	return 1
+}
+
+var families = []family{
+	{"bwh_up", "Whether the last poll of the instance succeeded.", "gauge", func(s *instanceState) []sample {
+		return one(boolValue(s.up))
+	}},
+	{"bwh_last_poll_timestamp_seconds", "Unix time of the last poll attempt.", "gauge", func(s *instanceState) []sample {
+		if s.lastPoll.IsZero() {
+			return nil
+		}
+		return one(float64(s.lastPoll.Unix()))
+	}},
+	{"bwh_last_success_timestamp_seconds", "Unix time of the last successful poll.", "gauge", func(s *instanceState) []sample {
+		if s.lastSuccess.IsZero() {
+			return nil
+		}
+		return one(float64(s.lastSuccess.Unix()))
+	}},
+	{"bwh_exporter_poll_interval_seconds", "Delay before the next poll, derived from the remaining API points.", "gauge", func(s *instanceState) []sample {
+		if s.nextDelay == 0 {
+			return nil
+		}
+		return one(s.nextDelay.Seconds())
+	}},
+	{"bwh_exporter_poll_errors_total", "Number of failed polls.", "counter", func(s *instanceState) []sample {
+		return one(float64(s.pollErrors))
+	}},
+	{"bwh_exporter_poll_skips_total", "Number of polls skipped to stay within the API budget.", "counter", func(s *instanceState) []sample {
+		return one(float64(s.pollSkips))
+	}},
+	{"bwh_api_remaining_points", "API calls remaining in the KiwiVM rate limit window.", "gauge", func(s *instanceState) []sample {
+		if s.rate == nil {
+			return nil
+		}
+		return []sample{
+			{labels: [][2]string{{"window", "15m"}}, value: float64(s.rate.RemainingPoints15Min)},
+			{labels: [][2]string{{"window", "24h"}}, value: float64(s.rate.RemainingPoints24H)},
+		}
+	}},
+	{"bwh_running", "Whether the VPS reports the running power state.", "gauge", withLive(func(l *client.LiveServiceInfo) []sample {
+		return one(boolValue(client.PowerState(l) == client.PowerStateRunning))
+	})},
+	{"bwh_cpu_usage_percent", "CPU usage in the latest usage sample.", "gauge", withUsage(func(u *client.UsageDataPoint) []sample {
+		return one(float64(u.CPUUsage))
+	})},
+	{"bwh_network_receive_bytes", "Bytes received in the latest usage sample.", "gauge", withUsage(func(u *client.UsageDataPoint) []sample {
+		return one(float64(u.NetworkInBytes))
+	})},
+	{"bwh_network_transmit_bytes", "Bytes sent in the latest usage sample.", "gauge", withUsage(func(u *client.UsageDataPoint) []sample {
+		return one(float64(u.NetworkOutBytes))
+	})},
+	{"bwh_disk_read_bytes", "Bytes read from disk in the latest usage sample.", "gauge", withUsage(func(u *client.UsageDataPoint) []sample {
+		return one(float64(u.DiskReadBytes))
+	})},
+	{"bwh_disk_write_bytes", "Bytes written to disk in the latest usage sample.", "gauge", withUsage(func(u *client.UsageDataPoint) []sample {
+		return one(float64(u.DiskWriteBytes))
+	})},
+	{"bwh_usage_sample_timestamp_seconds", "Unix time of the latest usage sample.", "gauge", withUsage(func(u *client.UsageDataPoint) []sample {
+		return one(float64(u.Timestamp))
+	})},
+	{"bwh_data_used_bytes", "Data transfer used this billing month, multiplier applied.", "gauge", withInfo(func(i *client.ServiceInfo) []sample {
+		return one(float64(i.DataCounter * multiplier(i)))
+	})},
+	{"bwh_data_limit_bytes", "Monthly data transfer allowance, multiplier applied.", "gauge", withInfo(func(i *client.ServiceInfo) []sample {
+		return one(float64(i.PlanMonthlyData * multiplier(i)))
+	})},
+	{"bwh_data_usage_ratio", "Share of the monthly data transfer allowance used.", "gauge", withInfo(func(i *client.ServiceInfo) []sample {
+		if i.PlanMonthlyData <= 0 {
+			return nil
+		}
+		return one(float64(i.DataCounter) / float64(i.PlanMonthlyData))
+	})},
+	{"bwh_data_multiplier", "Bandwidth accounting multiplier of the location.", "gauge", withInfo(func(i *client.ServiceInfo) []sample {
+		return one(float64(multiplier(i)))
+	})},
+	{"bwh_data_next_reset_timestamp_seconds", "Unix time the data transfer counter resets.", "gauge", withInfo(func(i *client.ServiceInfo) []sample {
+		return one(float64(i.DataNextReset))
+	})},
+	{"bwh_memory_available_bytes", "Available RAM reported by the VPS.", "gauge", withKVM(func(l *client.LiveServiceInfo) []sample {
+		return one(float64(l.MemAvailableKB.Value * 1024))
+	})},
+	{"bwh_swap_total_bytes", "Total swap reported by the VPS.", "gauge", withKVM(func(l *client.LiveServiceInfo) []sample {
+		return one(float64(l.SwapTotalKB.Value * 1024))
+	})},
+	{"bwh_swap_available_bytes", "Available swap reported by the VPS.", "gauge", withKVM(func(l *client.LiveServiceInfo) []sample {
+		return one(float64(l.SwapAvailableKB.Value * 1024))
+	})},
+	{"bwh_cpu_throttled", "Whether the CPU is throttled.", "gauge", withLive(func(l *client.LiveServiceInfo) []sample {
+		return one(boolValue(l.IsCPUThrottled.Value != 0))
+	})},
+	{"bwh_disk_throttled", "Whether disk IO is throttled.", "gauge", withKVM(func(l *client.LiveServiceInfo) []sample {
+		return one(boolValue(l.IsDiskThrottled.Value != 0))
+	})},
+	{"bwh_abuse_points", "Abuse points accumulated in the current calendar year.", "gauge", withInfo(func(i *client.ServiceInfo) []sample {
+		return one(float64(i.TotalAbusePoints))
+	})},
+	{"bwh_abuse_points_max", "Abuse points allowed by the plan per calendar year.", "gauge", withInfo(func(i *client.ServiceInfo) []sample {
+		return one(float64(i.MaxAbusePoints))
+	})},
+	{"bwh_suspended", "Whether the VPS is suspended.", "gauge", withInfo(func(i *client.ServiceInfo) []sample {
+		return one(boolValue(i.Suspended))
+	})},
+	{"bwh_policy_violation", "Whether there is an active policy violation.", "gauge", withInfo(func(i *client.ServiceInfo) []sample {
+		return one(boolValue(i.PolicyViolation))
+	})},
+	{"bwh_nullrouted_ips", "Number of IP addresses currently null-routed.", "gauge", withInfo(func(i *client.ServiceInfo) []sample {
+		return one(float64(len(i.IPNullroutes)))
+	})},
+	{"bwh_ip_nullrouted", "Null-routed IP addresses; the value is the Unix time the nullroute started.", "gauge", withInfo(func(i *client.ServiceInfo) []sample {
+		var samples []sample
+		for _, ip := range slices.Sorted(maps.Keys(i.IPNullroutes)) {
+			samples = append(samples, sample{
+				labels: [][2]string{{"ip", ip}},
+				value:  float64(i.IPNullroutes[ip].NullrouteTimestamp),
+			})
+		}
+		return samples
+	})},
+}
+
+// WriteMetrics writes all metrics in the Prometheus text exposition format.
+func (e *Exporter) WriteMetrics(w io.Writer) error {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	names := slices.Sorted(maps.Keys(e.states))
+
+	var b strings.Builder
+	for _, f := range families {
+		var lines []string
+		for _, name := range names {
+			for _, s := range f.samples(e.states[name]) {
+				lines = append(lines, formatSample(f.name, name, s))
+			}
+		}
+		if len(lines) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", f.name, f.help, f.name, f.kind)
+		for _, line := range lines {
+			b.WriteString(line)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func formatSample(metric, instance string, s sample) string {
+	labels := []string{`instance="` + escapeLabel(instance) + `"`}
+	for _, l := range s.labels {
+		labels = append(labels, l[0]+`="`+escapeLabel(l[1])+`"`)
+	}
+	return fmt.Sprintf("%s{%s} %s\n", metric, strings.Join(labels, ","), formatValue(s.value))
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(v string) string {
+	return labelEscaper.Replace(v)
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
@@ -0,0 +1,192 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/strahe/bwh/pkg/client"
+)
+
+type fakeAPI struct {
+	rate  *client.RateLimitStatus
+	info  *client.ServiceInfo
+	live  *client.LiveServiceInfo
+	usage *client.UsageStatsResponse
+	err   error
+	calls int
+}
+
+func (f *fakeAPI) GetRateLimitStatus(context.Context) (*client.RateLimitStatus, error) {
+	f.calls++
+	return f.rate, nil
+}
+
+func (f *fakeAPI) GetServiceInfo(context.Context) (*client.ServiceInfo, error) {
+	f.calls++
+	return f.info, nil
+}
+
+func (f *fakeAPI) GetLiveServiceInfo(context.Context) (*client.LiveServiceInfo, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	return f.live, nil
+}
+
+func (f *fakeAPI) GetRawUsageStats(context.Context) (*client.UsageStatsResponse, error) {
+	f.calls++
+	return f.usage, nil
+}
+
+func newFakeAPI() *fakeAPI {
+	info := client.ServiceInfo{
+		PlanMonthlyData:       1000,
+		DataCounter:           250,
+		MonthlyDataMultiplier: 2,
+		DataNextReset:         1700000000,
+		TotalAbusePoints:      3,
+		MaxAbusePoints:        100,
+		IPNullroutes: client.IPNullroutes{
+			"1.2.3.4": {NullrouteTimestamp: 1690000000},
+		},
+	}
+	return &fakeAPI{
+		rate: &client.RateLimitStatus{RemainingPoints15Min: 100, RemainingPoints24H: 1000},
+		info: &info,
+		live: &client.LiveServiceInfo{
+			ServiceInfo:     info,
+			VeStatus:        "Running",
+			MemAvailableKB:  client.FlexibleInt{Value: 2048},
+			IsCPUThrottled:  client.FlexibleInt{Value: 1},
+			SwapTotalKB:     client.FlexibleInt{Value: 1024},
+			SwapAvailableKB: client.FlexibleInt{Value: 512},
+		},
+		usage: &client.UsageStatsResponse{Data: []client.UsageDataPoint{
+			{Timestamp: 100, CPUUsage: 5, NetworkInBytes: 10},
+			{Timestamp: 200, CPUUsage: 42, NetworkInBytes: 4096, DiskWriteBytes: 7},
+		}},
+	}
+}
+
+func TestPollAndWriteMetrics(t *testing.T) {
+	api := newFakeAPI()
+	e := New([]Target{{Name: "web", API: api}}, Options{Interval: time.Minute})
+
+	delay := e.Poll(context.Background(), Target{Name: "web", API: api})
+	if api.calls != callsPerPoll {
+		t.Fatalf("calls = %d, want %d", api.calls, callsPerPoll)
+	}
+	// 1000 points in 24h at 50% budget and 4 calls per poll allows 125 polls.
+	if want := time.Duration(float64(24*time.Hour) / 125); delay != want {
+		t.Fatalf("delay = %v, want %v", delay, want)
+	}
+
+	var b strings.Builder
+	if err := e.WriteMetrics(&b); err != nil {
+		t.Fatalf("WriteMetrics() error = %v", err)
+	}
+	out := b.String()
+	for _, want := range []string{
+		"# TYPE bwh_up gauge\nbwh_up{instance=\"web\"} 1\n",
+		`bwh_cpu_usage_percent{instance="web"} 42`,
+		`bwh_network_receive_bytes{instance="web"} 4096`,
+		`bwh_disk_write_bytes{instance="web"} 7`,
+		`bwh_data_used_bytes{instance="web"} 500`,
+		`bwh_data_limit_bytes{instance="web"} 2000`,
+		`bwh_data_usage_ratio{instance="web"} 0.25`,
+		`bwh_memory_available_bytes{instance="web"} 2097152`,
+		`bwh_swap_available_bytes{instance="web"} 524288`,
+		`bwh_cpu_throttled{instance="web"} 1`,
+		`bwh_running{instance="web"} 1`,
+		`bwh_abuse_points{instance="web"} 3`,
+		`bwh_ip_nullrouted{instance="web",ip="1.2.3.4"} 1690000000`,
+		`bwh_api_remaining_points{instance="web",window="15m"} 100`,
+		`bwh_api_remaining_points{instance="web",window="24h"} 1000`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("metrics missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestPollRespectsBudget(t *testing.T) {
+	t.Run("skips data calls when budget is exhausted", func(t *testing.T) {
+		api := newFakeAPI()
+		api.rate = &client.RateLimitStatus{RemainingPoints15Min: 4, RemainingPoints24H: 1000}
+		e := New([]Target{{Name: "web", API: api}}, Options{})
+
+		delay := e.Poll(context.Background(), Target{Name: "web", API: api})
+		if api.calls != 1 {
+			t.Fatalf("calls = %d, want only the rate limit call", api.calls)
+		}
+		if delay < window15Min {
+			t.Fatalf("delay = %v, want at least %v", delay, window15Min)
+		}
+
+		var b strings.Builder
+		e.WriteMetrics(&b) //nolint:errcheck
+		if !strings.Contains(b.String(), `bwh_exporter_poll_skips_total{instance="web"} 1`) {
+			t.Fatalf("skip not recorded:\n%s", b.String())
+		}
+	})
+
+	t.Run("failed poll keeps previous values", func(t *testing.T) {
+		api := newFakeAPI()
+		e := New([]Target{{Name: "web", API: api}}, Options{})
+		e.Poll(context.Background(), Target{Name: "web", API: api})
+
+		api.err = errors.New("boom")
+		e.Poll(context.Background(), Target{Name: "web", API: api})
+
+		var b strings.Builder
+		e.WriteMetrics(&b) //nolint:errcheck
+		out := b.String()
+		for _, want := range []string{
+			`bwh_up{instance="web"} 0`,
+			`bwh_exporter_poll_errors_total{instance="web"} 1`,
+			`bwh_cpu_usage_percent{instance="web"} 42`,
+		} {
+			if !strings.Contains(out, want) {
+				t.Fatalf("metrics missing %q:\n%s", want, out)
+			}
+		}
+	})
+
+	t.Run("delay never drops below interval", func(t *testing.T) {
+		e := New(nil, Options{Interval: 10 * time.Minute})
+		delay := e.nextDelay(&client.RateLimitStatus{RemainingPoints15Min: 1000, RemainingPoints24H: 100000})
+		if delay != 10*time.Minute {
+			t.Fatalf("delay = %v, want 10m", delay)
+		}
+	})
+}
+
+func TestHandler(t *testing.T) {
+	e := New([]Target{{Name: `we"b`, API: newFakeAPI()}}, Options{})
+	srv := httptest.NewServer(e.Handler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/metrics")
+	if err != nil {
+		t.Fatalf("GET /metrics error = %v", err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read body error = %v", err)
+	}
+	if !strings.Contains(string(body), `bwh_up{instance="we\"b"} 0`) {
+		t.Fatalf("body = %s", body)
+	}
+}
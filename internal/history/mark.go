@@ -0,0 +1,53 @@
+package history
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Mark is a high-water mark: the newest timestamp that has been processed and
+// the keys of the records seen at exactly that timestamp, since several
+// records can share one.
+type Mark struct {
+	Timestamp int64    `json:"timestamp"`
+	Keys      []string `json:"keys,omitempty"`
+}
+
+// LoadMark reads a mark from path. A missing file yields nil and no error.
+func LoadMark(path string) (*Mark, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read high-water mark: %w", err)
+	}
+	var mark Mark
+	if err := json.Unmarshal(data, &mark); err != nil {
+		return nil, fmt.Errorf("failed to parse high-water mark %s: %w", path, err)
+	}
+	return &mark, nil
+}
+
+// SaveMark writes mark to path, replacing the previous file atomically.
+func SaveMark(path string, mark *Mark) error {
+	data, err := json.Marshal(mark)
+	if err != nil {
+		return fmt.Errorf("failed to encode high-water mark: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("failed to write high-water mark: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp) //nolint:errcheck
+		return fmt.Errorf("failed to write high-water mark: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package history
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Nullroute event kinds.
+const (
+	NullrouteStarted  = "nullrouted"
+	NullrouteReleased = "released"
+)
+
+// NullrouteEvent records an IP address being nullrouted or released, as
+// observed by polling the service info.
+type NullrouteEvent struct {
+	// Time is when the change was observed.
+	Time  time.Time `json:"time"`
+	IP    string    `json:"ip"`
+	Event string    `json:"event"`
+	// The remaining fields are copied from the nullroute while it is active.
+	NullrouteTimestamp int64  `json:"nullroute_timestamp,omitempty"`
+	DurationSeconds    int    `json:"duration_s,omitempty"`
+	Log                string `json:"log,omitempty"`
+}
+
+// NullrouteStore is the nullroute event history of one instance.
+type NullrouteStore struct {
+	path string
+}
+
+// NewNullrouteStore returns the nullroute store of instance under dir.
+func NewNullrouteStore(dir, instance string) *NullrouteStore {
+	return &NullrouteStore{path: FilePath(dir, instance, "nullroutes.jsonl")}
+}
+
+// Path returns the file the store reads and appends to.
+func (s *NullrouteStore) Path() string {
+	return s.path
+}
+
+// Load returns all stored events in the order they were recorded. A missing
+// file yields no events and no error.
+func (s *NullrouteStore) Load() ([]NullrouteEvent, error) {
+	var events []NullrouteEvent
+	err := readJSONLines(s.path, func(line []byte) error {
+		var e NullrouteEvent
+		if err := json.Unmarshal(line, &e); err != nil {
+			return err
+		}
+		events = append(events, e)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// Append stores events after the existing ones.
+func (s *NullrouteStore) Append(events ...NullrouteEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	var buf bytes.Buffer
+	for _, e := range events {
+		line, err := json.Marshal(e)
+		if err != nil {
+			return fmt.Errorf("failed to encode nullroute event: %w", err)
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	return appendFile(s.path, buf.Bytes())
+}
+
+// ActiveNullroutes returns the IP addresses whose latest event is a
+// nullroute, mapped to that event.
+func ActiveNullroutes(events []NullrouteEvent) map[string]NullrouteEvent {
+	active := map[string]NullrouteEvent{}
+	for _, e := range events {
+		switch e.Event {
+		case NullrouteStarted:
+			active[e.IP] = e
+		case NullrouteReleased:
+			delete(active, e.IP)
+		}
+	}
+	return active
+}
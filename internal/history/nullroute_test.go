@@ -0,0 +1,35 @@
+package history
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNullrouteStore(t *testing.T) {
+	store := NewNullrouteStore(t.TempDir(), "web")
+	now := time.Unix(1_700_000_000, 0).UTC()
+
+	events, err := store.Load()
+	if err != nil || len(events) != 0 {
+		t.Fatalf("Load() on missing file = %v, %v", events, err)
+	}
+
+	if err := store.Append(
+		NullrouteEvent{Time: now, IP: "203.0.113.5", Event: NullrouteStarted, DurationSeconds: 3600},
+		NullrouteEvent{Time: now, IP: "203.0.113.6", Event: NullrouteStarted},
+	); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+	if err := store.Append(NullrouteEvent{Time: now.Add(time.Hour), IP: "203.0.113.5", Event: NullrouteReleased}); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+
+	events, err = store.Load()
+	if err != nil || len(events) != 3 || !events[0].Time.Equal(now) || events[0].DurationSeconds != 3600 {
+		t.Fatalf("Load() = %+v, %v", events, err)
+	}
+	active := ActiveNullroutes(events)
+	if len(active) != 1 || active["203.0.113.6"].Event != NullrouteStarted {
+		t.Fatalf("ActiveNullroutes() = %+v", active)
+	}
+}
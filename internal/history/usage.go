@@ -0,0 +1,221 @@
+// Package history keeps local records of instance data that KiwiVM only
+// retains for a limited time.
+//
+// Records are stored as append-only JSON lines under ~/.bwh/history, or the
+// directory set in BWH_HISTORY_DIR, with one file per instance and kind.
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/strahe/bwh/pkg/client"
+)
+
+// DefaultDir returns the directory history files are stored in.
+func DefaultDir() (string, error) {
+	if dir := os.Getenv("BWH_HISTORY_DIR"); dir != "" {
+		return dir, nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".bwh", "history"), nil
+}
+
+// FilePath returns the path of a history file of an instance, such as
+// "usage.jsonl". Instance names are escaped so they are always a single path
+// element.
+func FilePath(dir, instance, name string) string {
+	return filepath.Join(dir, url.PathEscape(instance), name)
+}
+
+// UsageStore is the usage history of one instance.
+type UsageStore struct {
+	path string
+}
+
+// NewUsageStore returns the usage store of instance under dir.
+func NewUsageStore(dir, instance string) *UsageStore {
+	return &UsageStore{path: FilePath(dir, instance, "usage.jsonl")}
+}
+
+// Path returns the file the store reads and appends to.
+func (s *UsageStore) Path() string {
+	return s.path
+}
+
+// Load returns all stored data points sorted by timestamp. A missing file
+// yields no points and no error.
+func (s *UsageStore) Load() ([]client.UsageDataPoint, error) {
+	var points []client.UsageDataPoint
+	err := readJSONLines(s.path, func(line []byte) error {
+		var p client.UsageDataPoint
+		if err := json.Unmarshal(line, &p); err != nil {
+			return err
+		}
+		points = append(points, p)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return MergeUsage(points), nil
+}
+
+// Append stores the points whose timestamps are not yet in the store and
+// returns how many were added.
+func (s *UsageStore) Append(points []client.UsageDataPoint) (int, error) {
+	stored, err := s.Load()
+	if err != nil {
+		return 0, err
+	}
+	seen := make(map[int64]bool, len(stored))
+	for _, p := range stored {
+		seen[p.Timestamp] = true
+	}
+
+	var buf bytes.Buffer
+	added := 0
+	for _, p := range MergeUsage(points) {
+		if seen[p.Timestamp] {
+			continue
+		}
+		line, err := json.Marshal(p)
+		if err != nil {
+			return 0, fmt.Errorf("failed to encode usage data point: %w", err)
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+		added++
+	}
+	if added == 0 {
+		return 0, nil
+	}
+	if err := appendFile(s.path, buf.Bytes()); err != nil {
+		return 0, err
+	}
+	return added, nil
+}
+
+// MergeUsage returns the points sorted by timestamp with duplicate
+// timestamps removed, keeping the last occurrence.
+func MergeUsage(sets ...[]client.UsageDataPoint) []client.UsageDataPoint {
+	byTime := map[int64]client.UsageDataPoint{}
+	for _, set := range sets {
+		for _, p := range set {
+			byTime[p.Timestamp] = p
+		}
+	}
+	merged := make([]client.UsageDataPoint, 0, len(byTime))
+	for _, p := range byTime {
+		merged = append(merged, p)
+	}
+	slices.SortFunc(merged, func(a, b client.UsageDataPoint) int {
+		switch {
+		case a.Timestamp < b.Timestamp:
+			return -1
+		case a.Timestamp > b.Timestamp:
+			return 1
+		}
+		return 0
+	})
+	return merged
+}
+
+// readJSONLines calls fn for every complete, non-empty line of path.
+func readJSONLines(path string, fn func(line []byte) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("failed to open history file: %w", err)
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+	for lineNo := 1; ; lineNo++ {
+		line, readErr := reader.ReadBytes('\n')
+		if readErr != nil && !errors.Is(readErr, io.EOF) {
+			return fmt.Errorf("failed to read history file: %w", readErr)
+		}
+		if readErr != nil {
+			// A last line without a newline is an interrupted append.
+			return nil
+		}
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			if err := fn(line); err != nil {
+				return fmt.Errorf("invalid history entry at %s:%d: %w", path, lineNo, err)
+			}
+		}
+	}
+}
+
+func appendFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open history file: %w", err)
+	}
+	if err := ensureLineStart(f); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write history file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write history file: %w", err)
+	}
+	return nil
+}
+
+// ensureLineStart drops a partial last line left by an interrupted append so
+// new entries always start on their own line.
+func ensureLineStart(f *os.File) error {
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat history file: %w", err)
+	}
+
+	const chunkSize = 4096
+	end := info.Size()
+	buf := make([]byte, chunkSize)
+	for offset := end; offset > 0; {
+		n := int64(chunkSize)
+		if offset < n {
+			n = offset
+		}
+		offset -= n
+		if _, err := f.ReadAt(buf[:n], offset); err != nil {
+			return fmt.Errorf("failed to read history file: %w", err)
+		}
+		if i := bytes.LastIndexByte(buf[:n], '\n'); i >= 0 {
+			return truncateHistory(f, offset+int64(i)+1, end)
+		}
+	}
+	return truncateHistory(f, 0, end)
+}
+
+func truncateHistory(f *os.File, size, current int64) error {
+	if size == current {
+		return nil
+	}
+	if err := f.Truncate(size); err != nil {
+		return fmt.Errorf("failed to repair history file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/strahe/bwh/pkg/client"
+)
+
+func TestUsageStoreAppend(t *testing.T) {
+	dir := t.TempDir()
+	store := NewUsageStore(dir, "web/1")
+	if filepath.Dir(filepath.Dir(store.Path())) != dir {
+		t.Fatalf("Path() = %s, want instance escaped under %s", store.Path(), dir)
+	}
+
+	points, err := store.Load()
+	if err != nil || len(points) != 0 {
+		t.Fatalf("Load() on missing file = %v, %v", points, err)
+	}
+
+	added, err := store.Append([]client.UsageDataPoint{
+		{Timestamp: 300, CPUUsage: 3},
+		{Timestamp: 100, CPUUsage: 1},
+		{Timestamp: 200, CPUUsage: 2},
+	})
+	if err != nil || added != 3 {
+		t.Fatalf("Append() = %d, %v; want 3", added, err)
+	}
+
+	added, err = store.Append([]client.UsageDataPoint{
+		{Timestamp: 300, CPUUsage: 99},
+		{Timestamp: 400, CPUUsage: 4},
+	})
+	if err != nil || added != 1 {
+		t.Fatalf("second Append() = %d, %v; want 1", added, err)
+	}
+
+	points, err = store.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(points) != 4 {
+		t.Fatalf("Load() returned %d points, want 4", len(points))
+	}
+	for i, want := range []int64{100, 200, 300, 400} {
+		if points[i].Timestamp != want {
+			t.Fatalf("points[%d].Timestamp = %d, want %d", i, points[i].Timestamp, want)
+		}
+	}
+	if points[2].CPUUsage != 3 {
+		t.Fatalf("existing point was overwritten: %+v", points[2])
+	}
+
+	info, err := os.Stat(store.Path())
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Fatalf("history file mode = %v, want 0600", info.Mode().Perm())
+	}
+}
+
+func TestUsageStoreInterruptedAppend(t *testing.T) {
+	store := NewUsageStore(t.TempDir(), "web")
+	if _, err := store.Append([]client.UsageDataPoint{{Timestamp: 100}}); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+
+	f, err := os.OpenFile(store.Path(), os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		t.Fatalf("OpenFile() error = %v", err)
+	}
+	f.WriteString(`{"timestamp":200,"cpu_us`) //nolint:errcheck
+	f.Close()
+
+	points, err := store.Load()
+	if err != nil || len(points) != 1 {
+		t.Fatalf("Load() with torn line = %v, %v; want 1 point", points, err)
+	}
+
+	if _, err := store.Append([]client.UsageDataPoint{{Timestamp: 200}}); err != nil {
+		t.Fatalf("Append() after torn line error = %v", err)
+	}
+	data, err := os.ReadFile(store.Path())
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if strings.Contains(string(data), "cpu_us\n") || strings.Count(string(data), "\n") != 2 {
+		t.Fatalf("torn line not repaired:\n%s", data)
+	}
+}
+
+func TestUsageStoreRejectsCorruptEntries(t *testing.T) {
+	store := NewUsageStore(t.TempDir(), "web")
+	if err := os.MkdirAll(filepath.Dir(store.Path()), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(store.Path(), []byte("not json\n{\"timestamp\":1}\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Load(); err == nil || !strings.Contains(err.Error(), ":1:") {
+		t.Fatalf("Load() error = %v, want line number", err)
+	}
+}
+
+func TestMark(t *testing.T) {
+	path := FilePath(t.TempDir(), "web", "audit-mark.json")
+	mark, err := LoadMark(path)
+	if err != nil || mark != nil {
+		t.Fatalf("LoadMark() on missing file = %v, %v", mark, err)
+	}
+	if err := SaveMark(path, &Mark{Timestamp: 42, Keys: []string{"a", "b"}}); err != nil {
+		t.Fatalf("SaveMark() error = %v", err)
+	}
+	mark, err = LoadMark(path)
+	if err != nil || mark.Timestamp != 42 || len(mark.Keys) != 2 {
+		t.Fatalf("LoadMark() = %+v, %v", mark, err)
+	}
+}
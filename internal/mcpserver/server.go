@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net"
 	"sort"
 	"strings"
 	"time"
@@ -11,6 +12,7 @@ import (
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 	"github.com/strahe/bwh/internal/config"
+	"github.com/strahe/bwh/internal/vault"
 	"github.com/strahe/bwh/pkg/client"
 )
 
@@ -22,6 +24,9 @@ func RunMCPStdioServer(ctx context.Context, configPath, instanceName string) err
 	if err != nil {
 		return fmt.Errorf("failed to initialize config manager: %w", err)
 	}
+	// stdin carries the MCP protocol, so an encrypted config can only be
+	// unlocked from the environment or the agent.
+	manager.SetVaultKeyFunc(vault.KeyFunc(nil))
 
 	// Resolve once here only for connectivity check (uses provided instanceName or config default)
 	instForCheck, resolvedInstanceName, err := manager.ResolveInstance(instanceName)
@@ -60,21 +65,29 @@ func RunMCPStdioServer(ctx context.Context, configPath, instanceName string) err
 }
 
 func resolveClient(manager *config.Manager, requested, defaultInstance string) (*client.Client, string, error) {
+	inst, resolved, err := resolveInstance(manager, requested, defaultInstance)
+	if err != nil {
+		return nil, "", err
+	}
+	return newClient(inst), resolved, nil
+}
+
+// resolveInstance resolves requested, or defaultInstance when it is empty,
+// like the --instance flag of the CLI.
+func resolveInstance(manager *config.Manager, requested, defaultInstance string) (*config.Instance, string, error) {
 	target := strings.TrimSpace(requested)
 	if target == "" {
 		target = defaultInstance
 	}
+	return manager.ResolveInstance(target)
+}
 
-	inst, resolved, err := manager.ResolveInstance(target)
-	if err != nil {
-		return nil, "", err
-	}
-
+func newClient(inst *config.Instance) *client.Client {
 	c := client.NewClient(inst.APIKey, inst.VeID)
 	if inst.Endpoint != "" {
 		c.SetBaseURL(inst.Endpoint)
 	}
-	return c, resolved, nil
+	return c
 }
 
 func callReadOnlyTool[T any](
@@ -206,6 +219,76 @@ func notificationPreferencesPayload(resolved string, resp *client.NotificationPr
 	}
 }
 
+// auditCheckPayload reports the audit entries at or after since whose
+// requestor is outside trusted, newest first, with a per-address summary.
+func auditCheckPayload(resolved string, trusted []*net.IPNet, entries []client.AuditLogEntry, since int64) map[string]any {
+	type untrustedEntry struct {
+		Timestamp int64  `json:"timestamp"`
+		Time      string `json:"time"`
+		IP        string `json:"ip"`
+		Type      int    `json:"type"`
+		Summary   string `json:"summary"`
+	}
+	type addressSummary struct {
+		IP        string `json:"ip"`
+		Count     int    `json:"count"`
+		FirstSeen string `json:"first_seen"`
+		LastSeen  string `json:"last_seen"`
+	}
+
+	sorted := make([]client.AuditLogEntry, len(entries))
+	copy(sorted, entries)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Timestamp > sorted[j].Timestamp })
+
+	checked := 0
+	untrusted := []untrustedEntry{}
+	byIP := map[string]*addressSummary{}
+	for _, e := range sorted {
+		if since > 0 && e.Timestamp < since {
+			continue
+		}
+		checked++
+		if config.IPv4InNets(e.RequestorIPv4, trusted) {
+			continue
+		}
+		ip := config.IPv4(e.RequestorIPv4)
+		ts := time.Unix(e.Timestamp, 0).UTC().Format(time.RFC3339)
+		untrusted = append(untrusted, untrustedEntry{Timestamp: e.Timestamp, Time: ts, IP: ip.String(), Type: e.Type, Summary: e.Summary})
+		summary, ok := byIP[ip.String()]
+		if !ok {
+			summary = &addressSummary{IP: ip.String(), LastSeen: ts}
+			byIP[ip.String()] = summary
+		}
+		summary.Count++
+		summary.FirstSeen = ts
+	}
+
+	addresses := make([]*addressSummary, 0, len(byIP))
+	for _, summary := range byIP {
+		addresses = append(addresses, summary)
+	}
+	sort.Slice(addresses, func(i, j int) bool {
+		if addresses[i].Count != addresses[j].Count {
+			return addresses[i].Count > addresses[j].Count
+		}
+		return addresses[i].IP < addresses[j].IP
+	})
+
+	ranges := make([]string, len(trusted))
+	for i, n := range trusted {
+		ranges[i] = n.String()
+	}
+	return map[string]any{
+		"instance":        resolved,
+		"trusted_cidrs":   ranges,
+		"checked":         checked,
+		"untrusted_total": len(untrusted),
+		"ok":              len(untrusted) == 0,
+		"untrusted":       untrusted,
+		"by_ip":           addresses,
+	}
+}
+
 func countNotificationPreferences(prefs map[string]map[string]client.NotificationPreference) int {
 	total := 0
 	for _, category := range prefs {
@@ -748,6 +831,51 @@ func registerReadOnlyTools(s *server.MCPServer, manager *config.Manager, default
 		},
 	)
 
+	// audit_check
+	s.AddTool(
+		mcp.NewTool(
+			"audit_check",
+			mcp.WithDescription("Find audit log entries for BWH/BandwagonHost/搬瓦工/瓦工 from addresses outside the instance's trusted_cidrs"),
+			mcp.WithReadOnlyHintAnnotation(true),
+			mcp.WithDestructiveHintAnnotation(false),
+			mcp.WithIdempotentHintAnnotation(true),
+			mcp.WithOpenWorldHintAnnotation(true),
+			mcp.WithString("instance", mcp.Description("Target instance name; defaults to config default")),
+			mcp.WithString("since", mcp.Description("RFC3339 timestamp inclusive start filter")),
+		),
+		func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			requested := req.GetString("instance", "")
+			sinceStr := strings.TrimSpace(req.GetString("since", ""))
+
+			var sinceTs int64
+			if sinceStr != "" {
+				t, err := time.Parse(time.RFC3339, sinceStr)
+				if err != nil {
+					return mcp.NewToolResultError(fmt.Sprintf("invalid since: %v", err)), nil
+				}
+				sinceTs = t.Unix()
+			}
+
+			inst, resolved, err := resolveInstance(manager, requested, defaultInstance)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("resolve instance failed: %v", err)), nil
+			}
+			trusted, err := inst.TrustedNets()
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("invalid trusted_cidrs: %v", err)), nil
+			}
+			if len(trusted) == 0 {
+				return mcp.NewToolResultError(fmt.Sprintf("no trusted_cidrs configured for instance %s", resolved)), nil
+			}
+
+			logResp, err := newClient(inst).GetAuditLog(ctx)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("get audit log failed: %v", err)), nil
+			}
+			return mcp.NewToolResultStructuredOnly(auditCheckPayload(resolved, trusted, logResp.LogEntries, sinceTs)), nil
+		},
+	)
+
 	// iso_list
 	s.AddTool(
 		mcp.NewTool(
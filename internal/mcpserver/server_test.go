@@ -2,12 +2,16 @@ package mcpserver
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/mark3labs/mcp-go/mcp"
+	mcpgo "github.com/mark3labs/mcp-go/server"
 	"github.com/strahe/bwh/internal/config"
 	"github.com/strahe/bwh/pkg/client"
 )
@@ -167,6 +171,31 @@ func TestMCPReadOnlyPayloads(t *testing.T) {
 			t.Fatalf("total_preference_ids = %v", payload["total_preference_ids"])
 		}
 	})
+
+	t.Run("audit check", func(t *testing.T) {
+		trusted, err := config.ParseIPv4Nets([]string{"10.0.0.0/8"})
+		if err != nil {
+			t.Fatalf("ParseIPv4Nets() error = %v", err)
+		}
+		payload := auditCheckPayload("default", trusted, []client.AuditLogEntry{
+			{Timestamp: 100, RequestorIPv4: 0x01020304, Summary: "too old"},
+			{Timestamp: 200, RequestorIPv4: 0x01020304, Summary: "Logged in"},
+			{Timestamp: 300, RequestorIPv4: 0x0A000001, Summary: "trusted"},
+			{Timestamp: 400, RequestorIPv4: 0x01020304, Summary: "VPS stopped"},
+			{Timestamp: 500, RequestorIPv4: 0x05060708, Summary: "Logged in"},
+		}, 200)
+
+		if payload["checked"] != 4 || payload["untrusted_total"] != 3 || payload["ok"] != false {
+			t.Fatalf("payload = %v", payload)
+		}
+		raw, err := json.Marshal(payload["by_ip"])
+		if err != nil {
+			t.Fatalf("Marshal() error = %v", err)
+		}
+		if !strings.HasPrefix(string(raw), `[{"ip":"1.2.3.4","count":2,"first_seen":"1970-01-01T00:03:20Z","last_seen":"1970-01-01T00:06:40Z"}`) {
+			t.Fatalf("by_ip = %s", raw)
+		}
+	})
 }
 
 func TestCallReadOnlyTool(t *testing.T) {
@@ -311,3 +340,58 @@ func TestCallReadOnlyToolUsesServerDefaultInstance(t *testing.T) {
 		t.Fatalf("veids = %v, want second call to use 111111", seenVEIDs)
 	}
 }
+
+func TestAuditCheckToolResolvesInstance(t *testing.T) {
+	t.Setenv(config.NoProjectConfigEnv, "1")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"error":0,"log_entries":[{"timestamp":100,"requestor_ipv4":16909060,"type":0,"summary":"Logged in"}]}`))
+	}))
+	defer server.Close()
+
+	manager, err := config.NewManager(filepath.Join(t.TempDir(), "config.yaml"))
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	if err := manager.AddInstance("prod-web", &config.Instance{
+		APIKey:       "web-api-key-123456789",
+		VeID:         "100",
+		Endpoint:     server.URL,
+		TrustedCIDRs: []string{"10.0.0.0/8"},
+	}, true); err != nil {
+		t.Fatalf("AddInstance() error = %v", err)
+	}
+
+	s := mcpgo.NewMCPServer("test", "1.0.0", mcpgo.WithToolCapabilities(true))
+	registerReadOnlyTools(s, manager, "prod-web")
+	call := func(instance string) map[string]any {
+		t.Helper()
+		request := fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"audit_check","arguments":{"instance":%q}}}`, instance)
+		raw, err := json.Marshal(s.HandleMessage(context.Background(), []byte(request)))
+		if err != nil {
+			t.Fatalf("Marshal() error = %v", err)
+		}
+		var response struct {
+			Result mcp.CallToolResult `json:"result"`
+		}
+		if err := json.Unmarshal(raw, &response); err != nil {
+			t.Fatalf("Unmarshal() error = %v", err)
+		}
+		text, ok := response.Result.Content[0].(mcp.TextContent)
+		if response.Result.IsError || !ok {
+			t.Fatalf("audit_check(%q) failed: %s", instance, raw)
+		}
+		var payload map[string]any
+		if err := json.Unmarshal([]byte(text.Text), &payload); err != nil {
+			t.Fatalf("Unmarshal(%s) error = %v", text.Text, err)
+		}
+		return payload
+	}
+
+	// A fuzzy reference and the VEID resolve like the CLI's --instance.
+	for _, instance := range []string{"pweb", "100"} {
+		if payload := call(instance); payload["instance"] != "prod-web" || payload["untrusted_total"] != float64(1) {
+			t.Errorf("audit_check(%q) = %v", instance, payload)
+		}
+	}
+}
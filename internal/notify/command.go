@@ -0,0 +1,61 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// commandTimeout bounds a single command hook run.
+const commandTimeout = 30 * time.Second
+
+// command runs a local program for every event. The event is written to its
+// standard input as JSON, and the main fields are also set as BWH_EVENT_*
+// environment variables.
+type command struct {
+	argv []string
+}
+
+func newCommand(argv []string) (*command, error) {
+	if len(argv) == 0 || argv[0] == "" {
+		return nil, errors.New("command is required")
+	}
+	return &command{argv: argv}, nil
+}
+
+func (c *command) Send(ctx context.Context, e Event) error {
+	ctx, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+
+	input, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	cmd := exec.CommandContext(ctx, c.argv[0], c.argv[1:]...)
+	cmd.Stdin = bytes.NewReader(append(input, '\n'))
+	cmd.Env = append(os.Environ(),
+		"BWH_EVENT_TITLE="+e.Title,
+		"BWH_EVENT_MESSAGE="+e.Message,
+		"BWH_EVENT_SEVERITY="+string(e.Severity),
+		"BWH_EVENT_INSTANCE="+e.Instance,
+		"BWH_EVENT_SOURCE="+e.Source,
+		"BWH_EVENT_TIME="+e.Time.Format(time.RFC3339),
+	)
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	if err := cmd.Run(); err != nil {
+		if out := bytes.TrimSpace(output.Bytes()); len(out) > 0 {
+			return fmt.Errorf("%s: %w: %s", c.argv[0], err, out)
+		}
+		return fmt.Errorf("%s: %w", c.argv[0], err)
+	}
+	return nil
+}
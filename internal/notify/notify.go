@@ -0,0 +1,191 @@
+// Package notify delivers alert events to the notification sinks configured
+// under notifiers in the config file.
+//
+// Sinks are JSON webhooks, Slack and Discord incoming webhooks, SMTP email
+// and local commands. Every event goes to every sink whose minimum severity
+// it meets.
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/strahe/bwh/internal/config"
+)
+
+// Severity ranks events. Sinks can drop events below a minimum severity.
+type Severity string
+
+const (
+	Info     Severity = "info"
+	Warning  Severity = "warning"
+	Critical Severity = "critical"
+)
+
+func (s Severity) rank() int {
+	switch s {
+	case Warning:
+		return 1
+	case Critical:
+		return 2
+	default:
+		return 0
+	}
+}
+
+// ParseSeverity parses a severity name. An empty name is Info.
+func ParseSeverity(name string) (Severity, error) {
+	switch s := Severity(strings.ToLower(strings.TrimSpace(name))); s {
+	case "":
+		return Info, nil
+	case Info, Warning, Critical:
+		return s, nil
+	default:
+		return "", fmt.Errorf("unknown severity %q (want info, warning or critical)", name)
+	}
+}
+
+// Event is one alert. Source names the feature that raised it, such as
+// "audit" or "nullroute".
+type Event struct {
+	Title    string            `json:"title"`
+	Message  string            `json:"message"`
+	Severity Severity          `json:"severity"`
+	Instance string            `json:"instance,omitempty"`
+	Source   string            `json:"source,omitempty"`
+	Time     time.Time         `json:"time"`
+	Fields   map[string]string `json:"fields,omitempty"`
+}
+
+// withDefaults fills in the time and severity of an event that lacks them.
+func (e Event) withDefaults() Event {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	if e.Severity == "" {
+		e.Severity = Info
+	}
+	return e
+}
+
+// text renders the event as plain text for chat and email sinks.
+func (e Event) text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[%s] %s", strings.ToUpper(string(e.Severity)), e.Title)
+	if e.Instance != "" {
+		fmt.Fprintf(&b, " (%s)", e.Instance)
+	}
+	if e.Message != "" {
+		fmt.Fprintf(&b, "\n%s", e.Message)
+	}
+	for _, k := range slices.Sorted(maps.Keys(e.Fields)) {
+		fmt.Fprintf(&b, "\n%s: %s", k, e.Fields[k])
+	}
+	return b.String()
+}
+
+// Sink delivers events to one destination.
+type Sink interface {
+	Send(ctx context.Context, e Event) error
+}
+
+// Dispatcher sends events to a set of named sinks.
+type Dispatcher struct {
+	names []string
+	sinks map[string]Sink
+	min   map[string]Severity
+}
+
+// New creates a dispatcher for the configured notifiers.
+func New(notifiers map[string]*config.Notifier) (*Dispatcher, error) {
+	d := &Dispatcher{sinks: map[string]Sink{}, min: map[string]Severity{}}
+	for _, name := range slices.Sorted(maps.Keys(notifiers)) {
+		sink, err := newSink(notifiers[name])
+		if err != nil {
+			return nil, fmt.Errorf("notifier %s: %w", name, err)
+		}
+		minSeverity, err := ParseSeverity(notifiers[name].MinSeverity)
+		if err != nil {
+			return nil, fmt.Errorf("notifier %s: %w", name, err)
+		}
+		d.names = append(d.names, name)
+		d.sinks[name] = sink
+		d.min[name] = minSeverity
+	}
+	return d, nil
+}
+
+func newSink(n *config.Notifier) (Sink, error) {
+	switch n.Type {
+	case "webhook":
+		return newWebhook(n.URL, n.Headers, jsonPayload)
+	case "slack":
+		return newWebhook(n.URL, n.Headers, slackPayload)
+	case "discord":
+		return newWebhook(n.URL, n.Headers, discordPayload)
+	case "smtp":
+		return newSMTP(n.SMTP)
+	case "command":
+		return newCommand(n.Command)
+	case "":
+		return nil, errors.New("type is required")
+	default:
+		return nil, fmt.Errorf("unknown type %q (want webhook, slack, discord, smtp or command)", n.Type)
+	}
+}
+
+// Names returns the sink names in sorted order.
+func (d *Dispatcher) Names() []string {
+	return d.names
+}
+
+// Only returns a dispatcher with just the named sinks. Their minimum
+// severities still apply.
+func (d *Dispatcher) Only(names ...string) (*Dispatcher, error) {
+	only := &Dispatcher{sinks: map[string]Sink{}, min: map[string]Severity{}}
+	for _, name := range names {
+		sink, ok := d.sinks[name]
+		if !ok {
+			return nil, fmt.Errorf("notifier %s is not configured", name)
+		}
+		if _, dup := only.sinks[name]; dup {
+			continue
+		}
+		only.names = append(only.names, name)
+		only.sinks[name] = sink
+		only.min[name] = d.min[name]
+	}
+	slices.Sort(only.names)
+	return only, nil
+}
+
+// Send delivers e to every sink whose minimum severity it meets. Sinks are
+// tried in turn; the returned error joins the failures of all sinks.
+func (d *Dispatcher) Send(ctx context.Context, e Event) error {
+	e = e.withDefaults()
+
+	var errs []error
+	for _, name := range d.names {
+		if e.Severity.rank() < d.min[name].rank() {
+			continue
+		}
+		if err := d.sinks[name].Send(ctx, e); err != nil {
+			errs = append(errs, fmt.Errorf("notifier %s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SendTo delivers e to the named sink regardless of its minimum severity.
+func (d *Dispatcher) SendTo(ctx context.Context, name string, e Event) error {
+	sink, ok := d.sinks[name]
+	if !ok {
+		return fmt.Errorf("notifier %s is not configured", name)
+	}
+	return sink.Send(ctx, e.withDefaults())
+}
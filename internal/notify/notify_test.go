@@ -0,0 +1,263 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/strahe/bwh/internal/config"
+)
+
+var testEvent = Event{
+	Title:    "IP nullrouted",
+	Message:  "203.0.113.5 was nullrouted",
+	Severity: Critical,
+	Instance: "web",
+	Source:   "nullroute",
+	Time:     time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+	Fields:   map[string]string{"ip": "203.0.113.5"},
+}
+
+// recordingServer returns a webhook endpoint that records request bodies and
+// answers with status.
+func recordingServer(t *testing.T, status int) (*httptest.Server, *[]*http.Request, *[][]byte) {
+	t.Helper()
+	var requests []*http.Request
+	var bodies [][]byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body json.RawMessage
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decode body: %v", err)
+		}
+		requests = append(requests, r)
+		bodies = append(bodies, body)
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &requests, &bodies
+}
+
+func TestWebhookSinks(t *testing.T) {
+	srv, requests, bodies := recordingServer(t, http.StatusNoContent)
+
+	d, err := New(map[string]*config.Notifier{
+		"hook":    {Type: "webhook", URL: srv.URL, Headers: map[string]string{"Authorization": "Bearer secret"}},
+		"slack":   {Type: "slack", URL: srv.URL},
+		"discord": {Type: "discord", URL: srv.URL, MinSeverity: "critical"},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if got := strings.Join(d.Names(), ","); got != "discord,hook,slack" {
+		t.Fatalf("Names() = %s", got)
+	}
+
+	if err := d.Send(context.Background(), testEvent); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if len(*bodies) != 3 {
+		t.Fatalf("got %d requests, want 3", len(*bodies))
+	}
+
+	var discord map[string]string
+	if err := json.Unmarshal((*bodies)[0], &discord); err != nil || !strings.HasPrefix(discord["content"], "[CRITICAL] IP nullrouted (web)\n") {
+		t.Fatalf("discord payload = %s", (*bodies)[0])
+	}
+	var event Event
+	if err := json.Unmarshal((*bodies)[1], &event); err != nil || event.Fields["ip"] != "203.0.113.5" || event.Source != "nullroute" {
+		t.Fatalf("webhook payload = %s", (*bodies)[1])
+	}
+	if got := (*requests)[1].Header.Get("Authorization"); got != "Bearer secret" {
+		t.Fatalf("Authorization = %q", got)
+	}
+	var slack map[string]string
+	if err := json.Unmarshal((*bodies)[2], &slack); err != nil || !strings.Contains(slack["text"], "ip: 203.0.113.5") {
+		t.Fatalf("slack payload = %s", (*bodies)[2])
+	}
+
+	// The discord sink only takes critical events.
+	if err := d.Send(context.Background(), Event{Title: "test"}); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if len(*bodies) != 5 {
+		t.Fatalf("got %d requests, want 5", len(*bodies))
+	}
+	if err := d.SendTo(context.Background(), "discord", Event{Title: "test"}); err != nil || len(*bodies) != 6 {
+		t.Fatalf("SendTo() error = %v, requests = %d", err, len(*bodies))
+	}
+
+	only, err := d.Only("slack", "hook", "slack")
+	if err != nil || strings.Join(only.Names(), ",") != "hook,slack" {
+		t.Fatalf("Only() = %v, %v", only.Names(), err)
+	}
+	if err := only.Send(context.Background(), testEvent); err != nil || len(*bodies) != 8 {
+		t.Fatalf("Only().Send() error = %v, requests = %d", err, len(*bodies))
+	}
+	if _, err := d.Only("pager"); err == nil {
+		t.Fatal("Only(pager) error = nil, want not configured")
+	}
+}
+
+func TestWebhookError(t *testing.T) {
+	srv, _, _ := recordingServer(t, http.StatusForbidden)
+	d, err := New(map[string]*config.Notifier{"hook": {Type: "webhook", URL: srv.URL}})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	err = d.Send(context.Background(), testEvent)
+	if err == nil || !strings.Contains(err.Error(), "notifier hook: webhook returned 403") {
+		t.Fatalf("Send() error = %v, want 403", err)
+	}
+}
+
+func TestNewRejectsInvalidNotifiers(t *testing.T) {
+	tests := map[string]*config.Notifier{
+		"type is required":    {},
+		"unknown type":        {Type: "pager"},
+		"url is required":     {Type: "slack"},
+		"must be an http":     {Type: "webhook", URL: "ftp://example.com"},
+		"smtp.host":           {Type: "smtp"},
+		"smtp.to":             {Type: "smtp", SMTP: &config.SMTP{Host: "localhost", From: "bwh@example.com"}},
+		"command is required": {Type: "command"},
+		"unknown severity":    {Type: "command", Command: []string{"true"}, MinSeverity: "loud"},
+		"smtp.from":           {Type: "smtp", SMTP: &config.SMTP{Host: "localhost", From: "not an address", To: []string{"a@example.com"}}},
+		"smtp.to \"nobody\"":  {Type: "smtp", SMTP: &config.SMTP{Host: "localhost", From: "bwh@example.com", To: []string{"nobody"}}},
+	}
+	for want, n := range tests {
+		_, err := New(map[string]*config.Notifier{"broken": n})
+		if err == nil || !strings.HasPrefix(err.Error(), "notifier broken: ") || !strings.Contains(err.Error(), want) {
+			t.Errorf("New(%+v) error = %v, want %q", n, err, want)
+		}
+	}
+}
+
+func TestCommandSink(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "event")
+	d, err := New(map[string]*config.Notifier{
+		"hook": {Type: "command", Command: []string{"sh", "-c", `cat > "$0" && echo "$BWH_EVENT_SEVERITY $BWH_EVENT_INSTANCE" >> "$0"`, out}},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if err := d.Send(context.Background(), testEvent); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	var event Event
+	if len(lines) != 2 || json.Unmarshal([]byte(lines[0]), &event) != nil || event.Title != testEvent.Title || lines[1] != "critical web" {
+		t.Fatalf("command received %q", data)
+	}
+
+	failing, err := New(map[string]*config.Notifier{"hook": {Type: "command", Command: []string{"sh", "-c", "echo boom >&2; exit 3"}}})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if err := failing.Send(context.Background(), testEvent); err == nil || !strings.Contains(err.Error(), "exit status 3: boom") {
+		t.Fatalf("Send() error = %v, want exit status with output", err)
+	}
+}
+
+// fakeSMTPServer accepts one SMTP conversation without TLS or
+// authentication and returns the envelope recipients and message.
+func fakeSMTPServer(t *testing.T) (int, <-chan []string, <-chan string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	rcpts := make(chan []string, 1)
+	message := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+		var to []string
+		reply("220 localhost ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			cmd := strings.ToUpper(strings.TrimSpace(line))
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				reply("250 localhost")
+			case strings.HasPrefix(cmd, "MAIL FROM:"):
+				reply("250 OK")
+			case strings.HasPrefix(cmd, "RCPT TO:"):
+				to = append(to, strings.TrimSpace(line[len("RCPT TO:"):]))
+				reply("250 OK")
+			case cmd == "DATA":
+				reply("354 go ahead")
+				var b strings.Builder
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil || l == ".\r\n" {
+						break
+					}
+					b.WriteString(l)
+				}
+				rcpts <- to
+				message <- b.String()
+				reply("250 queued")
+			case cmd == "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("502 not implemented")
+			}
+		}
+	}()
+	return ln.Addr().(*net.TCPAddr).Port, rcpts, message
+}
+
+func TestSMTPSink(t *testing.T) {
+	port, rcpts, message := fakeSMTPServer(t)
+	d, err := New(map[string]*config.Notifier{
+		"mail": {Type: "smtp", SMTP: &config.SMTP{
+			Host: "127.0.0.1",
+			Port: port,
+			From: "BWH <bwh@example.com>",
+			To:   []string{"ops@example.com", "Oncall <oncall@example.com>"},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if err := d.Send(context.Background(), testEvent); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	if got := strings.Join(<-rcpts, " "); got != "<ops@example.com> <oncall@example.com>" {
+		t.Fatalf("recipients = %s", got)
+	}
+	msg := <-message
+	for _, want := range []string{
+		"From: BWH <bwh@example.com>\r\n",
+		"Subject: [bwh] IP nullrouted (web)\r\n",
+		"\r\n\r\n[CRITICAL] IP nullrouted (web)\r\n203.0.113.5 was nullrouted\r\nip: 203.0.113.5\r\n",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Fatalf("message missing %q:\n%s", want, msg)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package notify
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/strahe/bwh/internal/config"
+)
+
+const (
+	// smtpDefaultPort is the mail submission port, which uses STARTTLS.
+	smtpDefaultPort = 587
+	// smtpTLSPort is the submission port with implicit TLS.
+	smtpTLSPort = 465
+	// smtpTimeout bounds a whole SMTP conversation.
+	smtpTimeout = 30 * time.Second
+)
+
+// smtpSink emails events. STARTTLS is used whenever the server offers it;
+// port 465 connects with TLS directly.
+type smtpSink struct {
+	cfg         config.SMTP
+	addr        string
+	implicitTLS bool
+	tlsConfig   *tls.Config
+}
+
+func newSMTP(cfg *config.SMTP) (*smtpSink, error) {
+	if cfg == nil || cfg.Host == "" {
+		return nil, errors.New("smtp.host is required")
+	}
+	if _, err := mail.ParseAddress(cfg.From); err != nil {
+		return nil, fmt.Errorf("smtp.from: %w", err)
+	}
+	if len(cfg.To) == 0 {
+		return nil, errors.New("smtp.to needs at least one address")
+	}
+	for _, to := range cfg.To {
+		if _, err := mail.ParseAddress(to); err != nil {
+			return nil, fmt.Errorf("smtp.to %q: %w", to, err)
+		}
+	}
+	port := cfg.Port
+	if port == 0 {
+		port = smtpDefaultPort
+	}
+	return &smtpSink{
+		cfg:         *cfg,
+		addr:        net.JoinHostPort(cfg.Host, strconv.Itoa(port)),
+		implicitTLS: port == smtpTLSPort,
+		tlsConfig:   &tls.Config{ServerName: cfg.Host},
+	}, nil
+}
+
+func (s *smtpSink) Send(ctx context.Context, e Event) error {
+	ctx, cancel := context.WithTimeout(ctx, smtpTimeout)
+	defer cancel()
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if s.implicitTLS {
+		conn = tls.Client(conn, s.tlsConfig)
+	}
+
+	c, err := smtp.NewClient(conn, s.cfg.Host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if !s.implicitTLS {
+		if ok, _ := c.Extension("STARTTLS"); ok {
+			if err := c.StartTLS(s.tlsConfig); err != nil {
+				return fmt.Errorf("starttls failed: %w", err)
+			}
+		}
+	}
+	if s.cfg.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host)); err != nil {
+			return fmt.Errorf("authentication failed: %w", err)
+		}
+	}
+
+	from, _ := mail.ParseAddress(s.cfg.From)
+	if err := c.Mail(from.Address); err != nil {
+		return err
+	}
+	for _, to := range s.cfg.To {
+		addr, _ := mail.ParseAddress(to)
+		if err := c.Rcpt(addr.Address); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(s.message(e)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// message renders e as a plain text email.
+func (s *smtpSink) message(e Event) []byte {
+	subject := fmt.Sprintf("[bwh] %s", e.Title)
+	if e.Instance != "" {
+		subject += " (" + e.Instance + ")"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", s.cfg.From)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(s.cfg.To, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", e.Time.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(e.text(), "\n", "\r\n"))
+	b.WriteString("\r\n")
+	return []byte(b.String())
+}
@@ -0,0 +1,90 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// webhookTimeout bounds a single webhook request.
+const webhookTimeout = 10 * time.Second
+
+// webhook posts a JSON payload built from the event to a URL.
+type webhook struct {
+	url     string
+	headers map[string]string
+	payload func(Event) any
+	client  *http.Client
+}
+
+func newWebhook(rawURL string, headers map[string]string, payload func(Event) any) (*webhook, error) {
+	if rawURL == "" {
+		return nil, errors.New("url is required")
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("url %q must be an http or https URL", rawURL)
+	}
+	return &webhook{
+		url:     rawURL,
+		headers: headers,
+		payload: payload,
+		client:  &http.Client{Timeout: webhookTimeout},
+	}, nil
+}
+
+func (w *webhook) Send(ctx context.Context, e Event) error {
+	body, err := json.Marshal(w.payload(e))
+	if err != nil {
+		return fmt.Errorf("failed to encode payload: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "bwh-notify")
+	for k, v := range w.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook returned %s: %s", resp.Status, bytes.TrimSpace(detail))
+	}
+	return nil
+}
+
+// jsonPayload posts the event itself.
+func jsonPayload(e Event) any {
+	return e
+}
+
+// slackPayload is the Slack incoming webhook message format.
+func slackPayload(e Event) any {
+	return map[string]string{"text": e.text()}
+}
+
+// discordMaxContent is the longest message Discord accepts.
+const discordMaxContent = 2000
+
+// discordPayload is the Discord webhook message format.
+func discordPayload(e Event) any {
+	content := e.text()
+	if len(content) > discordMaxContent {
+		content = strings.ToValidUTF8(content[:discordMaxContent-3], "") + "..."
+	}
+	return map[string]string{"content": content}
+}
@@ -3,6 +3,7 @@ package progress
 import (
 	"bytes"
 	"io"
+	"strings"
 	"testing"
 	"time"
 )
@@ -249,3 +250,42 @@ func BenchmarkProgressWriter_Write(b *testing.B) {
 		writer.Write(data) //nolint:errcheck
 	}
 }
+
+func TestSpinner(t *testing.T) {
+	var buf bytes.Buffer
+	spinner := NewSpinnerWriter(&buf, "Waiting")
+	spinner.animate = true
+	spinner.interval = time.Millisecond
+	spinner.Start()
+	spinner.Update("Still waiting")
+	time.Sleep(10 * time.Millisecond)
+	spinner.Stop("done")
+
+	out := buf.String()
+	if !strings.Contains(out, "Still waiting") {
+		t.Fatalf("spinner output missing updated message: %q", out)
+	}
+	if !strings.HasSuffix(out, "done\n") {
+		t.Fatalf("spinner output should end with final message: %q", out)
+	}
+
+	// Stopping twice must not panic.
+	spinner.Stop("")
+}
+
+func TestSpinnerWithoutTerminal(t *testing.T) {
+	var buf bytes.Buffer
+	spinner := NewSpinnerWriter(&buf, "Waiting")
+	spinner.interval = time.Millisecond
+	spinner.Start()
+	spinner.Update("Waiting (current: running)")
+	spinner.Update("Waiting (current: running)")
+	time.Sleep(10 * time.Millisecond)
+	spinner.Update("Waiting (current: stopped)")
+	spinner.Stop("done")
+
+	want := "Waiting\nWaiting (current: running)\nWaiting (current: stopped)\ndone\n"
+	if out := buf.String(); out != want {
+		t.Fatalf("spinner output = %q, want %q", out, want)
+	}
+}
@@ -0,0 +1,118 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/term"
+)
+
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// Spinner shows an animated status line while waiting for something to finish.
+// When out is not a terminal, such as a pipe or a CI log, it prints each new
+// message on its own line instead.
+type Spinner struct {
+	out      io.Writer
+	interval time.Duration
+	animate  bool
+
+	mu      sync.Mutex
+	message string
+	start   time.Time
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// NewSpinner creates a spinner that writes to stdout
+func NewSpinner(message string) *Spinner {
+	return NewSpinnerWriter(os.Stdout, message)
+}
+
+// NewSpinnerWriter creates a spinner that writes to out
+func NewSpinnerWriter(out io.Writer, message string) *Spinner {
+	return &Spinner{
+		out:      out,
+		interval: 100 * time.Millisecond,
+		animate:  isTerminal(out),
+		message:  message,
+	}
+}
+
+func isTerminal(out io.Writer) bool {
+	f, ok := out.(*os.File)
+	return ok && term.IsTerminal(int(f.Fd()))
+}
+
+// Start begins animating the spinner in the background
+func (s *Spinner) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stop != nil {
+		return
+	}
+	s.start = time.Now()
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+	if !s.animate {
+		fmt.Fprintln(s.out, s.message)
+		close(s.done)
+		return
+	}
+	go s.run(s.stop, s.done)
+}
+
+// Update replaces the message shown next to the spinner
+func (s *Spinner) Update(message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.animate && s.stop != nil && message != s.message {
+		fmt.Fprintln(s.out, message)
+	}
+	s.message = message
+}
+
+// Stop halts the animation and replaces the spinner line with final.
+// An empty final clears the line.
+func (s *Spinner) Stop(final string) {
+	s.mu.Lock()
+	stop, done := s.stop, s.done
+	s.stop = nil
+	s.mu.Unlock()
+
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+	if s.animate {
+		fmt.Fprintf(s.out, "\r\033[K")
+	}
+	if final != "" {
+		fmt.Fprintf(s.out, "%s\n", final)
+	}
+}
+
+func (s *Spinner) run(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for frame := 0; ; frame++ {
+		s.mu.Lock()
+		message := s.message
+		elapsed := time.Since(s.start).Round(time.Second)
+		s.mu.Unlock()
+
+		fmt.Fprintf(s.out, "\r\033[K%s %s (%s)", spinnerFrames[frame%len(spinnerFrames)], message, elapsed)
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package vault
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrNoAgent is returned when no agent listens on the socket.
+var ErrNoAgent = errors.New("no vault agent running")
+
+const agentTimeout = 2 * time.Second
+
+// Agent requests, one per connection.
+const (
+	requestKey  = "key"
+	requestLock = "lock"
+)
+
+// Listen creates the agent socket at path. A stale socket left by an agent
+// that died is replaced; a live one is an error.
+func Listen(path string) (net.Listener, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create socket directory: %w", err)
+	}
+	if conn, err := net.DialTimeout("unix", path, agentTimeout); err == nil {
+		conn.Close() //nolint:errcheck
+		return nil, fmt.Errorf("a vault agent is already running on %s", path)
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to remove stale socket: %w", err)
+	}
+
+	ln, err := listenUnix(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", path, err)
+	}
+	if err := os.Chmod(path, 0o600); err != nil {
+		ln.Close() //nolint:errcheck
+		return nil, fmt.Errorf("failed to set socket permissions: %w", err)
+	}
+	return ln, nil
+}
+
+// Serve hands key to clients on ln until ttl expires, a client sends a lock
+// request or ctx is done. It closes ln before returning.
+func Serve(ctx context.Context, ln net.Listener, key []byte, ttl time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, ttl)
+	defer cancel()
+
+	go func() {
+		<-ctx.Done()
+		ln.Close() //nolint:errcheck
+	}()
+
+	encoded := hex.EncodeToString(key)
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to accept agent connection: %w", err)
+		}
+		if handleAgentConn(conn, encoded) == requestLock {
+			cancel()
+		}
+	}
+}
+
+// handleAgentConn answers one request and returns it.
+func handleAgentConn(conn net.Conn, encodedKey string) string {
+	defer conn.Close()                             //nolint:errcheck
+	conn.SetDeadline(time.Now().Add(agentTimeout)) //nolint:errcheck
+
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return ""
+	}
+	request := strings.TrimSpace(line)
+	switch request {
+	case requestKey:
+		fmt.Fprintln(conn, encodedKey) //nolint:errcheck
+	case requestLock:
+		fmt.Fprintln(conn, "ok") //nolint:errcheck
+	default:
+		fmt.Fprintln(conn, "error: unknown request") //nolint:errcheck
+	}
+	return request
+}
+
+// FetchKey asks the agent on path for the vault key.
+func FetchKey(path string) ([]byte, error) {
+	reply, err := agentRequest(path, requestKey)
+	if err != nil {
+		return nil, err
+	}
+	key, err := hex.DecodeString(reply)
+	if err != nil {
+		return nil, fmt.Errorf("invalid agent reply: %w", err)
+	}
+	return key, nil
+}
+
+// Lock stops the agent on path.
+func Lock(path string) error {
+	_, err := agentRequest(path, requestLock)
+	return err
+}
+
+func agentRequest(path, request string) (string, error) {
+	conn, err := net.DialTimeout("unix", path, agentTimeout)
+	if err != nil {
+		return "", ErrNoAgent
+	}
+	defer conn.Close()                             //nolint:errcheck
+	conn.SetDeadline(time.Now().Add(agentTimeout)) //nolint:errcheck
+
+	if _, err := fmt.Fprintln(conn, request); err != nil {
+		return "", fmt.Errorf("failed to send agent request: %w", err)
+	}
+	reply, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return "", fmt.Errorf("failed to read agent reply: %w", err)
+	}
+	reply = strings.TrimSpace(reply)
+	if msg, ok := strings.CutPrefix(reply, "error: "); ok {
+		return "", errors.New(msg)
+	}
+	return reply, nil
+}
@@ -0,0 +1,69 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAgent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vault.sock")
+	key := []byte{0x01, 0x02, 0xfe, 0xff}
+
+	if _, err := FetchKey(path); !errors.Is(err, ErrNoAgent) {
+		t.Fatalf("FetchKey() without agent error = %v, want %v", err, ErrNoAgent)
+	}
+
+	ln, err := Listen(path)
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	if _, err := Listen(path); err == nil {
+		t.Errorf("Listen() on a live socket succeeded")
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- Serve(context.Background(), ln, key, time.Minute) }()
+
+	got, err := FetchKey(path)
+	if err != nil {
+		t.Fatalf("FetchKey() error = %v", err)
+	}
+	if !bytes.Equal(got, key) {
+		t.Errorf("FetchKey() = %x, want %x", got, key)
+	}
+
+	if err := Lock(path); err != nil {
+		t.Fatalf("Lock() error = %v", err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Serve() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Serve() did not stop after Lock()")
+	}
+	if _, err := FetchKey(path); !errors.Is(err, ErrNoAgent) {
+		t.Errorf("FetchKey() after lock error = %v, want %v", err, ErrNoAgent)
+	}
+}
+
+func TestAgentTTL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vault.sock")
+	ln, err := Listen(path)
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+
+	start := time.Now()
+	if err := Serve(context.Background(), ln, []byte{1}, 50*time.Millisecond); err != nil {
+		t.Fatalf("Serve() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Serve() ran for %s after a 50ms TTL", elapsed)
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package vault
+
+import "net"
+
+// listenUnix creates the socket. Platforms without a umask rely on the
+// permissions of the socket directory.
+func listenUnix(path string) (net.Listener, error) {
+	return net.Listen("unix", path)
+}
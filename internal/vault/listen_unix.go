@@ -0,0 +1,17 @@
+//go:build unix
+
+package vault
+
+import (
+	"net"
+	"syscall"
+)
+
+// listenUnix creates the socket with a 0077 umask so that it is never
+// reachable by other users, not even before Listen tightens its mode. The
+// umask is process-wide, which is fine in the agent process.
+func listenUnix(path string) (net.Listener, error) {
+	old := syscall.Umask(0o077)
+	defer syscall.Umask(old)
+	return net.Listen("unix", path)
+}
@@ -0,0 +1,64 @@
+// Package vault unlocks the encrypted API keys of the config file. The key
+// comes from the BWH_VAULT_PASSPHRASE environment variable, from an agent
+// started by 'bwh config unlock', or from a passphrase prompt, in that order.
+package vault
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/strahe/bwh/internal/config"
+)
+
+const (
+	// PassphraseEnv holds the vault passphrase for non-interactive use.
+	PassphraseEnv = "BWH_VAULT_PASSPHRASE"
+	// SocketEnv overrides the path of the agent socket.
+	SocketEnv = "BWH_VAULT_SOCK"
+)
+
+// SocketPath returns the path of the agent socket: $BWH_VAULT_SOCK or
+// ~/.bwh/vault.sock.
+func SocketPath() (string, error) {
+	if path := os.Getenv(SocketEnv); path != "" {
+		return path, nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".bwh", "vault.sock"), nil
+}
+
+// KeyFunc returns a config.VaultKeyFunc that tries the environment, then the
+// agent, then prompt. A nil prompt makes the vault fail to unlock instead of
+// asking, for callers without a terminal.
+func KeyFunc(prompt func() (string, error)) config.VaultKeyFunc {
+	return func(v *config.Vault) ([]byte, error) {
+		if passphrase := os.Getenv(PassphraseEnv); passphrase != "" {
+			key, err := v.DeriveKey(passphrase)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", PassphraseEnv, err)
+			}
+			return key, nil
+		}
+
+		// An agent for another config file holds a key that does not verify;
+		// fall through to the prompt in that case.
+		if path, err := SocketPath(); err == nil {
+			if key, err := FetchKey(path); err == nil && v.VerifyKey(key) == nil {
+				return key, nil
+			}
+		}
+
+		if prompt == nil {
+			return nil, fmt.Errorf("%w: set %s or run 'bwh config unlock'", config.ErrVaultLocked, PassphraseEnv)
+		}
+		passphrase, err := prompt()
+		if err != nil {
+			return nil, err
+		}
+		return v.DeriveKey(passphrase)
+	}
+}
@@ -202,12 +202,20 @@ func (c *Client) GetAvailableOS(ctx context.Context) (*AvailableOSResponse, erro
 // ReinstallOS reinstalls the operating system.
 // WARNING: This will destroy all data on the VPS!
 func (c *Client) ReinstallOS(ctx context.Context, osTemplate string) error {
-	var resp BaseResponse
+	_, err := c.ReinstallOSWithResult(ctx, osTemplate)
+	return err
+}
+
+// ReinstallOSWithResult reinstalls the operating system and returns the new
+// root password and SSH port reported by KiwiVM.
+// WARNING: This will destroy all data on the VPS!
+func (c *Client) ReinstallOSWithResult(ctx context.Context, osTemplate string) (*ReinstallOSResponse, error) {
+	var resp ReinstallOSResponse
 	if err := c.doPostRequest(ctx, "reinstallOS", map[string]string{"os": osTemplate}, &resp); err != nil {
-		return err
+		return nil, err
 	}
 
-	return wrapOnlyErrorFromBase(resp)
+	return wrapErrorWithBase(&resp, resp.BaseResponse)
 }
 
 // GetRawUsageStats gets detailed usage statistics.
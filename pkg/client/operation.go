@@ -0,0 +1,201 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const defaultPollInterval = 5 * time.Second
+
+// ErrWaitTimeout is returned when a VE is still locked after WaitOptions.Timeout.
+var ErrWaitTimeout = errors.New("timed out waiting for VE to unlock")
+
+// ProgressEvent describes the locking state reported by KiwiVM while a
+// long-running operation (snapshot, reinstall, migration, ...) is in progress.
+type ProgressEvent struct {
+	Operation            string // Operation description from additionalErrorInfo
+	CompletedPercent     int    // Completion percentage reported by KiwiVM
+	Message              string // Friendly progress message
+	LastUpdateSecondsAgo int    // Seconds since KiwiVM last updated the status
+}
+
+// WaitOptions configures how a locked VE is polled.
+type WaitOptions struct {
+	// PollInterval is the delay between probes. Defaults to 5 seconds.
+	PollInterval time.Duration
+	// Timeout bounds the whole wait. Zero means no limit beyond ctx.
+	Timeout time.Duration
+	// Probe performs a cheap API call that fails with a locked error while the
+	// VE is busy. Client.TrackOperation defaults it to GetServiceInfo.
+	Probe func(context.Context) error
+	// OnProgress is called by WaitUntilUnlocked for every progress change.
+	OnProgress func(ProgressEvent)
+}
+
+// Operation is a handle to a background poller that tracks a locked VE until
+// it becomes available again.
+type Operation struct {
+	events chan ProgressEvent
+	done   chan struct{}
+	cancel context.CancelFunc
+	err    error
+}
+
+// NewOperation starts polling opts.Probe in the background and returns a handle
+// to the running operation. The first probe is sent after one poll interval so
+// that a lock started by the preceding write call has time to appear.
+//
+// Locked errors are converted to progress events, transport errors are retried
+// and any other API error stops the operation.
+func NewOperation(ctx context.Context, opts WaitOptions) (*Operation, error) {
+	if opts.Probe == nil {
+		return nil, errors.New("wait options require a probe function")
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = defaultPollInterval
+	}
+
+	var cancel context.CancelFunc
+	if opts.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+
+	op := &Operation{
+		events: make(chan ProgressEvent, 16),
+		done:   make(chan struct{}),
+		cancel: cancel,
+	}
+	go op.run(ctx, opts)
+	return op, nil
+}
+
+// TrackOperation starts tracking the current VE lock using GetServiceInfo as
+// the default probe.
+func (c *Client) TrackOperation(ctx context.Context, opts WaitOptions) *Operation {
+	if opts.Probe == nil {
+		opts.Probe = func(ctx context.Context) error {
+			_, err := c.GetServiceInfo(ctx)
+			return err
+		}
+	}
+	op, _ := NewOperation(ctx, opts)
+	return op
+}
+
+// WaitUntilUnlocked blocks until the VE is no longer locked, the timeout
+// expires or ctx is cancelled. Progress is reported through opts.OnProgress.
+func (c *Client) WaitUntilUnlocked(ctx context.Context, opts WaitOptions) error {
+	op := c.TrackOperation(ctx, opts)
+	for event := range op.Events() {
+		if opts.OnProgress != nil {
+			opts.OnProgress(event)
+		}
+	}
+	return op.Wait()
+}
+
+// Events returns progress events. The channel is closed when the operation
+// finishes. Events are dropped if the receiver falls behind.
+func (o *Operation) Events() <-chan ProgressEvent {
+	return o.events
+}
+
+// Done is closed when the VE is unlocked or polling stops with an error.
+func (o *Operation) Done() <-chan struct{} {
+	return o.done
+}
+
+// Err returns the terminal error once Done is closed.
+func (o *Operation) Err() error {
+	select {
+	case <-o.done:
+		return o.err
+	default:
+		return nil
+	}
+}
+
+// Wait blocks until the operation finishes and returns its terminal error.
+func (o *Operation) Wait() error {
+	<-o.done
+	return o.err
+}
+
+// Cancel stops polling.
+func (o *Operation) Cancel() {
+	o.cancel()
+}
+
+func (o *Operation) run(ctx context.Context, opts WaitOptions) {
+	defer close(o.done)
+	defer close(o.events)
+	defer o.cancel()
+
+	ticker := time.NewTicker(opts.PollInterval)
+	defer ticker.Stop()
+
+	var last *ProgressEvent
+	for {
+		select {
+		case <-ctx.Done():
+			o.err = waitContextError(ctx, opts.Timeout)
+			return
+		case <-ticker.C:
+		}
+
+		err := opts.Probe(ctx)
+		if err == nil {
+			return
+		}
+		if ctx.Err() != nil {
+			o.err = waitContextError(ctx, opts.Timeout)
+			return
+		}
+
+		bwhErr, ok := GetBWHError(err)
+		if !ok {
+			// Transport errors are common during long operations; keep polling.
+			continue
+		}
+		if !IsLockedError(err) {
+			o.err = err
+			return
+		}
+
+		event := progressEventFromError(bwhErr)
+		if last != nil && sameProgress(*last, event) {
+			continue
+		}
+		last = &event
+		select {
+		case o.events <- event:
+		default:
+		}
+	}
+}
+
+func progressEventFromError(err *BWHError) ProgressEvent {
+	event := ProgressEvent{Operation: err.AdditionalErrorInfo}
+	if info := err.AdditionalLockingInfo; info != nil {
+		event.CompletedPercent = info.CompletedPercent
+		event.Message = info.FriendlyProgressMessage
+		event.LastUpdateSecondsAgo = info.LastStatusUpdateSecondsAgo
+	}
+	return event
+}
+
+// sameProgress ignores the status age, which changes on every poll.
+func sameProgress(a, b ProgressEvent) bool {
+	return a.Operation == b.Operation && a.CompletedPercent == b.CompletedPercent && a.Message == b.Message
+}
+
+func waitContextError(ctx context.Context, timeout time.Duration) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) && timeout > 0 {
+		return fmt.Errorf("%w after %s", ErrWaitTimeout, timeout)
+	}
+	return ctx.Err()
+}
@@ -0,0 +1,122 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const lockedResponse = `{"error":788888,"message":"VE is currently locked, try again in a few minutes","additionalErrorInfo":"OS Reinstall: debian-13-x86_64","additionalLockingInfo":{"last_status_update_s_ago":%d,"completed_percent":%d,"friendly_progress_message":"%s"}}`
+
+func newLockSequenceServer(t *testing.T, bodies []string) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(calls.Add(1)) - 1
+		if n >= len(bodies) {
+			n = len(bodies) - 1
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(bodies[n]))
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func lockedBody(secondsAgo, percent int, message string) string {
+	return fmt.Sprintf(lockedResponse, secondsAgo, percent, message)
+}
+
+func TestWaitUntilUnlocked(t *testing.T) {
+	server, calls := newLockSequenceServer(t, []string{
+		lockedBody(3, 40, "Copying files"),
+		lockedBody(8, 40, "Copying files"),
+		lockedBody(2, 80, "Starting VM"),
+		`{"error":0,"hostname":"test"}`,
+	})
+
+	c := NewClient("test-key", "123456")
+	c.SetBaseURL(server.URL)
+
+	var events []ProgressEvent
+	err := c.WaitUntilUnlocked(context.Background(), WaitOptions{
+		PollInterval: time.Millisecond,
+		OnProgress: func(event ProgressEvent) {
+			events = append(events, event)
+		},
+	})
+	if err != nil {
+		t.Fatalf("WaitUntilUnlocked() error = %v", err)
+	}
+	if got := calls.Load(); got != 4 {
+		t.Fatalf("probe calls = %d, want 4", got)
+	}
+	if len(events) != 2 {
+		t.Fatalf("events = %+v, want 2 deduplicated events", events)
+	}
+	if events[0].Operation != "OS Reinstall: debian-13-x86_64" || events[0].CompletedPercent != 40 {
+		t.Fatalf("events[0] = %+v", events[0])
+	}
+	if events[1].CompletedPercent != 80 || events[1].Message != "Starting VM" || events[1].LastUpdateSecondsAgo != 2 {
+		t.Fatalf("events[1] = %+v", events[1])
+	}
+}
+
+func TestWaitUntilUnlockedTimeout(t *testing.T) {
+	server, _ := newLockSequenceServer(t, []string{lockedBody(1, 10, "Working")})
+
+	c := NewClient("test-key", "123456")
+	c.SetBaseURL(server.URL)
+
+	err := c.WaitUntilUnlocked(context.Background(), WaitOptions{
+		PollInterval: time.Millisecond,
+		Timeout:      50 * time.Millisecond,
+	})
+	if !errors.Is(err, ErrWaitTimeout) {
+		t.Fatalf("WaitUntilUnlocked() error = %v, want ErrWaitTimeout", err)
+	}
+}
+
+func TestWaitUntilUnlockedStopsOnAPIError(t *testing.T) {
+	server, calls := newLockSequenceServer(t, []string{`{"error":700005,"message":"Authentication failure"}`})
+
+	c := NewClient("test-key", "123456")
+	c.SetBaseURL(server.URL)
+
+	err := c.WaitUntilUnlocked(context.Background(), WaitOptions{PollInterval: time.Millisecond})
+	if !IsAuthenticationError(err) {
+		t.Fatalf("WaitUntilUnlocked() error = %v, want auth error", err)
+	}
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("probe calls = %d, want 1", got)
+	}
+}
+
+func TestNewOperationRequiresProbe(t *testing.T) {
+	if _, err := NewOperation(context.Background(), WaitOptions{}); err == nil {
+		t.Fatal("NewOperation() error = nil, want error")
+	}
+}
+
+func TestOperationCancel(t *testing.T) {
+	op, err := NewOperation(context.Background(), WaitOptions{
+		PollInterval: time.Millisecond,
+		Probe: func(context.Context) error {
+			return &BWHError{Code: 788888, Message: "VE is currently locked"}
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewOperation() error = %v", err)
+	}
+
+	op.Cancel()
+	if err := op.Wait(); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Wait() error = %v, want context.Canceled", err)
+	}
+}
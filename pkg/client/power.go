@@ -0,0 +1,146 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Power states reported by PowerState.
+const (
+	PowerStateRunning  = "running"
+	PowerStateStopped  = "stopped"
+	PowerStateStarting = "starting"
+	PowerStateUnknown  = "unknown"
+)
+
+// ErrPowerStateTimeout is returned when the VPS does not reach the requested
+// power state within PowerWaitOptions.Timeout.
+var ErrPowerStateTimeout = errors.New("timed out waiting for VPS power state")
+
+// PowerWaitOptions configures WaitForPowerState.
+type PowerWaitOptions struct {
+	// PollInterval is the delay between GetLiveServiceInfo calls. Defaults to 5 seconds.
+	PollInterval time.Duration
+	// Timeout bounds the whole wait. Zero means no limit beyond ctx.
+	Timeout time.Duration
+	// OnStatus is called with the observed power state after every poll.
+	OnStatus func(state string)
+	// Transition requires the VPS to report another known state before the
+	// target counts, or TransitionGrace to pass. Waiting for "running" after
+	// a restart sets it, since the VPS is still running until the restart
+	// takes effect.
+	Transition bool
+	// TransitionGrace is how long after the wait starts the target counts
+	// with Transition even if no other state was seen, for a VPS that
+	// reboots between two polls. Defaults to 30 seconds.
+	TransitionGrace time.Duration
+}
+
+const defaultTransitionGrace = 30 * time.Second
+
+// PowerState returns the normalized power state of a VPS.
+//
+// KVM instances report it in ve_status. OpenVZ instances do not, so the state
+// is derived from vz_status: an explicit "status" entry is used when present,
+// otherwise a non-zero process count means the container is running.
+func PowerState(info *LiveServiceInfo) string {
+	if info == nil {
+		return PowerStateUnknown
+	}
+	if status := strings.ToLower(strings.TrimSpace(info.VeStatus)); status != "" {
+		return status
+	}
+	if len(info.VzStatus) == 0 {
+		return PowerStateUnknown
+	}
+	if status, ok := info.VzStatus["status"].(string); ok && status != "" {
+		return strings.ToLower(strings.TrimSpace(status))
+	}
+	switch nproc := info.VzStatus["nproc"].(type) {
+	case float64:
+		if nproc > 0 {
+			return PowerStateRunning
+		}
+		return PowerStateStopped
+	case string:
+		if nproc != "" && nproc != "0" && nproc != "-" {
+			return PowerStateRunning
+		}
+		return PowerStateStopped
+	}
+	return PowerStateUnknown
+}
+
+// WaitForPowerState polls GetLiveServiceInfo until the VPS reports the target
+// power state, the timeout expires or ctx is cancelled. The first poll is sent
+// after one poll interval so a preceding Start, Stop or Restart call has time
+// to take effect. With opts.Transition, polls reporting the target are ignored
+// until the VPS has been seen in another state or opts.TransitionGrace has
+// passed.
+//
+// Locked and transport errors are retried; any other API error stops the wait.
+func (c *Client) WaitForPowerState(ctx context.Context, target string, opts PowerWaitOptions) (*LiveServiceInfo, error) {
+	target = strings.ToLower(strings.TrimSpace(target))
+	if target == "" {
+		return nil, errors.New("target power state is required")
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = defaultPollInterval
+	}
+	if opts.TransitionGrace <= 0 {
+		opts.TransitionGrace = defaultTransitionGrace
+	}
+	graceEnd := time.Now().Add(opts.TransitionGrace)
+
+	var cancel context.CancelFunc
+	if opts.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	ticker := time.NewTicker(opts.PollInterval)
+	defer ticker.Stop()
+
+	left := !opts.Transition
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, powerContextError(ctx, target, opts.Timeout)
+		case <-ticker.C:
+		}
+
+		info, err := c.GetLiveServiceInfo(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, powerContextError(ctx, target, opts.Timeout)
+			}
+			if IsBWHError(err) && !IsLockedError(err) {
+				return nil, err
+			}
+			continue
+		}
+
+		state := PowerState(info)
+		if opts.OnStatus != nil {
+			opts.OnStatus(state)
+		}
+		switch {
+		case state == target && (left || time.Now().After(graceEnd)):
+			return info, nil
+		case state != target && state != PowerStateUnknown:
+			left = true
+		}
+	}
+}
+
+func powerContextError(ctx context.Context, target string, timeout time.Duration) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) && timeout > 0 {
+		return fmt.Errorf("%w %q after %s", ErrPowerStateTimeout, target, timeout)
+	}
+	return ctx.Err()
+}
@@ -0,0 +1,149 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPowerState(t *testing.T) {
+	tests := []struct {
+		name string
+		info *LiveServiceInfo
+		want string
+	}{
+		{name: "nil", info: nil, want: PowerStateUnknown},
+		{name: "kvm running", info: &LiveServiceInfo{VeStatus: "Running"}, want: PowerStateRunning},
+		{name: "kvm stopped", info: &LiveServiceInfo{VeStatus: " stopped "}, want: PowerStateStopped},
+		{name: "ovz status", info: &LiveServiceInfo{VzStatus: map[string]any{"status": "Stopped"}}, want: PowerStateStopped},
+		{name: "ovz processes", info: &LiveServiceInfo{VzStatus: map[string]any{"nproc": float64(42)}}, want: PowerStateRunning},
+		{name: "ovz no processes", info: &LiveServiceInfo{VzStatus: map[string]any{"nproc": "0"}}, want: PowerStateStopped},
+		{name: "empty", info: &LiveServiceInfo{}, want: PowerStateUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PowerState(tt.info); got != tt.want {
+				t.Fatalf("PowerState() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWaitForPowerState(t *testing.T) {
+	server, calls := newLockSequenceServer(t, []string{
+		`{"error":0,"ve_status":"Running"}`,
+		lockedBody(1, 50, "Stopping VM"),
+		`{"error":0,"ve_status":"Stopped"}`,
+	})
+
+	c := NewClient("test-key", "123456")
+	c.SetBaseURL(server.URL)
+
+	var states []string
+	info, err := c.WaitForPowerState(context.Background(), "stopped", PowerWaitOptions{
+		PollInterval: time.Millisecond,
+		OnStatus: func(state string) {
+			states = append(states, state)
+		},
+	})
+	if err != nil {
+		t.Fatalf("WaitForPowerState() error = %v", err)
+	}
+	if info.VeStatus != "Stopped" {
+		t.Fatalf("VeStatus = %q, want Stopped", info.VeStatus)
+	}
+	if got := calls.Load(); got != 3 {
+		t.Fatalf("calls = %d, want 3", got)
+	}
+	if len(states) != 2 || states[0] != PowerStateRunning || states[1] != PowerStateStopped {
+		t.Fatalf("states = %v, want [running stopped]", states)
+	}
+}
+
+func TestWaitForPowerStateTransition(t *testing.T) {
+	server, calls := newLockSequenceServer(t, []string{
+		`{"error":0,"ve_status":"Running"}`,
+		`{"error":0,"ve_status":"Stopped"}`,
+		`{"error":0,"ve_status":"Running"}`,
+	})
+
+	c := NewClient("test-key", "123456")
+	c.SetBaseURL(server.URL)
+
+	var states []string
+	if _, err := c.WaitForPowerState(context.Background(), "running", PowerWaitOptions{
+		PollInterval: time.Millisecond,
+		Transition:   true,
+		OnStatus: func(state string) {
+			states = append(states, state)
+		},
+	}); err != nil {
+		t.Fatalf("WaitForPowerState() error = %v", err)
+	}
+	if got := calls.Load(); got != 3 {
+		t.Fatalf("calls = %d, want 3", got)
+	}
+	if len(states) != 3 || states[0] != PowerStateRunning || states[1] != PowerStateStopped || states[2] != PowerStateRunning {
+		t.Fatalf("states = %v, want [running stopped running]", states)
+	}
+}
+
+func TestWaitForPowerStateTransitionGrace(t *testing.T) {
+	// A VPS that reboots between two polls never reports another state.
+	server, calls := newLockSequenceServer(t, []string{`{"error":0,"ve_status":"Running"}`})
+
+	c := NewClient("test-key", "123456")
+	c.SetBaseURL(server.URL)
+
+	start := time.Now()
+	if _, err := c.WaitForPowerState(context.Background(), "running", PowerWaitOptions{
+		PollInterval:    time.Millisecond,
+		Timeout:         5 * time.Second,
+		Transition:      true,
+		TransitionGrace: 50 * time.Millisecond,
+	}); err != nil {
+		t.Fatalf("WaitForPowerState() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Fatalf("WaitForPowerState() returned after %s, before the grace period", elapsed)
+	}
+	if got := calls.Load(); got < 2 {
+		t.Fatalf("calls = %d, want polls during the grace period", got)
+	}
+}
+
+func TestWaitForPowerStateErrors(t *testing.T) {
+	t.Run("timeout", func(t *testing.T) {
+		server, _ := newLockSequenceServer(t, []string{`{"error":0,"ve_status":"Running"}`})
+		c := NewClient("test-key", "123456")
+		c.SetBaseURL(server.URL)
+
+		_, err := c.WaitForPowerState(context.Background(), "stopped", PowerWaitOptions{
+			PollInterval: time.Millisecond,
+			Timeout:      50 * time.Millisecond,
+		})
+		if !errors.Is(err, ErrPowerStateTimeout) {
+			t.Fatalf("WaitForPowerState() error = %v, want ErrPowerStateTimeout", err)
+		}
+	})
+
+	t.Run("api error", func(t *testing.T) {
+		server, _ := newLockSequenceServer(t, []string{`{"error":700005,"message":"Authentication failure"}`})
+		c := NewClient("test-key", "123456")
+		c.SetBaseURL(server.URL)
+
+		_, err := c.WaitForPowerState(context.Background(), "running", PowerWaitOptions{PollInterval: time.Millisecond})
+		if !IsAuthenticationError(err) {
+			t.Fatalf("WaitForPowerState() error = %v, want auth error", err)
+		}
+	})
+
+	t.Run("empty target", func(t *testing.T) {
+		c := NewClient("test-key", "123456")
+		if _, err := c.WaitForPowerState(context.Background(), " ", PowerWaitOptions{}); err == nil {
+			t.Fatal("WaitForPowerState() error = nil, want error")
+		}
+	})
+}
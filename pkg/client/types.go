@@ -208,6 +208,14 @@ type ResetRootPasswordResponse struct {
 	Password string `json:"password"` // The new root password
 }
 
+// ReinstallOSResponse represents the response from reinstallOS API call.
+type ReinstallOSResponse struct {
+	BaseResponse
+	RootPassword      string      `json:"rootPassword"`      // New root password
+	SSHPort           FlexibleInt `json:"sshPort"`           // SSH port of the new installation
+	NotificationEmail string      `json:"notificationEmail"` // Email notified when the reinstall completes
+}
+
 // SnapshotInfo represents a single snapshot.
 type SnapshotInfo struct {
 	FileName        string      `json:"fileName"`        // File name of the snapshot
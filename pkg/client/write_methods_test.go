@@ -397,6 +397,33 @@ func TestClient_SetNotificationPreferences_Mock(t *testing.T) {
 	}
 }
 
+func TestClient_ReinstallOSWithResult_Mock(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assertPostForm(t, r, "reinstallOS", "valid_key", map[string]string{"os": "debian-12-x86_64"})
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"error":0,"rootPassword":"N3wP4ss","sshPort":"28979","notificationEmail":"user@example.com"}`))
+	}))
+	defer server.Close()
+
+	c := NewClient("valid_key", "123456")
+	c.SetBaseURL(server.URL)
+
+	resp, err := c.ReinstallOSWithResult(context.Background(), "debian-12-x86_64")
+	if err != nil {
+		t.Fatalf("ReinstallOSWithResult() error = %v", err)
+	}
+	if resp.RootPassword != "N3wP4ss" {
+		t.Fatalf("RootPassword = %q, want N3wP4ss", resp.RootPassword)
+	}
+	if resp.SSHPort.Value != 28979 {
+		t.Fatalf("SSHPort = %d, want 28979", resp.SSHPort.Value)
+	}
+	if resp.NotificationEmail != "user@example.com" {
+		t.Fatalf("NotificationEmail = %q", resp.NotificationEmail)
+	}
+}
+
 func TestSetNotificationPreferencesResponse_EmptyArrayMaps(t *testing.T) {
 	var resp SetNotificationPreferencesResponse
 	err := json.Unmarshal([]byte(`{